@@ -0,0 +1,261 @@
+/*
+Package classifier exposes the classification algorithms of hector to Go programs.
+
+Every algorithm is built from its typed params struct, trained on a core.DataSet and
+saved with the model package:
+
+	p := classifier.FTRLLogisticRegressionParams{}
+	params.Defaults(&p)
+	c := classifier.NewFTRLLogisticRegression(p)
+	c.Train(dataset)
+	prob := c.Predict(sample)
+
+The model body written by SaveModel does not hold the params of the algorithm. Build the
+algorithm with the params it was trained with before LoadModel; model.Header.Params keeps
+them as written by the hector command.
+*/
+package classifier
+
+import (
+	"io"
+
+	"github.com/pantsing/hector/core"
+	"github.com/pantsing/hector/eval"
+	"github.com/pantsing/hector/internal/algorithms/classifier"
+	"github.com/pantsing/hector/internal/algorithms/classifier/ann"
+	"github.com/pantsing/hector/internal/algorithms/classifier/dt"
+	"github.com/pantsing/hector/internal/algorithms/classifier/fm"
+	"github.com/pantsing/hector/internal/algorithms/classifier/lr"
+	"github.com/pantsing/hector/internal/algorithms/classifier/sa"
+	"github.com/pantsing/hector/internal/algorithms/classifier/svm"
+)
+
+// Classifier is a binary classifier.
+type Classifier interface {
+	// Train model on a given dataset
+	Train(dataset *core.DataSet)
+	// Predict the probability of a sample to be positive sample
+	Predict(sample *core.Sample) float64
+	SaveModel(w io.Writer) error
+	LoadModel(r io.Reader) error
+}
+
+// StreamingClassifier is a classifier learning from one sample at a time, which can
+// train on a stream of samples too large to be held in memory with TrainStream.
+type StreamingClassifier interface {
+	Classifier
+	// Learn updates the model with a sample and returns the probability of the sample
+	// to be positive predicted before the update.
+	Learn(sample *core.Sample) float64
+}
+
+// Recalibrated is a Classifier trained on negative samples kept at NegSampleRate, see
+// core.NegativeSampler, whose predictions are corrected back to the base rate of the data
+// they were drawn from. model.Header.NegSampleRate holds the rate of a model file.
+type Recalibrated struct {
+	Classifier
+	NegSampleRate float64
+}
+
+func (c *Recalibrated) Predict(sample *core.Sample) float64 {
+	return core.Recalibrate(c.Classifier.Predict(sample), c.NegSampleRate)
+}
+
+// WarmStarter is a classifier which can continue training a model it loaded: build it
+// with the params of the model, load the model, call WarmStart, then Train.
+type WarmStarter interface {
+	Classifier
+	// WarmStart makes the next Train continue from the loaded model instead of starting over.
+	WarmStart()
+}
+
+// Seeder is a classifier which draws random numbers. Seed makes it draw them from a
+// source seeded with seed, so that equal seeds train equal models. Classifiers which are
+// not seeded draw from a source seeded with 1.
+type Seeder interface {
+	Classifier
+	Seed(seed int64)
+}
+
+// MultiClassClassifier is a classifier over the labels 0 to n-1.
+type MultiClassClassifier interface {
+	Train(dataset *core.DataSet)
+	// PredictMultiClass returns the probability of every label
+	PredictMultiClass(sample *core.Sample) *core.ArrayVector
+	SaveModel(w io.Writer) error
+	LoadModel(r io.Reader) error
+}
+
+// The algorithms. CART, RandomForest, RandomDecisionTree, KNN and NeuralNetwork are
+// multi-class classifiers as well.
+type (
+	LogisticRegression       = lr.LogisticRegression
+	LogisticRegressionStream = lr.LogisticRegressionStream
+	FTRLLogisticRegression   = lr.FTRLLogisticRegression
+	EPLogisticRegression     = lr.EPLogisticRegression
+	LROWLQN                  = lr.LROWLQN
+	FactorizeMachine         = fm.FactorizeMachine
+	SAOptAUC                 = sa.SAOptAUC
+	RegressionTree           = dt.RegressionTree
+	GBDT                     = dt.GBDT
+	RankNet                  = lr.RankNet
+	LambdaMART               = dt.LambdaMART
+	SVM                      = svm.SVM
+	LinearSVM                = svm.LinearSVM
+	L1VM                     = svm.L1VM
+	CART                     = dt.CART
+	RandomForest             = dt.RandomForest
+	RandomDecisionTree       = dt.RandomDecisionTree
+	KNN                      = svm.KNN
+	NeuralNetwork            = ann.NeuralNetwork
+)
+
+var (
+	_ Classifier           = (*LogisticRegression)(nil)
+	_ Classifier           = (*FTRLLogisticRegression)(nil)
+	_ Classifier           = (*EPLogisticRegression)(nil)
+	_ Classifier           = (*LROWLQN)(nil)
+	_ Classifier           = (*FactorizeMachine)(nil)
+	_ Classifier           = (*SAOptAUC)(nil)
+	_ Classifier           = (*RegressionTree)(nil)
+	_ Classifier           = (*GBDT)(nil)
+	_ Classifier           = (*RankNet)(nil)
+	_ Classifier           = (*LambdaMART)(nil)
+	_ Classifier           = (*SVM)(nil)
+	_ Classifier           = (*LinearSVM)(nil)
+	_ Classifier           = (*L1VM)(nil)
+	_ Classifier           = (*CART)(nil)
+	_ Classifier           = (*RandomForest)(nil)
+	_ Classifier           = (*RandomDecisionTree)(nil)
+	_ Classifier           = (*KNN)(nil)
+	_ Classifier           = (*NeuralNetwork)(nil)
+	_ StreamingClassifier  = (*LogisticRegressionStream)(nil)
+	_ StreamingClassifier  = (*FTRLLogisticRegression)(nil)
+	_ WarmStarter          = (*LogisticRegression)(nil)
+	_ WarmStarter          = (*FTRLLogisticRegression)(nil)
+	_ WarmStarter          = (*EPLogisticRegression)(nil)
+	_ WarmStarter          = (*FactorizeMachine)(nil)
+	_ WarmStarter          = (*NeuralNetwork)(nil)
+	_ WarmStarter          = (*GBDT)(nil)
+	_ Seeder               = (*FactorizeMachine)(nil)
+	_ Seeder               = (*SAOptAUC)(nil)
+	_ Seeder               = (*SVM)(nil)
+	_ Seeder               = (*LinearSVM)(nil)
+	_ Seeder               = (*L1VM)(nil)
+	_ Seeder               = (*CART)(nil)
+	_ Seeder               = (*RandomForest)(nil)
+	_ Seeder               = (*RandomDecisionTree)(nil)
+	_ Seeder               = (*KNN)(nil)
+	_ Seeder               = (*NeuralNetwork)(nil)
+	_ MultiClassClassifier = (*CART)(nil)
+	_ MultiClassClassifier = (*RandomForest)(nil)
+	_ MultiClassClassifier = (*RandomDecisionTree)(nil)
+	_ MultiClassClassifier = (*KNN)(nil)
+	_ MultiClassClassifier = (*NeuralNetwork)(nil)
+)
+
+type (
+	LogisticRegressionParams     = lr.LogisticRegressionParams
+	FTRLLogisticRegressionParams = lr.FTRLLogisticRegressionParams
+	EPLogisticRegressionParams   = lr.EPLogisticRegressionParams
+	LROWLQNParams                = lr.LROWLQNParams
+	FactorizeMachineParams       = fm.FactorizeMachineParams
+	CARTParams                   = dt.CARTParams
+	RegressionTreeParams         = dt.RegressionTreeParams
+	RandomForestParams           = dt.RandomForestParams
+	RDTParams                    = dt.RDTParams
+	GBDTParams                   = dt.GBDTParams
+	RankNetParams                = lr.RankNetParams
+	LambdaMARTParams             = dt.LambdaMARTParams
+	SVMParams                    = svm.SVMParams
+	L1VMParams                   = svm.L1VMParams
+	KNNParams                    = svm.KNNParams
+	NeuralNetworkParams          = ann.NeuralNetworkParams
+)
+
+func NewLogisticRegression(params LogisticRegressionParams) *LogisticRegression {
+	return lr.NewLogisticRegression(params)
+}
+
+func NewLogisticRegressionStream(params LogisticRegressionParams) *LogisticRegressionStream {
+	return lr.NewLogisticRegressionStream(params)
+}
+
+func NewFTRLLogisticRegression(params FTRLLogisticRegressionParams) *FTRLLogisticRegression {
+	return lr.NewFTRLLogisticRegression(params)
+}
+
+func NewEPLogisticRegression(params EPLogisticRegressionParams) *EPLogisticRegression {
+	return lr.NewEPLogisticRegression(params)
+}
+
+func NewLROWLQN(params LROWLQNParams) *LROWLQN {
+	return lr.NewLROWLQN(params)
+}
+
+func NewFactorizeMachine(params FactorizeMachineParams) *FactorizeMachine {
+	return fm.NewFactorizeMachine(params)
+}
+
+func NewSAOptAUC() *SAOptAUC {
+	return sa.NewSAOptAUC()
+}
+
+func NewRegressionTree(params RegressionTreeParams) *RegressionTree {
+	return dt.NewRegressionTree(params)
+}
+
+func NewGBDT(params GBDTParams) *GBDT {
+	return dt.NewGBDT(params)
+}
+
+// NewRankNet returns a linear ranker learning the order of the pairs of samples of
+// every query, the samples grouped by their Qid.
+func NewRankNet(params RankNetParams) *RankNet {
+	return lr.NewRankNet(params)
+}
+
+// NewLambdaMART returns boosted regression trees optimizing the NDCG of every query,
+// the samples grouped by their Qid.
+func NewLambdaMART(params LambdaMARTParams) *LambdaMART {
+	return dt.NewLambdaMART(params)
+}
+
+func NewSVM(params SVMParams) *SVM {
+	return svm.NewSVM(params)
+}
+
+func NewLinearSVM(params SVMParams) *LinearSVM {
+	return svm.NewLinearSVM(params)
+}
+
+func NewL1VM(params L1VMParams) *L1VM {
+	return svm.NewL1VM(params)
+}
+
+func NewCART(params CARTParams) *CART {
+	return dt.NewCART(params)
+}
+
+func NewRandomForest(params RandomForestParams) *RandomForest {
+	return dt.NewRandomForest(params)
+}
+
+func NewRandomDecisionTree(params RDTParams) *RandomDecisionTree {
+	return dt.NewRandomDecisionTree(params)
+}
+
+func NewKNN(params KNNParams) *KNN {
+	return svm.NewKNN(params)
+}
+
+func NewNeuralNetwork(params NeuralNetworkParams) *NeuralNetwork {
+	return ann.NewNeuralNetwork(params)
+}
+
+// TrainStream trains the classifier on the samples of dataset as they come, and returns
+// the progressive validation of the predictions it made of them before learning each.
+// The metrics so far are logged every reportEvery samples if it is positive.
+func TrainStream(c StreamingClassifier, dataset *core.StreamingDataSet, reportEvery int) *eval.ProgressiveValidation {
+	return classifier.TrainStream(c, dataset, reportEvery)
+}
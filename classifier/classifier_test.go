@@ -0,0 +1,191 @@
+package classifier_test
+
+import (
+	"bytes"
+	"github.com/pantsing/hector/classifier"
+	"github.com/pantsing/hector/core"
+	"github.com/pantsing/hector/eval"
+	"github.com/pantsing/hector/model"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+// separable returns samples labeled 1 if feature 1 is set, and 0 if feature 2 is set.
+func separable(n int) *core.DataSet {
+	dataset := core.NewDataSet()
+	for i := 0; i < n; i++ {
+		sample := core.NewSample()
+		sample.Label = i % 2
+		sample.AddFeature(core.Feature{Id: int64(2 - sample.Label), Value: 1})
+		sample.AddFeature(core.Feature{Id: 3, Value: 1})
+		dataset.AddSample(sample)
+	}
+	return dataset
+}
+
+func TestTrainSaveLoad(t *testing.T) {
+	dataset := separable(100)
+	params := classifier.FTRLLogisticRegressionParams{Alpha: 0.1, Beta: 1, Lambda1: 0.01, Lambda2: 0.01, Steps: 5}
+	c := classifier.NewFTRLLogisticRegression(params)
+	c.Train(dataset)
+
+	var buf bytes.Buffer
+	h := &model.Header{Algorithm: "ftrl", Task: model.TaskBinary}
+	if err := model.Write(&buf, h, c); err != nil {
+		t.Fatal(err)
+	}
+	// The weights of FTRL depend on its params, which the model body does not hold.
+	loaded := classifier.NewFTRLLogisticRegression(params)
+	if _, err := model.Read(&buf, loaded); err != nil {
+		t.Fatal(err)
+	}
+
+	predictions := []*eval.LabelPrediction{}
+	for _, sample := range dataset.Samples {
+		p := loaded.Predict(sample)
+		if p != c.Predict(sample) {
+			t.Fatalf("loaded model predicts %v, trained one %v", p, c.Predict(sample))
+		}
+		predictions = append(predictions, &eval.LabelPrediction{Label: sample.Label, Prediction: p})
+	}
+	if auc := eval.AUC(predictions); auc < 0.99 {
+		t.Errorf("AUC is %v on a separable data set", auc)
+	}
+}
+
+func TestTrainStream(t *testing.T) {
+	dataset := core.NewStreamingDataSet()
+	go func() {
+		for _, sample := range separable(1000).Samples {
+			dataset.AddSample(sample)
+		}
+		close(dataset.Samples)
+	}()
+	params := classifier.LogisticRegressionParams{LearningRate: 0.1, Regularization: 0.01, Steps: 1}
+	c := classifier.NewLogisticRegressionStream(params)
+	validation := classifier.TrainStream(c, dataset, 0)
+	if validation.Count != 1000 {
+		t.Errorf("progressive validation counted %d samples, want 1000", validation.Count)
+	}
+	if auc := validation.AUC(); auc < 0.99 {
+		t.Errorf("progressive validation AUC is %v on a separable data set", auc)
+	}
+}
+
+func TestWarmStartGBDT(t *testing.T) {
+	dataset := separable(100)
+	p := classifier.GBDTParams{TreeCount: 2, LearningRate: 0.1, Tree: classifier.RegressionTreeParams{MaxDepth: 3, MinLeafSize: 10}}
+	c := classifier.NewGBDT(p)
+	c.Train(dataset)
+	var buf bytes.Buffer
+	h := &model.Header{Algorithm: "gbdt", Task: model.TaskBinary}
+	if err := model.Write(&buf, h, c); err != nil {
+		t.Fatal(err)
+	}
+
+	warm := classifier.NewGBDT(p)
+	if _, err := model.Read(&buf, warm); err != nil {
+		t.Fatal(err)
+	}
+	warm.WarmStart()
+	warm.Train(dataset)
+	buf.Reset()
+	if err := model.Write(&buf, h, warm); err != nil {
+		t.Fatal(err)
+	}
+	if trees := bytes.Count(buf.Bytes(), []byte("\n#\n")); trees != 4 {
+		t.Errorf("warm started GBDT has %d trees, want 4", trees)
+	}
+}
+
+// continuous returns samples of 5 continuous features, labeled 1 if the first two add
+// up to more than 1.
+func continuous(n int) *core.DataSet {
+	r := rand.New(rand.NewSource(3))
+	dataset := core.NewDataSet()
+	for i := 0; i < n; i++ {
+		sample := core.NewSample()
+		for id := int64(1); id <= 5; id++ {
+			sample.AddFeature(core.Feature{Id: id, Value: r.Float64()})
+		}
+		if sample.Features[0].Value+sample.Features[1].Value > 1 {
+			sample.Label = 1
+		}
+		dataset.AddSample(sample)
+	}
+	return dataset
+}
+
+func TestSeed(t *testing.T) {
+	algorithms := map[string]func() classifier.Seeder{
+		"rf": func() classifier.Seeder {
+			return classifier.NewRandomForest(classifier.RandomForestParams{TreeCount: 8, FeatureCount: 0.7, Tree: classifier.CARTParams{MaxDepth: 5, MinLeafSize: 5, GiniThreshold: 1, SamplingRatio: 0.8}})
+		},
+		"rdt": func() classifier.Seeder {
+			return classifier.NewRandomDecisionTree(classifier.RDTParams{TreeCount: 8, MinLeafSize: 5, MaxDepth: 5})
+		},
+		"cart": func() classifier.Seeder {
+			return classifier.NewCART(classifier.CARTParams{MaxDepth: 5, MinLeafSize: 5, GiniThreshold: 1, SamplingRatio: 0.5})
+		},
+		"ann": func() classifier.Seeder {
+			return classifier.NewNeuralNetwork(classifier.NeuralNetworkParams{LearningRate: 0.1, LearningRateDiscount: 0.9, Hidden: 3, Steps: 2, Verbose: 1})
+		},
+	}
+	train := func(newSeeder func() classifier.Seeder, seed int64) []byte {
+		c := newSeeder()
+		c.Seed(seed)
+		c.Train(continuous(200))
+		var buf bytes.Buffer
+		if err := c.SaveModel(&buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	for name, newSeeder := range algorithms {
+		if !bytes.Equal(train(newSeeder, 7), train(newSeeder, 7)) {
+			t.Errorf("%s trains different models with the same seed", name)
+		}
+		if bytes.Equal(train(newSeeder, 7), train(newSeeder, 8)) {
+			t.Errorf("%s trains the same model with different seeds", name)
+		}
+	}
+}
+
+// queries returns n queries of 8 samples, graded 0 to 2 by the sum of features 1 and 2,
+// feature 2 weighing more, and a noise feature 3.
+func queries(n int, r *rand.Rand) *core.DataSet {
+	dataset := core.NewDataSet()
+	for q := 0; q < n; q++ {
+		for i := 0; i < 8; i++ {
+			sample := core.NewSample()
+			sample.Qid = strconv.Itoa(q)
+			x1, x2 := r.Float64(), r.Float64()
+			sample.Label = int(x1 + 2*x2)
+			sample.AddFeature(core.Feature{Id: 1, Value: x1})
+			sample.AddFeature(core.Feature{Id: 2, Value: x2})
+			sample.AddFeature(core.Feature{Id: 3, Value: r.Float64()})
+			dataset.AddSample(sample)
+		}
+	}
+	return dataset
+}
+
+func TestRankers(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	train, test := queries(50, r), queries(20, r)
+	rankers := map[string]classifier.Classifier{
+		"ranknet":    classifier.NewRankNet(classifier.RankNetParams{LearningRate: 0.1, Regularization: 0.001, Steps: 20, NDCGAt: 5}),
+		"lambdamart": classifier.NewLambdaMART(classifier.LambdaMARTParams{TreeCount: 20, LearningRate: 0.1, NDCGAt: 5, Tree: classifier.RegressionTreeParams{MaxDepth: 4, MinLeafSize: 5}}),
+	}
+	for name, c := range rankers {
+		c.Train(train)
+		predictions := []*eval.LabelPrediction{}
+		for _, sample := range test.Samples {
+			predictions = append(predictions, &eval.LabelPrediction{Label: sample.Label, Prediction: c.Predict(sample), Qid: sample.Qid})
+		}
+		if ndcg := eval.NDCG(predictions, 5); ndcg < 0.9 {
+			t.Errorf("%s: NDCG@5 is %v", name, ndcg)
+		}
+	}
+}
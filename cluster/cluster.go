@@ -0,0 +1,11 @@
+// Package cluster defines the interface of clustering algorithms. hector has no
+// clustering algorithm yet.
+package cluster
+
+import (
+	"github.com/pantsing/hector/core"
+)
+
+type Cluster interface {
+	Cluster(dataset *core.DataSet)
+}
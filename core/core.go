@@ -0,0 +1,146 @@
+/*
+Package core holds the data sets and samples the algorithms of hector train on.
+
+	dataset := core.NewDataSet()
+	if err := dataset.Load("train.tsv", -1); err != nil {
+		...
+	}
+
+Data sets are read in the libsvm like format described in the README. Set Mode to
+Lenient before loading to skip malformed lines instead of failing on the first one.
+*/
+package core
+
+import (
+	"github.com/pantsing/hector/internal/core"
+)
+
+type (
+	// DataSet is a labeled data set for classification.
+	DataSet = core.DataSet
+	// Sample is one line of a DataSet.
+	Sample = core.Sample
+	// Feature is a feature ID and its value.
+	Feature = core.Feature
+	// RealDataSet is a data set with real valued targets for regression.
+	RealDataSet = core.RealDataSet
+	// RealSample is one line of a RealDataSet.
+	RealSample = core.RealSample
+	// StreamingDataSet sends the samples of a file to a channel while it is read.
+	StreamingDataSet = core.StreamingDataSet
+	// Vector is a sparse vector, as used by the models.
+	Vector = core.Vector
+	// ArrayVector is a dense vector, as returned by multi-class predictions.
+	ArrayVector = core.ArrayVector
+	// ParseMode tells the loaders what to do with a malformed line.
+	ParseMode = core.ParseMode
+	// LineError reports a malformed line of a data set file.
+	LineError = core.LineError
+	// CrossValidation splits data sets into the train and test sets of folds.
+	CrossValidation = core.CrossValidation
+	// CVStrategy tells how cross validation assigns samples to folds.
+	CVStrategy = core.CVStrategy
+	// NegativeSampler keeps the positive samples and the negative ones at a rate.
+	NegativeSampler = core.NegativeSampler
+	// CSVFormat reads data sets from CSV or TSV files with a header line. Set the CSV
+	// field of a data set to load it from such a file.
+	CSVFormat = core.CSVFormat
+	// ColumnRole tells what a column of a CSV or TSV data set holds.
+	ColumnRole = core.ColumnRole
+	// FeatureDict maps the IDs of hashed features back to their names.
+	FeatureDict = core.FeatureDict
+	// Hasher turns feature names into IDs. Set the Hasher field of a data set to hash
+	// its names with another function, into fewer bits, signed or by namespace.
+	Hasher = core.Hasher
+	// HashStats counts the hashed feature names a loader saw and their collisions.
+	HashStats = core.HashStats
+)
+
+const (
+	Strict  = core.Strict
+	Lenient = core.Lenient
+
+	CVShuffle    = core.CVShuffle
+	CVStratified = core.CVStratified
+	CVGroup      = core.CVGroup
+	CVTime       = core.CVTime
+	CVSequential = core.CVSequential
+
+	RoleLabel       = core.RoleLabel
+	RoleWeight      = core.RoleWeight
+	RoleID          = core.RoleID
+	RoleQid         = core.RoleQid
+	RoleNumeric     = core.RoleNumeric
+	RoleCategorical = core.RoleCategorical
+	RoleIgnore      = core.RoleIgnore
+)
+
+func NewDataSet() *DataSet {
+	return core.NewDataSet()
+}
+
+func NewSample() *Sample {
+	return core.NewSample()
+}
+
+func NewRealDataSet() *RealDataSet {
+	return core.NewRealDataSet()
+}
+
+func NewRealSample() *RealSample {
+	return core.NewRealSample()
+}
+
+func NewStreamingDataSet() *StreamingDataSet {
+	return core.NewStreamingDataSet()
+}
+
+func NewVector() *Vector {
+	return core.NewVector()
+}
+
+// NewNegativeSampler returns a sampler keeping negative samples at rate, which is in
+// (0,1], drawing from a source seeded with seed.
+func NewNegativeSampler(rate float64, seed int64) *NegativeSampler {
+	return core.NewNegativeSampler(rate, seed)
+}
+
+// Recalibrate corrects the probability p predicted by a model trained on negative
+// samples kept at rate back to the base rate of the data they were drawn from.
+func Recalibrate(p, rate float64) float64 {
+	return core.Recalibrate(p, rate)
+}
+
+// InputFiles returns the files a data set path names, in the order they are loaded: the
+// files matching a glob pattern, the files of a directory, or the path itself.
+func InputFiles(path string) ([]string, error) {
+	return core.InputFiles(path)
+}
+
+// IsCache tells whether the file at path is a data set cache, written by WriteCache or
+// hector convert, which loaders read in place of text.
+func IsCache(path string) bool {
+	return core.IsCache(path)
+}
+
+// NewFeatureDict returns the dictionary of the hashed features among names, such as the
+// FeatureNameIdMap of a data set.
+func NewFeatureDict(names map[int64]string) FeatureDict {
+	return core.NewFeatureDict(names)
+}
+
+// HashFuncs returns the names of the hash functions of Hasher.
+func HashFuncs() []string {
+	return core.HashFuncs()
+}
+
+// ParseHasher returns the Hasher whose String is spec, as recorded in model files.
+func ParseHasher(spec string) (*Hasher, error) {
+	return core.ParseHasher(spec)
+}
+
+// LoadFeatureDict reads a feature dictionary written by FeatureDict.Save or by train
+// --feature-dict.
+func LoadFeatureDict(path string) (FeatureDict, error) {
+	return core.LoadFeatureDict(path)
+}
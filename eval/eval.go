@@ -0,0 +1,230 @@
+// Package eval computes evaluation metrics of predictions.
+package eval
+
+import (
+	"github.com/pantsing/hector/internal/algorithms/eval"
+)
+
+type (
+	// LabelPrediction is the prediction of a classifier for a sample with the label Label.
+	LabelPrediction = eval.LabelPrediction
+	// RealPrediction is the prediction of a regressor for a sample with the value Value.
+	RealPrediction = eval.RealPrediction
+	// ProgressiveValidation accumulates the log loss and AUC of the predictions an
+	// online learner makes of samples before learning from them, in bounded memory.
+	ProgressiveValidation = eval.ProgressiveValidation
+	// Report holds the metrics of the predictions of a binary classifier, and writes them
+	// as a table or as JSON.
+	Report = eval.Report
+	// Confusion counts the predictions of a binary classifier at a threshold.
+	Confusion = eval.Confusion
+	// CalibrationBin holds the mean prediction and the share of positive samples of the
+	// predictions in a range.
+	CalibrationBin = eval.CalibrationBin
+	// LiftBin holds the lift and gain of a decile of the predictions.
+	LiftBin = eval.LiftBin
+	// MultiClassPrediction is the probability of every class of a sample of the class Label.
+	MultiClassPrediction = eval.MultiClassPrediction
+	// MultiClassReport holds the metrics of the predictions of a multi-class classifier,
+	// and writes them as a table or as JSON.
+	MultiClassReport = eval.MultiClassReport
+	// ClassMetrics holds the one-vs-rest metrics of a class.
+	ClassMetrics = eval.ClassMetrics
+	// RegressionReport holds the metrics of the predictions of a regressor and the histogram
+	// of their residuals, and writes them as a table or as JSON.
+	RegressionReport = eval.RegressionReport
+	// RegressionMetric is a named metric of the predictions of a regressor.
+	RegressionMetric = eval.RegressionMetric
+	// MetricValue is the value of a named metric.
+	MetricValue = eval.MetricValue
+	// HistogramBin counts the residuals in a range.
+	HistogramBin = eval.HistogramBin
+	// RankingReport holds the ranking metrics of predictions grouped by query.
+	RankingReport = eval.RankingReport
+)
+
+// NewRankingReport returns the NDCG, MAP, MRR and group AUC of the predictions grouped
+// by Qid.
+func NewRankingReport(predictions []*LabelPrediction) *RankingReport {
+	return eval.NewRankingReport(predictions)
+}
+
+// NDCG returns the mean normalized discounted cumulative gain at k of the queries with
+// a positive sample.
+func NDCG(predictions []*LabelPrediction, k int) float64 {
+	return eval.NDCG(predictions, k)
+}
+
+// MAP returns the mean average precision of the queries with a positive sample.
+func MAP(predictions []*LabelPrediction) float64 {
+	return eval.MAP(predictions)
+}
+
+// MRR returns the mean reciprocal rank of the first positive sample of the queries
+// with one.
+func MRR(predictions []*LabelPrediction) float64 {
+	return eval.MRR(predictions)
+}
+
+// GroupAUC returns the mean AUC of the queries, weighted by their number of samples.
+func GroupAUC(predictions []*LabelPrediction) float64 {
+	return eval.GroupAUC(predictions)
+}
+
+// RegressionMetrics are the metrics of regressors known by name.
+var RegressionMetrics = eval.RegressionMetrics
+
+// ParseRegressionMetrics returns the metrics of a comma separated list of names, such as
+// "rmse,mae,pinball@0.9", or all of RegressionMetrics if the list is empty.
+func ParseRegressionMetrics(list string) ([]RegressionMetric, error) {
+	return eval.ParseRegressionMetrics(list)
+}
+
+// NewRegressionReport returns the report of the metrics of the predictions. Metrics of
+// the predictive standard deviations are left out unless hasStd.
+func NewRegressionReport(predictions []*RealPrediction, metrics []RegressionMetric, hasStd bool) *RegressionReport {
+	return eval.NewRegressionReport(predictions, metrics, hasStd)
+}
+
+// RegMAE returns the mean absolute error of the predictions.
+func RegMAE(predictions []*RealPrediction) float64 {
+	return eval.RegMAE(predictions)
+}
+
+// RegMAPE returns the mean absolute error relative to the value, over the non zero values.
+func RegMAPE(predictions []*RealPrediction) float64 {
+	return eval.RegMAPE(predictions)
+}
+
+// RegR2 returns the coefficient of determination of the predictions.
+func RegR2(predictions []*RealPrediction) float64 {
+	return eval.RegR2(predictions)
+}
+
+// RegExplainedVariance returns the explained variance of the predictions.
+func RegExplainedVariance(predictions []*RealPrediction) float64 {
+	return eval.RegExplainedVariance(predictions)
+}
+
+// RegMedianAE returns the median absolute error of the predictions.
+func RegMedianAE(predictions []*RealPrediction) float64 {
+	return eval.RegMedianAE(predictions)
+}
+
+// PinballLoss returns the quantile loss of the predictions taken as the quantile q.
+func PinballLoss(predictions []*RealPrediction, q float64) float64 {
+	return eval.PinballLoss(predictions, q)
+}
+
+// NLPD returns the mean negative log predictive density of the values under normal
+// distributions of mean Prediction and standard deviation Std.
+func NLPD(predictions []*RealPrediction) float64 {
+	return eval.NLPD(predictions)
+}
+
+// ResidualHistogram counts the residuals of the predictions in n bins of equal width.
+func ResidualHistogram(predictions []*RealPrediction, n int) []HistogramBin {
+	return eval.ResidualHistogram(predictions, n)
+}
+
+// NewMultiClassReport returns the report of the predictions of a multi-class classifier.
+func NewMultiClassReport(predictions []*MultiClassPrediction) *MultiClassReport {
+	return eval.NewMultiClassReport(predictions)
+}
+
+// MultiClassAccuracy returns the share of predictions whose most probable class is the label.
+func MultiClassAccuracy(predictions []*MultiClassPrediction) float64 {
+	return eval.MultiClassAccuracy(predictions)
+}
+
+// TopKAccuracy returns the share of predictions whose label is among the k most probable classes.
+func TopKAccuracy(predictions []*MultiClassPrediction, k int) float64 {
+	return eval.TopKAccuracy(predictions, k)
+}
+
+// MultiClassLogLoss returns the mean negative log of the probability of the label.
+func MultiClassLogLoss(predictions []*MultiClassPrediction) float64 {
+	return eval.MultiClassLogLoss(predictions)
+}
+
+// OneVsRestAUC returns the AUC of the probability of the class c to tell its samples from the others.
+func OneVsRestAUC(predictions []*MultiClassPrediction, c int) float64 {
+	return eval.OneVsRestAUC(predictions, c)
+}
+
+// NewReport returns the report of the predictions, whose confusion matrix metrics are
+// those at the threshold.
+func NewReport(predictions []*LabelPrediction, threshold float64) *Report {
+	return eval.NewReport(predictions, threshold)
+}
+
+// NewConfusion counts the predictions at the threshold.
+func NewConfusion(predictions []*LabelPrediction, threshold float64) Confusion {
+	return eval.NewConfusion(predictions, threshold)
+}
+
+// OptimalThreshold returns the threshold with the highest F1.
+func OptimalThreshold(predictions []*LabelPrediction) float64 {
+	return eval.OptimalThreshold(predictions)
+}
+
+// PRAUC returns the area under the precision recall curve.
+func PRAUC(predictions []*LabelPrediction) float64 {
+	return eval.PRAUC(predictions)
+}
+
+// KS returns the Kolmogorov-Smirnov statistic of the predictions.
+func KS(predictions []*LabelPrediction) float64 {
+	return eval.KS(predictions)
+}
+
+// Brier returns the mean square difference between the predictions and the labels.
+func Brier(predictions []*LabelPrediction) float64 {
+	return eval.Brier(predictions)
+}
+
+// Calibration returns the calibration of the predictions in n bins over [0, 1].
+func Calibration(predictions []*LabelPrediction, n int) []CalibrationBin {
+	return eval.Calibration(predictions, n)
+}
+
+// Lift returns the lift and gain table of the predictions by decile.
+func Lift(predictions []*LabelPrediction) []LiftBin {
+	return eval.Lift(predictions)
+}
+
+// AUC returns the area under the ROC curve of the predictions.
+func AUC(predictions []*LabelPrediction) float64 {
+	return eval.AUC(predictions)
+}
+
+// RMSE returns the root mean square error of the predictions against the labels.
+func RMSE(predictions []*LabelPrediction) float64 {
+	return eval.RMSE(predictions)
+}
+
+// LogLoss returns the mean log loss of the predictions.
+func LogLoss(predictions []*LabelPrediction) float64 {
+	return eval.LogLoss(predictions)
+}
+
+// ErrorRate returns the fraction of predictions on the wrong side of 0.5.
+func ErrorRate(predictions []*LabelPrediction) float64 {
+	return eval.ErrorRate(predictions)
+}
+
+// BalanceErrorRate returns the error rate of predictions made on a set whose negative
+// samples were sub-sampled at the rate ssr.
+func BalanceErrorRate(predictions []*LabelPrediction, ssr float64) float64 {
+	return eval.BalanceErrorRate(predictions, ssr)
+}
+
+// RegRMSE returns the root mean square error of regression predictions.
+func RegRMSE(predictions []*RealPrediction) float64 {
+	return eval.RegRMSE(predictions)
+}
+
+// MeanStd returns the mean and the standard deviation of the values.
+func MeanStd(values []float64) (mean, std float64) {
+	return eval.MeanStd(values)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"github.com/pantsing/hector/internal"
 	"github.com/urfave/cli"
 	"os"
@@ -14,5 +15,8 @@ func main() {
 	app.Version = "1.0.0"
 	app.Commands = internal.Commands
 	sort.Sort(cli.CommandsByName(app.Commands))
-	app.Run(os.Args)
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
@@ -3,8 +3,10 @@ package algorithms
 import (
 	"github.com/pantsing/hector/internal/algorithms/classifier"
 	"github.com/pantsing/hector/internal/algorithms/cluster"
+	"github.com/pantsing/hector/internal/algorithms/internal"
 	"github.com/pantsing/hector/internal/algorithms/regressor"
 	"github.com/urfave/cli"
+	"reflect"
 	"sort"
 )
 
@@ -13,6 +15,22 @@ func Commands() []cli.Command {
 	cmds = append(cmds, classifier.Commands()...)
 	cmds = append(cmds, cluster.Commands()...)
 	cmds = append(cmds, regressor.Commands()...)
+	cmds = append(cmds, inferCommands()...)
 	sort.Sort(cli.CommandsByName(cmds))
 	return cmds
 }
+
+// New returns a new instance of the algorithm with the command or dispatch name name,
+// or nil if there is no such algorithm.
+func New(name string) internal.Algorithm {
+	algos := classifier.Algorithms()
+	for key, algo := range regressor.Algorithms() {
+		algos[key] = algo
+	}
+	for key, algo := range algos {
+		if key == name || algo.Command().Name == name {
+			return reflect.New(reflect.TypeOf(algo).Elem()).Interface().(internal.Algorithm)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package algorithms
+
+import (
+	"fmt"
+	"github.com/pantsing/hector/internal/algorithms/classifier"
+	"github.com/pantsing/hector/internal/algorithms/internal"
+	"github.com/pantsing/hector/internal/algorithms/regressor"
+	"github.com/pantsing/hector/internal/model"
+	"github.com/pantsing/hector/internal/params"
+	"github.com/urfave/cli"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// commands are the commands of the algorithms, which Commands makes only once.
+var commands = Commands()
+
+// run runs hector with the arguments args after the program name.
+func run(t *testing.T, args ...string) {
+	app := cli.NewApp()
+	app.Commands = commands
+	if err := app.Run(append([]string{"hector"}, args...)); err != nil {
+		t.Fatalf("%v: %v", args, err)
+	}
+}
+
+// changedValues returns a valid value other than the default for every param of p.
+func changedValues(t *testing.T, name string, p interface{}) map[string]string {
+	working := reflect.New(reflect.TypeOf(p).Elem()).Interface()
+	params.Defaults(working)
+	defaults := params.Values(working)
+	changed := make(map[string]string)
+	for _, key := range params.Names(working) {
+		value := defaults[key]
+		var candidates []string
+		if b, err := strconv.ParseBool(value); err == nil {
+			candidates = append(candidates, strconv.FormatBool(!b))
+		}
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			// Int params reject the fractions.
+			for _, c := range []float64{f + 1, f - 1, f / 2} {
+				candidates = append(candidates, strconv.FormatFloat(c, 'g', -1, 64))
+			}
+		}
+		for _, candidate := range candidates {
+			if params.Set(working, key, candidate) == nil && params.Validate(working) == nil {
+				changed[key] = candidate
+				break
+			}
+			params.Set(working, key, value)
+		}
+		if _, ok := changed[key]; !ok {
+			t.Errorf("%s: found no valid value for %s besides %s", name, key, value)
+			changed[key] = value
+		}
+	}
+	return changed
+}
+
+func TestParamsReachableFromCommandLine(t *testing.T) {
+	algos := classifier.Algorithms()
+	for name, algo := range regressor.Algorithms() {
+		algos[name] = algo
+	}
+	for name, algo := range algos {
+		want := changedValues(t, name, algo.Parameters())
+		cmd := internal.ActionCommand(algo, nil, internal.Actions{
+			Train: func(ctx *cli.Context) error { return params.Bind(ctx, algo.Parameters()) },
+		})
+		args := []string{"hector", cmd.Name, internal.ActionTrain}
+		for key, value := range want {
+			args = append(args, "--"+key+"="+value)
+		}
+		app := cli.NewApp()
+		app.Commands = []cli.Command{cmd}
+		if err := app.Run(args); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if got := params.Values(algo.Parameters()); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: params are %v after %v, want %v", name, got, args, want)
+		}
+	}
+}
+
+func TestCrossValidationKeepsFinalModel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hector-algorithms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var lines []string
+	for i := 0; i < 60; i++ {
+		lines = append(lines, fmt.Sprintf("%d 1:%d 2:%d", i%2, i%7, i%2*3+i%5))
+	}
+	train := filepath.Join(dir, "train")
+	if err := ioutil.WriteFile(train, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every fold and the final model start from no trees.
+	for _, name := range []string{"rf", "rdt"} {
+		for _, cv := range []string{"0", "3"} {
+			path := filepath.Join(dir, name+".cv"+cv)
+			run(t, name, internal.ActionTrain, "--train", train, "--tc", "5", "--cv", cv, "--model", path)
+			_, body, err := model.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if trees := strings.Count(string(body), "\n#\n"); trees != 5 {
+				t.Errorf("%s --cv %s saved %d trees, want 5", name, cv, trees)
+			}
+		}
+	}
+}
+
+func TestPredictWithRecordedFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hector-algorithms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	lines := []string{"click,age,country"}
+	for i := 0; i < 40; i++ {
+		lines = append(lines, fmt.Sprintf("%d,%d,%s", i%2, i%5, []string{"fr", "us", "de"}[i%3]))
+	}
+	data, schema := filepath.Join(dir, "clicks.csv"), filepath.Join(dir, "schema")
+	path := filepath.Join(dir, "model")
+	if err := ioutil.WriteFile(data, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(schema, []byte("click:label\ncountry:categorical\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run(t, "ftrl", internal.ActionTrain, "--train", data, "--format", "csv", "--schema", schema, "--model", path)
+
+	// predict reads the input as the train set was read unless told otherwise.
+	recorded, given := filepath.Join(dir, "recorded"), filepath.Join(dir, "given")
+	run(t, "ftrl", internal.ActionPredict, "--input", data, "--model", path, "--predict", recorded)
+	run(t, "ftrl", internal.ActionPredict, "--input", data, "--model", path, "--predict", given,
+		"--format", "csv", "--schema", schema)
+	got, err := ioutil.ReadFile(recorded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ioutil.ReadFile(given)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) || strings.Count(string(got), "\n") != 40 {
+		t.Errorf("predictions with the recorded format:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -3,19 +3,21 @@ package ann
 import (
 	"fmt"
 	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/hector/internal/model"
 	"github.com/pantsing/hector/internal/utils"
 	"github.com/urfave/cli"
+	"io"
 	"math"
 	"math/rand"
 )
 
 type NeuralNetworkParams struct {
-	LearningRate         float64
-	LearningRateDiscount float64
-	Regularization       float64
-	Hidden               int64
-	Steps                int
-	Verbose              int
+	LearningRate         float64 `flag:"learning-rate,lrate" default:"0.1" min:"0" usage:"Learning rate"`
+	LearningRateDiscount float64 `flag:"learning-rate-discount,lRateDiscount" default:"1" min:"0" max:"1" usage:"Factor the learning rate is multiplied by after every step"`
+	Regularization       float64 `flag:"regularization,r" default:"0.01" min:"0" usage:"L2 regularization"`
+	Hidden               int64   `flag:"hidden" default:"10" min:"1" usage:"Number of hidden units"`
+	Steps                int     `flag:"steps" default:"10" min:"1" usage:"Number of passes over the train set"`
+	Verbose              int     `flag:"verbose" default:"0" usage:"1: log the training progress"`
 }
 
 type TwoLayerWeights struct {
@@ -31,23 +33,74 @@ type NeuralNetwork struct {
 	Model    TwoLayerWeights
 	MaxLabel int64
 	Params   NeuralNetworkParams
+	warm     bool
+	rng      *rand.Rand
 }
 
-func RandomInitVector(dim int64) *core.Vector {
+func RandomInitVector(dim int64, r *rand.Rand) *core.Vector {
 	v := core.NewVector()
 	var i int64
 	for i = 0; i < dim; i++ {
-		v.Data[i] = (rand.Float64() - 0.5) / math.Sqrt(float64(dim))
+		v.Data[i] = (r.Float64() - 0.5) / math.Sqrt(float64(dim))
 	}
 	return v
 }
 
-func (self *NeuralNetwork) SaveModel(path string) {
-
+/*
+SaveModel writes the hidden layer size and MaxLabel on the first line, then one line per
+weight: the layer (1 or 2), the row, the column and the weight, sorted by row and column.
+*/
+func (self *NeuralNetwork) SaveModel(w io.Writer) error {
+	sb := utils.StringBuilder{}
+	sb.Int64(self.Params.Hidden)
+	sb.Write("\t")
+	sb.Int64(self.MaxLabel)
+	sb.Write("\n")
+	for layer, m := range []*core.Matrix{self.Model.L1, self.Model.L2} {
+		if m == nil {
+			continue
+		}
+		for _, i := range m.SortedKeys() {
+			row := m.Data[i]
+			for _, j := range row.SortedKeys() {
+				w := row.Data[j]
+				sb.Int(layer + 1)
+				sb.Write("\t")
+				sb.Int64(i)
+				sb.Write("\t")
+				sb.Int64(j)
+				sb.Write("\t")
+				sb.Float(w)
+				sb.Write("\n")
+			}
+		}
+	}
+	_, err := sb.WriteTo(w)
+	return err
 }
 
-func (self *NeuralNetwork) LoadModel(path string) {
-
+func (self *NeuralNetwork) LoadModel(r io.Reader) error {
+	self.Model = TwoLayerWeights{L1: core.NewMatrix(), L2: core.NewMatrix()}
+	scaner := model.NewScanner(r)
+	if scaner.Scan() {
+		self.Params.Hidden = scaner.Int64(0)
+		self.MaxLabel = scaner.Int64(1)
+	}
+	for i := int64(0); i < self.Params.Hidden; i++ {
+		self.Model.L1.Data[i] = core.NewVector()
+	}
+	for scaner.Scan() {
+		i, j, w := scaner.Int64(1), scaner.Int64(2), scaner.Float(3)
+		switch scaner.String(0) {
+		case "1":
+			self.Model.L1.SetValue(i, j, w)
+		case "2":
+			self.Model.L2.SetValue(i, j, w)
+		default:
+			scaner.Errorf("unknown layer %q", scaner.String(0))
+		}
+	}
+	return scaner.Err()
 }
 
 func (algo *NeuralNetwork) Command() cli.Command {
@@ -55,51 +108,63 @@ func (algo *NeuralNetwork) Command() cli.Command {
 		Name:     "ann",
 		Usage:    "ANN",
 		Category: "ANN",
-		Flags: []cli.Flag{
-			cli.Float64Flag{
-				Name: "learning-rate,lrate",
-			},
-			cli.Float64Flag{
-				Name: "learning-rate-discount,lRateDiscount",
-			},
-			cli.Float64Flag{
-				Name: "regularization,r",
-			},
-			cli.Int64Flag{
-				Name: "hidden",
-			},
-			cli.IntFlag{
-				Name: "steps",
-			},
-			cli.IntFlag{
-				Name: "verbose",
-			},
-		},
-	}
-}
-
-func (algo *NeuralNetwork) Init(ctx *cli.Context) {
-	algo.Params.LearningRate = ctx.Float64("learning-rate")
-	algo.Params.LearningRateDiscount = ctx.Float64("learning-rate-discount")
-	algo.Params.Regularization = ctx.Float64("regularization")
-	algo.Params.Steps = ctx.Int("steps")
-	algo.Params.Hidden = ctx.Int64("hidden")
-	algo.Params.Verbose = ctx.Int("verbose")
-}
-
-func (algo *NeuralNetwork) Clear() {}
+	}
+}
+
+func NewNeuralNetwork(params NeuralNetworkParams) *NeuralNetwork {
+	return &NeuralNetwork{Params: params, rng: rand.New(rand.NewSource(1))}
+}
+
+// Seed makes Train draw the initial weights from a source seeded with seed.
+func (algo *NeuralNetwork) Seed(seed int64) {
+	algo.rng = rand.New(rand.NewSource(seed))
+}
+
+func (algo *NeuralNetwork) Parameters() interface{} {
+	return &algo.Params
+}
+
+func (algo *NeuralNetwork) Init() {
+	*algo = *NewNeuralNetwork(algo.Params)
+}
+
+func (algo *NeuralNetwork) Clear() {
+	algo.warm = false
+}
+
+// WarmStart makes Train keep the loaded weights, initializing only the ones of features
+// and labels the model has not seen.
+func (algo *NeuralNetwork) WarmStart() {
+	algo.warm = true
+}
 
 func (algo *NeuralNetwork) Train(dataset *core.DataSet) {
-	algo.Model = TwoLayerWeights{}
-	algo.Model.L1 = core.NewMatrix()
-	algo.Model.L2 = core.NewMatrix()
+	if algo.rng == nil {
+		algo.Seed(1)
+	}
+	old_max_label := int64(-1)
+	if algo.warm {
+		old_max_label = algo.MaxLabel
+	} else {
+		algo.Model = TwoLayerWeights{}
+		algo.Model.L1 = core.NewMatrix()
+		algo.Model.L2 = core.NewMatrix()
 
-	for i := int64(0); i < algo.Params.Hidden; i++ {
-		algo.Model.L1.Data[i] = core.NewVector()
+		for i := int64(0); i < algo.Params.Hidden; i++ {
+			algo.Model.L1.Data[i] = core.NewVector()
+		}
 	}
 
 	initalized := make(map[int64]int)
+	if algo.warm {
+		for id := range algo.Model.L1.Data[0].Data {
+			initalized[id] = 1
+		}
+	}
 	max_label := 0
+	if algo.warm {
+		max_label = int(old_max_label)
+	}
 	for _, sample := range dataset.Samples {
 		if max_label < sample.Label {
 			max_label = sample.Label
@@ -108,7 +173,7 @@ func (algo *NeuralNetwork) Train(dataset *core.DataSet) {
 			_, ok := initalized[f.Id]
 			if !ok {
 				for i := int64(0); i < algo.Params.Hidden; i++ {
-					algo.Model.L1.SetValue(i, f.Id, (rand.Float64() - 0.5) / math.Sqrt(float64(algo.Params.Hidden)))
+					algo.Model.L1.SetValue(i, f.Id, (algo.rng.Float64() - 0.5) / math.Sqrt(float64(algo.Params.Hidden)))
 				}
 				initalized[f.Id] = 1
 			}
@@ -117,8 +182,8 @@ func (algo *NeuralNetwork) Train(dataset *core.DataSet) {
 	algo.MaxLabel = int64(max_label)
 
 	for i := int64(0); i <= algo.Params.Hidden; i++ {
-		for j := int64(0); j <= algo.MaxLabel; j++ {
-			algo.Model.L2.SetValue(i, j, (rand.NormFloat64() / math.Sqrt(float64(algo.MaxLabel) + 1.0)))
+		for j := old_max_label + 1; j <= algo.MaxLabel; j++ {
+			algo.Model.L2.SetValue(i, j, (algo.rng.NormFloat64() / math.Sqrt(float64(algo.MaxLabel) + 1.0)))
 		}
 	}
 
@@ -153,6 +218,7 @@ func (algo *NeuralNetwork) Train(dataset *core.DataSet) {
 			z = z.SoftMaxNorm()
 			e.SetValue(int64(sample.Label), 1.0)
 			e.AddVector(z, -1.0)
+			e.ApplyScale(sample.GetWeight())
 
 			for i := int64(0); i <= algo.Params.Hidden; i++ {
 				delta := float64(0)
@@ -0,0 +1,33 @@
+package ann
+
+import (
+	"bytes"
+	"github.com/pantsing/hector/internal/core"
+	"testing"
+)
+
+func TestNeuralNetworkSaveLoad(t *testing.T) {
+	dataset := core.XORDataSet(300)
+	algo := &NeuralNetwork{Params: NeuralNetworkParams{LearningRate: 0.1, LearningRateDiscount: 0.9, Hidden: 5, Steps: 3, Verbose: 1}}
+	algo.Train(dataset)
+	var buf bytes.Buffer
+	if err := algo.SaveModel(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := &NeuralNetwork{}
+	if err := loaded.LoadModel(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.MaxLabel != algo.MaxLabel || loaded.Params.Hidden != algo.Params.Hidden {
+		t.Errorf("MaxLabel %d, Hidden %d after load, want %d, %d", loaded.MaxLabel, loaded.Params.Hidden, algo.MaxLabel, algo.Params.Hidden)
+	}
+	for i, sample := range dataset.Samples {
+		p, q := algo.PredictMultiClass(sample), loaded.PredictMultiClass(sample)
+		for label := 0; label <= int(algo.MaxLabel); label++ {
+			if p.GetValue(label) != q.GetValue(label) {
+				t.Fatalf("sample %d: prediction of label %d is %v after load, want %v", i, label, q.GetValue(label), p.GetValue(label))
+			}
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package classifier
 
 import (
+	"bufio"
 	"fmt"
 	"github.com/pantsing/hector/internal/algorithms/classifier/ann"
 	"github.com/pantsing/hector/internal/algorithms/classifier/common"
@@ -12,51 +13,133 @@ import (
 	"github.com/pantsing/hector/internal/algorithms/eval"
 	"github.com/pantsing/hector/internal/algorithms/internal"
 	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/hector/internal/model"
 	"github.com/pantsing/log"
 	"github.com/urfave/cli"
-	"math/rand"
-	"os"
+	"io"
 	"strconv"
-	"time"
 )
 
 func Commands() []cli.Command {
 	cmds := make([]cli.Command, 0, 1<<5)
-	for _, alog := range classifierIndex {
+	for name, alog := range classifierIndex {
 		cmd := alog.Command()
 		if _, ok := internal.AlogCmdsChecker[cmd.Name]; ok {
 			continue
 		}
 		internal.AlogCmdsChecker[cmd.Name] = struct{}{}
-		cmd.Flags = append(cmd.Flags, common.ClassifierCammandFlags...)
-		cmd.Action = AlgorithmRun
-		cmds = append(cmds, cmd)
+		var flags []cli.Flag
+		if _, ok := multiClassClassifierIndex[name]; ok {
+			flags = append(flags, common.MultiClassFlag)
+		}
+		runs := actions(name)
+		if _, ok := alog.(StreamingClassifier); ok {
+			runs.TrainFlags = append(runs.TrainFlags, common.StreamFlags...)
+		}
+		if _, ok := alog.(internal.WarmStarter); ok {
+			runs.TrainFlags = append(runs.TrainFlags, internal.InitModelFlag)
+		}
+		runs.TrainFlags = append(runs.TrainFlags, internal.NegSampleRateFlag)
+		cmds = append(cmds, internal.ActionCommand(alog, flags, runs))
 	}
-	for _, alog := range multiClassClassifierIndex {
+	for name, alog := range multiClassClassifierIndex {
 		cmd := alog.Command()
 		if _, ok := internal.AlogCmdsChecker[cmd.Name]; ok {
 			continue
 		}
 		internal.AlogCmdsChecker[cmd.Name] = struct{}{}
-		cmd.Flags = append(cmd.Flags, common.ClassifierCammandFlags...)
-		cmd.Action = MultiClassRun
-		cmds = append(cmds, cmd)
+		runs := multiClassActions(name)
+		if _, ok := alog.(internal.WarmStarter); ok {
+			runs.TrainFlags = append(runs.TrainFlags, internal.InitModelFlag)
+		}
+		cmds = append(cmds, internal.ActionCommand(alog, nil, runs))
 	}
 	return cmds
 }
 
+// actions dispatches to the multi-class runs when --multi-class is set.
+func actions(name string) internal.Actions {
+	multi := multiClassActions(name)
+	return internal.Actions{
+		Train: func(ctx *cli.Context) error {
+			if ctx.Bool("multi-class") {
+				return multi.Train(ctx)
+			}
+			if ctx.Bool("stream") {
+				return StreamTrainRun(ctx, name)
+			}
+			return TrainRun(ctx, name)
+		},
+		Test: func(ctx *cli.Context) error {
+			if ctx.Bool("multi-class") {
+				return multi.Test(ctx)
+			}
+			return TestRun(ctx, name)
+		},
+		Predict: func(ctx *cli.Context) error {
+			if ctx.Bool("multi-class") {
+				return multi.Predict(ctx)
+			}
+			return PredictRun(ctx, name)
+		},
+	}
+}
+
+func multiClassActions(name string) internal.Actions {
+	return internal.Actions{
+		Train:   func(ctx *cli.Context) error { return MultiClassTrainRun(ctx, name) },
+		Test:    func(ctx *cli.Context) error { return MultiClassTestRun(ctx, name) },
+		Predict: func(ctx *cli.Context) error { return MultiClassPredictRun(ctx, name) },
+	}
+}
+
 type Classifier interface {
 	internal.Algorithm
 	//Train model on a given dataset
 	Train(dataset *core.DataSet)
 	//Predict the probability of a sample to be positive sample
 	Predict(sample *core.Sample) float64
-	SaveModel(path string)
-	LoadModel(path string)
+	SaveModel(w io.Writer) error
+	LoadModel(r io.Reader) error
+}
+
+// Learner learns from one sample at a time.
+type Learner interface {
+	// Learn updates the model with a sample and returns the probability of the sample
+	// to be positive predicted before the update.
+	Learn(sample *core.Sample) float64
+}
+
+// StreamingClassifier is a classifier learning from one sample at a time, which can
+// train on a stream of samples too large to be held in memory.
+type StreamingClassifier interface {
+	Classifier
+	Learner
+}
+
+// Recalibrated is a Classifier trained on negative samples kept at NegSampleRate whose
+// predictions are corrected back to the base rate of the data they were drawn from.
+type Recalibrated struct {
+	Classifier
+	NegSampleRate float64
+}
+
+func (c *Recalibrated) Predict(sample *core.Sample) float64 {
+	return core.Recalibrate(c.Classifier.Predict(sample), c.NegSampleRate)
+}
+
+// Recalibrate returns the classifier wrapped in a Recalibrated if rate is in (0,1), and
+// the classifier itself otherwise.
+func Recalibrate(classifier Classifier, rate float64) Classifier {
+	if rate <= 0 || rate >= 1 {
+		return classifier
+	}
+	return &Recalibrated{Classifier: classifier, NegSampleRate: rate}
 }
 
 var classifierIndex map[string]Classifier = map[string]Classifier{
 	"logRegr":         new(lr.LogisticRegression),
+	"streamLogRegr":   new(lr.LogisticRegressionStream),
 	"ftrl":            new(lr.FTRLLogisticRegression),
 	"ep":              new(lr.EPLogisticRegression),
 	"rdt":             new(dt.RandomDecisionTree),
@@ -72,10 +155,21 @@ var classifierIndex map[string]Classifier = map[string]Classifier{
 	"knn":             new(svm.KNN),
 	"ann":             new(ann.NeuralNetwork),
 	"lrowlqn":         new(lr.LROWLQN),
+	"ranknet":         new(lr.RankNet),
+	"lambdamart":      new(dt.LambdaMART),
+}
+
+// Algorithms returns the classifiers keyed by the names their actions dispatch on.
+// The multi-class classifiers are among them.
+func Algorithms() map[string]internal.Algorithm {
+	ret := make(map[string]internal.Algorithm, len(classifierIndex))
+	for name, algo := range classifierIndex {
+		ret[name] = algo
+	}
+	return ret
 }
 
 func GetClassifier(method string) Classifier {
-	rand.Seed(time.Now().UTC().UnixNano())
 	return classifierIndex[method]
 }
 
@@ -85,8 +179,8 @@ type MultiClassClassifier interface {
 	Train(dataset *core.DataSet)
 	//Predict the probability of a sample to be positive sample
 	PredictMultiClass(sample *core.Sample) *core.ArrayVector
-	SaveModel(path string)
-	LoadModel(path string)
+	SaveModel(w io.Writer) error
+	LoadModel(r io.Reader) error
 }
 
 var multiClassClassifierIndex map[string]MultiClassClassifier = map[string]MultiClassClassifier{
@@ -98,101 +192,321 @@ var multiClassClassifierIndex map[string]MultiClassClassifier = map[string]Multi
 }
 
 func GetMutliClassClassifier(method string) MultiClassClassifier {
-	rand.Seed(time.Now().UTC().UnixNano())
 	return multiClassClassifierIndex[method]
 }
 
-func AlgorithmRun(ctx *cli.Context) (err error) {
-	algoName := ctx.Command.Name
-	trainSetPath := ctx.String("trainSet")
+func loadDataSet(path string, global int64, mode core.ParseMode, csv *core.CSVFormat, hasher *core.Hasher, keyColumns int) (*core.DataSet, error) {
+	if path == "" {
+		return nil, nil
+	}
+	dataset := core.NewDataSet()
+	dataset.Mode = mode
+	dataset.CSV = csv
+	dataset.Hasher = hasher
+	dataset.KeyColumns = keyColumns
+	if err := dataset.Load(path, global); err != nil {
+		return nil, err
+	}
+	return dataset, nil
+}
+
+// TrainRun trains the classifier on --train. With --cv it reports the metrics of
+// cross validation; with --test it reports the metrics of the trained model on the
+// test set. The trained model is saved to --model if the path is set. With --init-model
+// the training continues from the model at the path.
+func TrainRun(ctx *cli.Context, algoName string) (err error) {
+	if err = internal.RequireFlags(ctx, "trainSet"); err != nil {
+		return
+	}
 	testSetPath := ctx.String("testSet")
-	predictResultPath := ctx.String("predict")
 	modelPath := ctx.String("model")
-	global := ctx.Int64("global")
+	mode := internal.ParseMode(ctx)
 	cv := ctx.Int("cv")
 
-	log.Info("Running ", algoName)
+	log.Info("Training ", algoName)
 	classifier := GetClassifier(algoName)
-	classifier.Init(ctx)
-
-	var trainSet *core.DataSet
-	if trainSetPath != "" {
-		trainSet = core.NewDataSet()
-		err = trainSet.Load(trainSetPath, global)
-		if err != nil {
-			log.Error(err)
+	if err = internal.ReadConfig(ctx); err != nil {
+		return
+	}
+	if err = internal.ConfigureTraining(ctx, classifier, model.TaskBinary); err != nil {
+		return
+	}
+	// --init-model may set the global bias feature ID, the hashing of the features, the
+	// format of the data sets and the negative sampling rate.
+	global := ctx.Int64("global")
+	hasher, err := internal.Hasher(ctx)
+	if err != nil {
+		return
+	}
+	csv, err := internal.CSVFormat(ctx)
+	if err != nil {
+		return
+	}
+	cvs, err := internal.CrossValidation(ctx)
+	if err != nil {
+		return
+	}
+	rate, err := internal.NegSampleRate(ctx)
+	if err != nil {
+		return
+	}
+	sampler := core.NewNegativeSampler(rate, ctx.Int64("seed"))
+	recalibrated := Recalibrate(classifier, rate)
+
+	trainSet, err := loadDataSet(ctx.String("trainSet"), global, mode, csv, hasher, cvs.KeyColumns())
+	if err != nil {
+		return
+	}
+	names, err := internal.SaveFeatureDict(ctx, trainSet.FeatureNameIdMap)
+	if err != nil {
+		return
+	}
+	testSet, err := loadDataSet(testSetPath, global, mode, csv, hasher, 0)
+	if err != nil {
+		return
+	}
+
+	if cv > 1 {
+		var folds [][2]*core.DataSet
+		if folds, err = cvs.Split(trainSet); err != nil {
+			return
+		}
+		metrics := [][]float64{make([]float64, cv), make([]float64, cv), make([]float64, cv)}
+		for part, fold := range folds {
+			auc, predictions := AlgorithmRunOnDataSet(recalibrated, sampler.Downsample(fold[0]), fold[1])
+			logLoss, er := eval.LogLoss(predictions), eval.ErrorRate(predictions)
+			log.Infof("AUC: %.20g LogLoss: %.9g ER: %.9g", auc, logLoss, er)
+			metrics[0][part], metrics[1][part], metrics[2][part] = auc, logLoss, er
+			internal.Restart(ctx, classifier)
+		}
+		internal.LogFolds([]string{"AUC", "LogLoss", "ER"}, metrics)
+		if testSet == nil && modelPath == "" {
 			return
 		}
 	}
 
-	var testSet *core.DataSet
-	if testSetPath != "" {
-		testSet = core.NewDataSet()
-		err = testSet.Load(testSetPath, global)
-		if err != nil {
-			log.Error(err)
+	if rate < 1 {
+		downsampled := sampler.Downsample(trainSet)
+		log.Infof("Kept %d of %d samples at --neg-sample-rate %g", len(downsampled.Samples), len(trainSet.Samples), rate)
+		trainSet = downsampled
+	}
+	_, predictions := AlgorithmRunOnDataSet(recalibrated, trainSet, testSet)
+	if predictions != nil {
+		if err = writeReport(ctx, predictions); err != nil {
 			return
 		}
+		if path := ctx.String("predict"); path != "" {
+			if err = writePredictions(path, predictions); err != nil {
+				return
+			}
+		}
+	}
+
+	if modelPath != "" {
+		err = internal.SaveModel(ctx, classifier, model.TaskBinary, names)
+	}
+	return
+}
+
+// StreamTrainRun trains the streaming classifier in one pass over --train, or stdin if
+// it is "-", without holding the samples in memory. It logs the progressive validation
+// metrics every --report-every samples and at the end, reports the metrics of the
+// trained model on --test if it is set, and saves the model to --model.
+func StreamTrainRun(ctx *cli.Context, algoName string) (err error) {
+	if err = internal.RequireFlags(ctx, "trainSet"); err != nil {
+		return
+	}
+	if ctx.Int("cv") > 1 {
+		return fmt.Errorf("%s: --cv does not apply to --stream", ctx.Command.Name)
+	}
+	trainSetPath := ctx.String("trainSet")
+	mode := internal.ParseMode(ctx)
+
+	log.Info("Training ", algoName, " on a stream")
+	classifier := GetClassifier(algoName).(StreamingClassifier)
+	if err = internal.ReadConfig(ctx); err != nil {
+		return
+	}
+	if err = internal.ConfigureTraining(ctx, classifier, model.TaskBinary); err != nil {
+		return
+	}
+	// --init-model may set the global bias feature ID, the hashing of the features, the
+	// format of the data sets and the negative sampling rate.
+	global := ctx.Int64("global")
+	hasher, err := internal.Hasher(ctx)
+	if err != nil {
+		return
+	}
+	csv, err := internal.CSVFormat(ctx)
+	if err != nil {
+		return
+	}
+	testSet, err := loadDataSet(ctx.String("testSet"), global, mode, csv, hasher, 0)
+	if err != nil {
+		return
+	}
+	rate, err := internal.NegSampleRate(ctx)
+	if err != nil {
+		return
 	}
 
-	var existModel bool
-	if modelPath != "" && trainSet == nil && testSet != nil {
-		_, err = os.Stat(modelPath)
-		existModel = !os.IsNotExist(err)
-		if !existModel {
-			log.Error(err)
-			return err
+	trainSet := core.NewStreamingDataSet()
+	trainSet.Mode = mode
+	trainSet.CSV = csv
+	trainSet.Hasher = hasher
+	if rate < 1 {
+		trainSet.Sampler = core.NewNegativeSampler(rate, ctx.Int64("seed"))
+	}
+	if internal.KeepsFeatureNames(ctx) {
+		trainSet.FeatureNameIdMap = make(map[int64]string)
+	}
+	loaded := make(chan error, 1)
+	go func() {
+		if trainSetPath == "-" {
+			loaded <- trainSet.LoadFromStdIn(global)
+		} else {
+			loaded <- trainSet.Load(trainSetPath, global)
 		}
-		classifier.LoadModel(modelPath)
+	}()
+	validation := TrainStream(classifier, trainSet, ctx.Int("report-every"))
+	if err = <-loaded; err != nil {
+		return
+	}
+	log.Infof("Progressive validation of %d samples: LogLoss: %.9g AUC: %.9g", validation.Count, validation.LogLoss(), validation.AUC())
+	names, err := internal.SaveFeatureDict(ctx, trainSet.FeatureNameIdMap)
+	if err != nil {
+		return
 	}
 
-	var predictions []*eval.LabelPrediction
-	var auc float64
-	if cv <= 1 {
-		auc, predictions = AlgorithmRunOnDataSet(classifier, trainSet, testSet)
-		if predictions != nil {
-			er := eval.ErrorRate(predictions)
-			log.Infof("AUC: %.20g\n", auc)
-			log.Infof("ER: %.20g\n", er)
+	if testSet != nil {
+		_, predictions := AlgorithmRunOnDataSet(Recalibrate(classifier, rate), nil, testSet)
+		if err = writeReport(ctx, predictions); err != nil {
+			return
 		}
-	} else {
-		if trainSet == nil || len(trainSet.Samples) == 0 {
-			err := fmt.Errorf("No sample in train set for cross validation.")
-			log.Error(err)
-			return err
+		if path := ctx.String("predict"); path != "" {
+			if err = writePredictions(path, predictions); err != nil {
+				return
+			}
 		}
-		average_auc := 0.0
-		average_er := 0.0
-		for part := 0; part < cv; part++ {
-			cvTrainSet, cvTestSet := trainSet.CVSplit(cv, part)
-			auc, predictions = AlgorithmRunOnDataSet(classifier, cvTrainSet, cvTestSet)
-			log.Infof("AUC: %.20g", auc)
-			average_auc += auc
-			er := eval.ErrorRate(predictions)
-			log.Infof("ER: %.20g", er)
-			average_er += er
-			classifier.Clear()
+	}
+	if ctx.String("model") != "" {
+		err = internal.SaveModel(ctx, classifier, model.TaskBinary, names)
+	}
+	return
+}
+
+// TrainStream trains the learner on the samples of dataset as they come, and returns
+// the progressive validation of the predictions it made of them before learning each.
+// The metrics so far are logged every reportEvery samples if it is positive.
+func TrainStream(learner Learner, dataset *core.StreamingDataSet, reportEvery int) *eval.ProgressiveValidation {
+	validation := &eval.ProgressiveValidation{}
+	for sample := range dataset.Samples {
+		validation.Add(sample.Label, learner.Learn(sample), sample.GetWeight())
+		if reportEvery > 0 && validation.Count%reportEvery == 0 {
+			log.Infof("%d samples: LogLoss: %.9g AUC: %.9g", validation.Count, validation.LogLoss(), validation.AUC())
 		}
-		log.Infof("AVG. AUC: %.20g", average_auc/float64(cv))
-		log.Infof("AVG. ER: %.20g", average_er/float64(cv))
 	}
+	return validation
+}
 
-	if trainSet != nil && modelPath != "" {
-		classifier.SaveModel(modelPath)
+// TestRun evaluates the model at --model on the labeled --test set.
+func TestRun(ctx *cli.Context, algoName string) (err error) {
+	if err = internal.RequireFlags(ctx, "model", "testSet"); err != nil {
+		return
+	}
+	if err = internal.RequireFile(ctx, "model"); err != nil {
+		return
 	}
 
-	if predictResultPath != "" {
-		predictResultFile, err := os.Create(predictResultPath)
-		if err != nil {
-			return err
-		}
-		defer predictResultFile.Close()
+	classifier, err := loadModel(ctx, GetClassifier(algoName))
+	if err != nil {
+		return
+	}
 
-		for i := range predictions {
-			predictResultFile.WriteString(strconv.FormatFloat(predictions[i].Prediction, 'g', 5, 64) + "\n")
-		}
+	csv, err := internal.CSVFormat(ctx)
+	if err != nil {
+		return
 	}
-	return
+	hasher, err := internal.Hasher(ctx)
+	if err != nil {
+		return
+	}
+	testSet, err := loadDataSet(ctx.String("testSet"), ctx.Int64("global"), internal.ParseMode(ctx), csv, hasher, 0)
+	if err != nil {
+		return
+	}
+	_, predictions := AlgorithmRunOnDataSet(classifier, nil, testSet)
+	if err = writeReport(ctx, predictions); err != nil {
+		return
+	}
+	if path := ctx.String("predict"); path != "" {
+		return writePredictions(path, predictions)
+	}
+	return nil
+}
+
+// PredictRun scores the unlabeled --input set, or stdin with --stdin, with the model at
+// --model and writes the predictions to --predict, or to stdout if it is not set.
+func PredictRun(ctx *cli.Context, algoName string) (err error) {
+	if err = internal.RequireFlags(ctx, "model"); err != nil {
+		return
+	}
+	if err = internal.RequireInput(ctx); err != nil {
+		return
+	}
+	if err = internal.RequireFile(ctx, "model"); err != nil {
+		return
+	}
+
+	classifier, err := loadModel(ctx, GetClassifier(algoName))
+	if err != nil {
+		return
+	}
+
+	return internal.StreamPredict(ctx, func(sample *core.Sample) string {
+		return strconv.FormatFloat(classifier.Predict(sample), 'g', 5, 64)
+	})
+}
+
+// loadModel loads the model at --model into classifier, see internal.LoadModel, and
+// returns it recalibrated by the negative sampling rate recorded in the model.
+func loadModel(ctx *cli.Context, classifier Classifier) (Classifier, error) {
+	if err := internal.LoadModel(ctx, classifier, model.TaskBinary); err != nil {
+		return nil, err
+	}
+	h, err := model.ReadHeader(ctx.String("model"))
+	if err != nil {
+		return nil, err
+	}
+	return Recalibrate(classifier, h.NegSampleRate), nil
+}
+
+// writeReport writes the evaluation report of the predictions at --threshold, see
+// internal.WriteReport.
+func writeReport(ctx *cli.Context, predictions []*eval.LabelPrediction) error {
+	threshold := ctx.String("threshold")
+	if threshold == "optimal" {
+		return internal.WriteReport(ctx, eval.NewReport(predictions, eval.OptimalThreshold(predictions)))
+	}
+	t, err := strconv.ParseFloat(threshold, 64)
+	if err != nil {
+		return fmt.Errorf("%s: --threshold %q is neither a number nor optimal", ctx.Command.Name, threshold)
+	}
+	return internal.WriteReport(ctx, eval.NewReport(predictions, t))
+}
+
+func writePredictions(path string, predictions []*eval.LabelPrediction) error {
+	w, err := internal.CreateOutput(path)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	bw := bufio.NewWriter(w)
+	for i := range predictions {
+		bw.WriteString(strconv.FormatFloat(predictions[i].Prediction, 'g', 5, 64) + "\n")
+	}
+	return bw.Flush()
 }
 
 func AlgorithmRunOnDataSet(classifier Classifier, trainSet, testSet *core.DataSet) (float64, []*eval.LabelPrediction) {
@@ -207,86 +521,180 @@ func AlgorithmRunOnDataSet(classifier Classifier, trainSet, testSet *core.DataSe
 	predictions := []*eval.LabelPrediction{}
 	for _, sample := range testSet.Samples {
 		prediction := classifier.Predict(sample)
-		predictions = append(predictions, &(eval.LabelPrediction{Label: sample.Label, Prediction: prediction}))
+		predictions = append(predictions, &(eval.LabelPrediction{Label: sample.Label, Prediction: prediction, Qid: sample.Qid, Weight: sample.Weight}))
 	}
 
 	auc := eval.AUC(predictions)
 	return auc, predictions
 }
 
-func MultiClassRun(ctx *cli.Context) (err error) {
-	alogName := ctx.Command.Name
-	classifier := GetMutliClassClassifier(alogName)
-	trainSetPath := ctx.String("trainSet")
+// MultiClassTrainRun is TrainRun for multi-class classifiers, reporting accuracy.
+func MultiClassTrainRun(ctx *cli.Context, algoName string) (err error) {
+	if err = internal.RequireFlags(ctx, "trainSet"); err != nil {
+		return
+	}
+	if ctx.IsSet("neg-sample-rate") {
+		return fmt.Errorf("%s: --neg-sample-rate does not apply to --multi-class", ctx.Command.Name)
+	}
 	testSetPath := ctx.String("testSet")
-	predictResultPath := ctx.String("predict")
 	modelPath := ctx.String("model")
-	global := ctx.Int64("global")
+	mode := internal.ParseMode(ctx)
 	cv := ctx.Int("cv")
 
-	classifier.Init(ctx)
+	log.Info("Training ", algoName)
+	classifier := GetMutliClassClassifier(algoName)
+	if err = internal.ReadConfig(ctx); err != nil {
+		return
+	}
+	if err = internal.ConfigureTraining(ctx, classifier, model.TaskMultiClass); err != nil {
+		return
+	}
+	// --init-model may set the global bias feature ID, the hashing of the features and
+	// the format of the data sets.
+	global := ctx.Int64("global")
+	hasher, err := internal.Hasher(ctx)
+	if err != nil {
+		return
+	}
+	csv, err := internal.CSVFormat(ctx)
+	if err != nil {
+		return
+	}
+	cvs, err := internal.CrossValidation(ctx)
+	if err != nil {
+		return
+	}
+
+	trainSet, err := loadDataSet(ctx.String("trainSet"), global, mode, csv, hasher, cvs.KeyColumns())
+	if err != nil {
+		return
+	}
+	names, err := internal.SaveFeatureDict(ctx, trainSet.FeatureNameIdMap)
+	if err != nil {
+		return
+	}
+	testSet, err := loadDataSet(testSetPath, global, mode, csv, hasher, 0)
+	if err != nil {
+		return
+	}
 
-	var trainSet *core.DataSet
-	if trainSetPath != "" {
-		trainSet = core.NewDataSet()
-		err = trainSet.Load(trainSetPath, global)
-		if err != nil {
+	if cv > 1 {
+		var folds [][2]*core.DataSet
+		if folds, err = cvs.Split(trainSet); err != nil {
+			return
+		}
+		metrics := [][]float64{make([]float64, cv), make([]float64, cv), make([]float64, cv)}
+		for part, fold := range folds {
+			accuracy, predictions := MultiClassRunOnDataSet(classifier, fold[0], fold[1])
+			logLoss, macroF1 := eval.MultiClassLogLoss(predictions), eval.NewMultiClassReport(predictions).MacroF1
+			log.Infof("Accuracy: %.20g LogLoss: %.9g MacroF1: %.9g", accuracy, logLoss, macroF1)
+			metrics[0][part], metrics[1][part], metrics[2][part] = accuracy, logLoss, macroF1
+			internal.Restart(ctx, classifier)
+		}
+		internal.LogFolds([]string{"Accuracy", "LogLoss", "MacroF1"}, metrics)
+		if testSet == nil && modelPath == "" {
 			return
 		}
 	}
 
-	var testSet *core.DataSet
-	if testSetPath != "" {
-		testSet = core.NewDataSet()
-		err = testSet.Load(testSetPath, global)
-		if err != nil {
+	_, predictions := MultiClassRunOnDataSet(classifier, trainSet, testSet)
+	if predictions != nil {
+		if err = internal.WriteReport(ctx, eval.NewMultiClassReport(predictions)); err != nil {
 			return
 		}
+		if path := ctx.String("predict"); path != "" {
+			if err = writePredictLabels(path, predictions); err != nil {
+				return
+			}
+		}
 	}
 
-	_, err = os.Stat(modelPath)
-	existModel := !os.IsNotExist(err)
-	if existModel {
-		return err
+	if modelPath != "" {
+		err = internal.SaveModel(ctx, classifier, model.TaskMultiClass, names)
 	}
-	classifier.LoadModel(modelPath)
-
-	var predictLabels []int
-	var accuracy float64
-	if cv <= 1 {
-		accuracy, predictLabels = MultiClassRunOnDataSet(classifier, trainSet, testSet)
-		log.Infof("Accuracy: %.20g", accuracy)
-	} else {
-		average_accuracy := 0.0
-		for part := 0; part < cv; part++ {
-			cvTrainSet, cvTestSet := trainSet.CVSplit(cv, part)
-			accuracy, predictLabels = MultiClassRunOnDataSet(classifier, cvTrainSet, cvTestSet)
-			log.Infof("Accuracy: %.20g", accuracy)
-			average_accuracy += accuracy
-			classifier.Clear()
-		}
-		log.Infof("AVG. Accuracy: %.20g", average_accuracy/float64(cv))
+	return
+}
+
+// MultiClassTestRun evaluates the multi-class model at --model on the labeled --test set.
+func MultiClassTestRun(ctx *cli.Context, algoName string) (err error) {
+	if err = internal.RequireFlags(ctx, "model", "testSet"); err != nil {
+		return
+	}
+	if err = internal.RequireFile(ctx, "model"); err != nil {
+		return
 	}
 
-	if trainSet != nil && !existModel {
-		classifier.SaveModel(modelPath)
+	classifier := GetMutliClassClassifier(algoName)
+	if err = internal.LoadModel(ctx, classifier, model.TaskMultiClass); err != nil {
+		return
 	}
 
-	if predictResultPath != "" {
-		predictResultFile, err := os.Create(predictResultPath)
-		if err != nil {
-			return err
-		}
-		defer predictResultFile.Close()
+	csv, err := internal.CSVFormat(ctx)
+	if err != nil {
+		return
+	}
+	hasher, err := internal.Hasher(ctx)
+	if err != nil {
+		return
+	}
+	testSet, err := loadDataSet(ctx.String("testSet"), ctx.Int64("global"), internal.ParseMode(ctx), csv, hasher, 0)
+	if err != nil {
+		return
+	}
+	_, predictions := MultiClassRunOnDataSet(classifier, nil, testSet)
+	if err = internal.WriteReport(ctx, eval.NewMultiClassReport(predictions)); err != nil {
+		return
+	}
+	if path := ctx.String("predict"); path != "" {
+		return writePredictLabels(path, predictions)
+	}
+	return nil
+}
 
-		for i := range predictLabels {
-			predictResultFile.WriteString(strconv.Itoa(predictLabels[i]) + "\n")
-		}
+// MultiClassPredictRun writes the most probable class of every sample of the
+// unlabeled --input set, or of stdin with --stdin, to --predict, or to stdout if it is
+// not set.
+func MultiClassPredictRun(ctx *cli.Context, algoName string) (err error) {
+	if err = internal.RequireFlags(ctx, "model"); err != nil {
+		return
 	}
-	return
+	if err = internal.RequireInput(ctx); err != nil {
+		return
+	}
+	if err = internal.RequireFile(ctx, "model"); err != nil {
+		return
+	}
+
+	classifier := GetMutliClassClassifier(algoName)
+	if err = internal.LoadModel(ctx, classifier, model.TaskMultiClass); err != nil {
+		return
+	}
+
+	return internal.StreamPredict(ctx, func(sample *core.Sample) string {
+		label, _ := classifier.PredictMultiClass(sample).KeyWithMaxValue()
+		return strconv.Itoa(label)
+	})
 }
 
-func MultiClassRunOnDataSet(classifier MultiClassClassifier, trainSet, testSet *core.DataSet) (accuracy float64, predictLabels []int) {
+// writePredictLabels writes the most probable class of every prediction.
+func writePredictLabels(path string, predictions []*eval.MultiClassPrediction) error {
+	w, err := internal.CreateOutput(path)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	bw := bufio.NewWriter(w)
+	for _, pred := range predictions {
+		label, _ := pred.Probabilities.KeyWithMaxValue()
+		bw.WriteString(strconv.Itoa(label) + "\n")
+	}
+	return bw.Flush()
+}
+
+// MultiClassRunOnDataSet trains the classifier on trainSet if it is not nil, and
+// returns the accuracy and the class probabilities of its predictions of testSet.
+func MultiClassRunOnDataSet(classifier MultiClassClassifier, trainSet, testSet *core.DataSet) (accuracy float64, predictions []*eval.MultiClassPrediction) {
 	if trainSet != nil {
 		classifier.Train(trainSet)
 	}
@@ -295,17 +703,9 @@ func MultiClassRunOnDataSet(classifier MultiClassClassifier, trainSet, testSet *
 		return 0, nil
 	}
 
-	total := 0.0
-	predictLabels = make([]int, 0, len(testSet.Samples))
+	predictions = make([]*eval.MultiClassPrediction, 0, len(testSet.Samples))
 	for _, sample := range testSet.Samples {
-		prediction := classifier.PredictMultiClass(sample)
-		label, _ := prediction.KeyWithMaxValue()
-		predictLabels = append(predictLabels, label)
-		if label == sample.Label {
-			accuracy += 1.0
-		}
-		total += 1.0
+		predictions = append(predictions, &eval.MultiClassPrediction{Probabilities: classifier.PredictMultiClass(sample), Label: sample.Label})
 	}
-	accuracy = accuracy / total
-	return
+	return eval.MultiClassAccuracy(predictions), predictions
 }
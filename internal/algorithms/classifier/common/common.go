@@ -4,33 +4,21 @@ import "github.com/urfave/cli"
 
 var Commands []cli.Command = make([]cli.Command, 0, 1<<3)
 
-var ClassifierCammandFlags []cli.Flag = []cli.Flag{
-	//cli.StringFlag{
-	//	Name:  "action, a",
-	//	Value: "run",
-	//	Usage: `"run", "train", "test" or "predict"`,
-	//},
-	cli.IntFlag{
-		Name:  "crossValidation,cv",
-		Value: 1,
-		Usage: "Cross Validation",
-	},
-	cli.StringFlag{
-		Name: "trainSet, train",
-	},
-	cli.StringFlag{
-		Name: "testSet, test",
-	},
-	cli.StringFlag{
-		Name: "predictResult, predict",
-	},
-	cli.StringFlag{
-		Name:  "modelPath, model",
-		Usage: "If trained on data set and model path is set, output model to the path.",
+// MultiClassFlag is accepted by classifiers that also implement multi-class prediction.
+var MultiClassFlag cli.Flag = cli.BoolFlag{
+	Name:  "multi-class,mc",
+	Usage: "Treat labels as classes 0..n and predict the most probable class",
+}
+
+// StreamFlags are accepted by the train action of classifiers that learn online.
+var StreamFlags []cli.Flag = []cli.Flag{
+	cli.BoolFlag{
+		Name:  "stream",
+		Usage: "Train in one pass over --train, or stdin if it is -, without loading it in memory",
 	},
 	cli.IntFlag{
-		Name:  "globalBiasFeatureID,global",
-		Value: 0,
-		Usage: "If you read/write a model file， you MUST set the global bias feature ID.",
+		Name:  "report-every",
+		Value: 100000,
+		Usage: "Log the progressive validation metrics of --stream every number of samples, never if it is 0",
 	},
 }
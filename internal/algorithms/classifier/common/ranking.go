@@ -0,0 +1,76 @@
+package common
+
+import (
+	"math"
+	"sort"
+)
+
+// gain is the gain of a sample of the label in the DCG of its query.
+func gain(label int) float64 {
+	if label <= 0 {
+		return 0
+	}
+	return math.Pow(2, float64(label)) - 1
+}
+
+// discount is the discount of the rank r, from 0, in the DCG at k of a query, 0 past k.
+func discount(r, k int) float64 {
+	if k > 0 && r >= k {
+		return 0
+	}
+	return 1 / math.Log2(float64(r+2))
+}
+
+// byDecreasing returns the indices of the values, sorted by decreasing value.
+func byDecreasing(n int, value func(i int) float64) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return value(order[a]) > value(order[b]) })
+	return order
+}
+
+// dcg returns the DCG at k of the labels in the order.
+func dcg(labels []int, order []int, k int) float64 {
+	ret := 0.0
+	for r, i := range order {
+		ret += gain(labels[i]) * discount(r, k)
+	}
+	return ret
+}
+
+// NDCG returns the NDCG at k of a query whose samples have the labels and are ranked by
+// the scores, k <= 0 being the whole query. It is false for queries without positive
+// labels.
+func NDCG(labels []int, scores []float64, k int) (float64, bool) {
+	ideal := dcg(labels, byDecreasing(len(labels), func(i int) float64 { return float64(labels[i]) }), k)
+	if ideal == 0 {
+		return 0, false
+	}
+	return dcg(labels, byDecreasing(len(scores), func(i int) float64 { return scores[i] }), k) / ideal, true
+}
+
+// RankPairs calls f with every pair of samples of a query whose labels differ, better
+// having the higher label, and the change of the NDCG at k of the query ranked by the
+// scores if the two were swapped, which LambdaRank weights the gradient of the pair
+// by. k <= 0 is the whole query. Queries without positive labels have no pairs.
+func RankPairs(labels []int, scores []float64, k int, f func(better, worse int, deltaNDCG float64)) {
+	ideal := dcg(labels, byDecreasing(len(labels), func(i int) float64 { return float64(labels[i]) }), k)
+	if ideal == 0 {
+		return
+	}
+	rank := make([]int, len(labels))
+	for r, i := range byDecreasing(len(scores), func(i int) float64 { return scores[i] }) {
+		rank[i] = r
+	}
+	for i := range labels {
+		for j := range labels {
+			if labels[i] <= labels[j] {
+				continue
+			}
+			delta := (gain(labels[i]) - gain(labels[j])) * (discount(rank[i], k) - discount(rank[j], k))
+			f(i, j, math.Abs(delta)/ideal)
+		}
+	}
+}
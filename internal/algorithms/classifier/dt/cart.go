@@ -1,15 +1,14 @@
 package dt
 
 import (
-	"bufio"
 	"container/list"
 	"fmt"
 	"github.com/pantsing/hector/internal/core"
 	"github.com/urfave/cli"
+	"io"
 	"io/ioutil"
 	"math"
 	"math/rand"
-	"os"
 	"sort"
 )
 
@@ -22,6 +21,7 @@ type CART struct {
 	params              CARTParams
 	continuous_features bool
 	salt                int64
+	rng                 *rand.Rand
 }
 
 func DTGoLeft(sample *core.MapBasedSample, feature_split core.Feature) bool {
@@ -52,14 +52,14 @@ func (dt *CART) RandByFeatureId(fid int64) float64 {
 	return r
 }
 
-func (dt *CART) FindBestSplitOfContinusousFeature(samples []*core.MapBasedSample, node *TreeNode, feature_select_prob float64) {
+func (dt *CART) FindBestSplitOfContinusousFeature(samples []*core.MapBasedSample, node *TreeNode, feature_select_prob float64, r *rand.Rand) {
 	feature_weight_labels := make(map[int64]*core.FeatureLabelDistribution)
 	total_dis := core.NewArrayVector()
 	for i, k := range node.samples {
-		if i > 10 && rand.Float64() > dt.params.SamplingRatio {
+		if i > 10 && r.Float64() > dt.params.SamplingRatio {
 			continue
 		}
-		total_dis.AddValue(samples[k].Label, 1.0)
+		total_dis.AddValue(samples[k].Label, samples[k].Weight)
 		for fid, fvalue := range samples[k].Features {
 			if dt.RandByFeatureId(fid) > feature_select_prob {
 				continue
@@ -68,7 +68,7 @@ func (dt *CART) FindBestSplitOfContinusousFeature(samples []*core.MapBasedSample
 			if !ok {
 				feature_weight_labels[fid] = core.NewFeatureLabelDistribution()
 			}
-			feature_weight_labels[fid].AddWeightLabel(fvalue, samples[k].Label)
+			feature_weight_labels[fid].AddWeightLabel(fvalue, samples[k].Label, samples[k].Weight)
 		}
 	}
 
@@ -77,7 +77,7 @@ func (dt *CART) FindBestSplitOfContinusousFeature(samples []*core.MapBasedSample
 	for fid, distribution := range feature_weight_labels {
 		sort.Sort(distribution)
 		split, gini := distribution.BestSplitByGini(total_dis)
-		if min_gini > gini {
+		if betterSplit(gini, fid, min_gini, node.feature_split.Id) {
 			min_gini = gini
 			node.feature_split.Id = fid
 			node.feature_split.Value = split
@@ -89,14 +89,14 @@ func (dt *CART) FindBestSplitOfContinusousFeature(samples []*core.MapBasedSample
 	}
 }
 
-func (dt *CART) FindBestSplitOfBinaryFeature(samples []*core.MapBasedSample, node *TreeNode, feature_select_prob float64) {
+func (dt *CART) FindBestSplitOfBinaryFeature(samples []*core.MapBasedSample, node *TreeNode, feature_select_prob float64, r *rand.Rand) {
 	feature_right_dis := make(map[int64]*core.ArrayVector)
 	total_dis := core.NewArrayVector()
 	for i, k := range node.samples {
-		if i > 10 && rand.Float64() > dt.params.SamplingRatio {
+		if i > 10 && r.Float64() > dt.params.SamplingRatio {
 			continue
 		}
-		total_dis.AddValue(samples[k].Label, 1.0)
+		total_dis.AddValue(samples[k].Label, samples[k].Weight)
 		for fid, _ := range samples[k].Features {
 			if dt.RandByFeatureId(fid) > feature_select_prob {
 				continue
@@ -105,7 +105,7 @@ func (dt *CART) FindBestSplitOfBinaryFeature(samples []*core.MapBasedSample, nod
 			if !ok {
 				feature_right_dis[fid] = core.NewArrayVector()
 			}
-			feature_right_dis[fid].AddValue(samples[k].Label, 1.0)
+			feature_right_dis[fid].AddValue(samples[k].Label, samples[k].Weight)
 		}
 	}
 
@@ -115,7 +115,7 @@ func (dt *CART) FindBestSplitOfBinaryFeature(samples []*core.MapBasedSample, nod
 		left_dis := total_dis.Copy()
 		left_dis.AddVector(right_dis, -1.0)
 		gini := core.Gini(left_dis, right_dis)
-		if min_gini > gini {
+		if betterSplit(gini, fid, min_gini, node.feature_split.Id) {
 			min_gini = gini
 			node.feature_split.Id = fid
 			node.feature_split.Value = 1.0
@@ -127,15 +127,15 @@ func (dt *CART) FindBestSplitOfBinaryFeature(samples []*core.MapBasedSample, nod
 	}
 }
 
-func (dt *CART) AppendNodeToTree(samples []*core.MapBasedSample, node *TreeNode, queue *list.List, tree *Tree, feature_select_prob float64) {
+func (dt *CART) AppendNodeToTree(samples []*core.MapBasedSample, node *TreeNode, queue *list.List, tree *Tree, feature_select_prob float64, r *rand.Rand) {
 	if node.depth >= dt.params.MaxDepth {
 		return
 	}
 
 	if dt.continuous_features {
-		dt.FindBestSplitOfContinusousFeature(samples, node, feature_select_prob)
+		dt.FindBestSplitOfContinusousFeature(samples, node, feature_select_prob, r)
 	} else {
-		dt.FindBestSplitOfBinaryFeature(samples, node, feature_select_prob)
+		dt.FindBestSplitOfBinaryFeature(samples, node, feature_select_prob, r)
 	}
 	if node.feature_split.Id < 0 {
 		return
@@ -148,10 +148,10 @@ func (dt *CART) AppendNodeToTree(samples []*core.MapBasedSample, node *TreeNode,
 	for _, k := range node.samples {
 		if DTGoLeft(samples[k], node.feature_split) {
 			left_node.samples = append(left_node.samples, k)
-			left_node.prediction.AddValue(samples[k].Label, 1.0)
+			left_node.prediction.AddValue(samples[k].Label, samples[k].Weight)
 		} else {
 			right_node.samples = append(right_node.samples, k)
-			right_node.prediction.AddValue(samples[k].Label, 1.0)
+			right_node.prediction.AddValue(samples[k].Label, samples[k].Weight)
 		}
 	}
 	node.samples = nil
@@ -173,7 +173,9 @@ func (dt *CART) AppendNodeToTree(samples []*core.MapBasedSample, node *TreeNode,
 	}
 }
 
-func (dt *CART) SingleTreeBuild(samples []*core.MapBasedSample, feature_select_prob float64, bootstrap bool) Tree {
+// SingleTreeBuild builds a tree, drawing the random numbers it needs from r, which is not
+// shared with other goroutines.
+func (dt *CART) SingleTreeBuild(samples []*core.MapBasedSample, feature_select_prob float64, bootstrap bool, r *rand.Rand) Tree {
 	tree := Tree{}
 	queue := list.New()
 	root := TreeNode{depth: 0, left: -1, right: -1, prediction: core.NewArrayVector(), samples: []int{}}
@@ -181,13 +183,13 @@ func (dt *CART) SingleTreeBuild(samples []*core.MapBasedSample, feature_select_p
 	if !bootstrap {
 		for i, sample := range samples {
 			root.AddSample(i)
-			root.prediction.AddValue(sample.Label, 1.0)
+			root.prediction.AddValue(sample.Label, sample.Weight)
 		}
 	} else {
 		for i := 0; i < len(samples); i++ {
-			k := rand.Intn(len(samples))
+			k := r.Intn(len(samples))
 			root.AddSample(k)
-			root.prediction.AddValue(samples[k].Label, 1.0)
+			root.prediction.AddValue(samples[k].Label, samples[k].Weight)
 		}
 	}
 	root.sample_count = len(root.samples)
@@ -202,7 +204,7 @@ func (dt *CART) SingleTreeBuild(samples []*core.MapBasedSample, feature_select_p
 		}
 
 		for _, node := range nodes {
-			dt.AppendNodeToTree(samples, node, queue, &tree, feature_select_prob)
+			dt.AppendNodeToTree(samples, node, queue, &tree, feature_select_prob, r)
 		}
 	}
 	return tree
@@ -233,6 +235,9 @@ func PredictBySingleTree(tree *Tree, sample *core.MapBasedSample) (*TreeNode, st
 }
 
 func (dt *CART) Train(dataset *core.DataSet) {
+	if dt.rng == nil {
+		dt.Seed(1)
+	}
 	samples := []*core.MapBasedSample{}
 	feature_weights := make(map[int64]float64)
 	for _, sample := range dataset.Samples {
@@ -255,7 +260,14 @@ func (dt *CART) Train(dataset *core.DataSet) {
 	} else {
 		fmt.Println("Binary DataSet")
 	}
-	dt.tree = dt.SingleTreeBuild(samples, 1.0, false)
+	dt.tree = dt.SingleTreeBuild(samples, 1.0, false, dt.rng)
+}
+
+// betterSplit tells if the split of feature fid with the gini impurity gini is better than
+// the best one so far, of feature best. Ties go to the smaller feature ID so that the
+// order features are visited in does not matter.
+func betterSplit(gini float64, fid int64, min_gini float64, best int64) bool {
+	return gini < min_gini || (gini == min_gini && best >= 0 && fid < best)
 }
 
 func (dt *CART) Predict(sample *core.Sample) float64 {
@@ -270,26 +282,24 @@ func (dt *CART) PredictMultiClass(sample *core.Sample) *core.ArrayVector {
 	return node.prediction
 }
 
-func (self *CART) SaveModel(path string) {
-	ioutil.WriteFile(path, self.tree.ToString(), 0600)
+func (self *CART) SaveModel(w io.Writer) error {
+	_, err := w.Write(self.tree.ToString())
+	return err
 }
 
-func (self *CART) LoadModel(path string) {
-	file, _ := os.Open(path)
-	defer file.Close()
-	text := ""
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		text += scanner.Text() + "\n"
+func (self *CART) LoadModel(r io.Reader) error {
+	text, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
 	}
-	self.tree.FromString(string(text))
+	return self.tree.FromString(string(text))
 }
 
 type CARTParams struct {
-	MaxDepth      int
-	MinLeafSize   int
-	GiniThreshold float64
-	SamplingRatio float64
+	MaxDepth      int     `flag:"max-depth" default:"10" min:"1" usage:"Maximum depth of a tree"`
+	MinLeafSize   int     `flag:"min-leaf-size" default:"10" min:"0" usage:"Nodes with at most this many samples are not split"`
+	GiniThreshold float64 `flag:"gini" default:"1" min:"0" usage:"Nodes whose best split has a larger gini impurity are not split"`
+	SamplingRatio float64 `flag:"dt-sample-ratio,sr" default:"1" min:"0" max:"1" usage:"Ratio of the samples of a node used to find its split"`
 }
 
 func (self *CART) Command() cli.Command {
@@ -297,32 +307,27 @@ func (self *CART) Command() cli.Command {
 		Name:     "cart",
 		Usage:    "CART",
 		Category: "DT",
-		Flags: []cli.Flag{
-			cli.IntFlag{
-				Name: "min-leaf-size",
-			},
-			cli.IntFlag{
-				Name: "max-depth",
-			},
-			cli.Float64Flag{
-				Name: "gini",
-			},
-			cli.Float64Flag{
-				Name: "dt-sample-ratio,sr",
-			},
-		},
 	}
 }
 
-func (dt *CART) Init(ctx *cli.Context) {
-	dt.tree = Tree{}
-	dt.continuous_features = false
-	dt.salt = rand.Int63n(10000000000)
+func NewCART(params CARTParams) *CART {
+	dt := &CART{tree: Tree{}, params: params}
+	dt.Seed(1)
+	return dt
+}
+
+// Seed makes the tree draw its random numbers from a source seeded with seed.
+func (dt *CART) Seed(seed int64) {
+	dt.rng = rand.New(rand.NewSource(seed))
+	dt.salt = dt.rng.Int63n(10000000000)
+}
+
+func (dt *CART) Parameters() interface{} {
+	return &dt.params
+}
 
-	dt.params.MinLeafSize = ctx.Int("min-leaf-size")
-	dt.params.MaxDepth = ctx.Int("max-depth")
-	dt.params.GiniThreshold = ctx.Float64("gini")
-	dt.params.SamplingRatio = ctx.Float64("dt-sample-ratio")
+func (dt *CART) Init() {
+	*dt = *NewCART(dt.params)
 }
 
 func (dt *CART) Clear() {}
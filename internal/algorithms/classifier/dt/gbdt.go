@@ -1,49 +1,45 @@
 package dt
 
 import (
-	"bufio"
 	"fmt"
 	"github.com/pantsing/hector/internal/core"
 	"github.com/urfave/cli"
+	"io"
 	"math"
-	"os"
 )
 
+type GBDTParams struct {
+	TreeCount    int     `flag:"tree-count,tc" default:"10" min:"1" usage:"Number of trees"`
+	LearningRate float64 `flag:"learning-rate,lrate" default:"0.1" min:"0" usage:"Shrinkage of every tree"`
+	Tree         RegressionTreeParams
+}
+
 type GBDT struct {
 	dts        []*RegressionTree
 	tree_count int
 	shrink     float64
+	params     GBDTParams
+	warm       bool
 }
 
-func (self *GBDT) SaveModel(path string) {
-	file, _ := os.Create(path)
-	defer file.Close()
+func (self *GBDT) SaveModel(w io.Writer) error {
+	trees := make([]*Tree, 0, len(self.dts))
 	for _, dt := range self.dts {
-		buf := dt.tree.ToString()
-		file.Write(buf)
-		file.WriteString("\n#\n")
+		trees = append(trees, &dt.tree)
 	}
+	return writeTrees(w, trees)
 }
 
-func (self *GBDT) LoadModel(path string) {
-	file, _ := os.Open(path)
-	defer file.Close()
-
+func (self *GBDT) LoadModel(r io.Reader) error {
+	trees, err := readTrees(r)
+	if err != nil {
+		return err
+	}
 	self.dts = []*RegressionTree{}
-	scanner := bufio.NewScanner(file)
-	text := ""
-	for scanner.Scan() {
-		line := scanner.Text()
-		if line == "#" {
-			tree := Tree{}
-			tree.FromString(text)
-			dt := RegressionTree{tree: tree}
-			self.dts = append(self.dts, &dt)
-			text = ""
-		} else {
-			text += line + "\n"
-		}
+	for _, tree := range trees {
+		self.dts = append(self.dts, &RegressionTree{tree: *tree})
 	}
+	return nil
 }
 
 func (dt *GBDT) Command() cli.Command {
@@ -51,37 +47,34 @@ func (dt *GBDT) Command() cli.Command {
 		Name:     "gbdt",
 		Usage:    "GBDT",
 		Category: "DT",
-		Flags: []cli.Flag{
-			cli.IntFlag{
-				Name: "tree-count,tc",
-			},
-			cli.Float64Flag{
-				Name: "learning-rate,lrate",
-			},
-			cli.IntFlag{
-				Name: "min-leaf-size",
-			},
-			cli.IntFlag{
-				Name: "max-depth",
-			},
-			cli.Float64Flag{
-				Name: "gini",
-			},
-		},
 	}
 }
 
-func (c *GBDT) Init(ctx *cli.Context) {
-	c.tree_count = ctx.Int("tree-count")
+func NewGBDT(params GBDTParams) *GBDT {
+	c := &GBDT{tree_count: params.TreeCount, shrink: params.LearningRate, params: params}
 	for i := 0; i < c.tree_count; i++ {
-		dt := RegressionTree{}
-		dt.Init(ctx)
-		c.dts = append(c.dts, &dt)
+		c.dts = append(c.dts, NewRegressionTree(params.Tree))
 	}
-	c.shrink = ctx.Float64("learning-rate")
+	return c
 }
 
-func (c *GBDT) Clear() {}
+func (c *GBDT) Parameters() interface{} {
+	return &c.params
+}
+
+func (c *GBDT) Init() {
+	*c = *NewGBDT(c.params)
+}
+
+func (c *GBDT) Clear() {
+	*c = *NewGBDT(c.params)
+}
+
+// WarmStart makes Train keep the loaded trees and add tree-count trees fitted to their
+// residuals.
+func (c *GBDT) WarmStart() {
+	c.warm = true
+}
 
 func (c *GBDT) RMSE(dataset *core.DataSet) float64 {
 	rmse := 0.0
@@ -93,19 +86,47 @@ func (c *GBDT) RMSE(dataset *core.DataSet) float64 {
 	return math.Sqrt(rmse / n)
 }
 
+/*
+boost is the boosting loop of GBDT and LambdaMART. It grows the trees dts one after the
+other on the samples of dataset, whose Prediction target sets to what the k-th tree is
+fitted to before it is grown, and adds every tree times shrink to the scores of the
+samples. fit, unless nil, sets the values of the leaves of every tree once it is grown.
+*/
+func boost(dataset *core.DataSet, dts []*RegressionTree, shrink float64, scores map[*core.Sample]float64, target func(k int), fit func(dt *RegressionTree)) {
+	for k, dt := range dts {
+		target(k)
+		dt.Train(dataset)
+		if fit != nil {
+			fit(dt)
+		}
+		for _, sample := range dataset.Samples {
+			scores[sample] += shrink * dt.Predict(sample)
+		}
+	}
+}
+
 func (c *GBDT) Train(dataset *core.DataSet) {
-	for _, sample := range dataset.Samples {
-		sample.Prediction = sample.LabelDoubleValue()
+	dts := c.dts
+	scores := make(map[*core.Sample]float64, len(dataset.Samples))
+	if c.warm {
+		for _, sample := range dataset.Samples {
+			scores[sample] = c.Predict(sample)
+		}
+		dts = make([]*RegressionTree, 0, c.tree_count)
+		for i := 0; i < c.tree_count; i++ {
+			dts = append(dts, NewRegressionTree(c.params.Tree))
+		}
+		c.dts = append(c.dts, dts...)
 	}
-	for k, dt := range c.dts {
-		dt.Train(dataset)
+	// The trees are fitted to the residuals.
+	boost(dataset, dts, c.shrink, scores, func(k int) {
 		for _, sample := range dataset.Samples {
-			sample.Prediction -= c.shrink * dt.Predict(sample)
+			sample.Prediction = sample.LabelDoubleValue() - scores[sample]
 		}
-		if k % 10 == 0 {
+		if k%10 == 1 {
 			fmt.Println(c.RMSE(dataset))
 		}
-	}
+	}, nil)
 }
 
 func (c *GBDT) Predict(sample *core.Sample) float64 {
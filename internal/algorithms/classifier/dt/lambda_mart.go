@@ -0,0 +1,151 @@
+package dt
+
+import (
+	"github.com/pantsing/hector/internal/algorithms/classifier/common"
+	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/hector/internal/utils"
+	"github.com/pantsing/log"
+	"github.com/urfave/cli"
+	"io"
+	"math"
+)
+
+type LambdaMARTParams struct {
+	TreeCount    int     `flag:"tree-count,tc" default:"10" min:"1" usage:"Number of trees"`
+	LearningRate float64 `flag:"learning-rate,lrate" default:"0.1" min:"0" usage:"Shrinkage of every tree"`
+	NDCGAt       int     `flag:"ndcg-at" default:"10" min:"0" usage:"Rank k of the NDCG@k optimized, 0 for the whole query"`
+	Tree         RegressionTreeParams
+}
+
+// LambdaMART boosts regression trees, with the loop of GBDT, fitted to the lambda
+// gradients of the NDCG of the queries of the train set, the samples grouped by their
+// Qid. The values of the leaves are Newton steps: the sum of the lambdas of their
+// samples over the sum of their second derivatives.
+type LambdaMART struct {
+	dts    []*RegressionTree
+	params LambdaMARTParams
+}
+
+func (self *LambdaMART) SaveModel(w io.Writer) error {
+	trees := make([]*Tree, 0, len(self.dts))
+	for _, dt := range self.dts {
+		trees = append(trees, &dt.tree)
+	}
+	return writeTrees(w, trees)
+}
+
+func (self *LambdaMART) LoadModel(r io.Reader) error {
+	trees, err := readTrees(r)
+	if err != nil {
+		return err
+	}
+	self.dts = []*RegressionTree{}
+	for _, tree := range trees {
+		self.dts = append(self.dts, &RegressionTree{tree: *tree})
+	}
+	return nil
+}
+
+func (c *LambdaMART) Command() cli.Command {
+	return cli.Command{
+		Name:     "lambdamart",
+		Usage:    "LambdaMART boosted trees optimizing the NDCG of qid groups",
+		Category: "DT",
+	}
+}
+
+func NewLambdaMART(params LambdaMARTParams) *LambdaMART {
+	return &LambdaMART{params: params}
+}
+
+func (c *LambdaMART) Parameters() interface{} {
+	return &c.params
+}
+
+func (c *LambdaMART) Init() {
+	*c = *NewLambdaMART(c.params)
+}
+
+func (c *LambdaMART) Clear() {
+	*c = *NewLambdaMART(c.params)
+}
+
+// lambdas sets the Prediction of every sample to its lambda, the gradient of the NDCG
+// of its query at the scores, and returns the second derivatives of the samples and
+// the mean NDCG of the queries with a positive sample.
+func (c *LambdaMART) lambdas(queries [][]*core.Sample, scores map[*core.Sample]float64) (map[*core.Sample]float64, float64) {
+	hessians := make(map[*core.Sample]float64)
+	ndcg, n := 0.0, 0.0
+	for _, query := range queries {
+		labels := make([]int, len(query))
+		s := make([]float64, len(query))
+		for i, sample := range query {
+			labels[i], s[i] = sample.Label, scores[sample]
+			sample.Prediction = 0
+		}
+		common.RankPairs(labels, s, c.params.NDCGAt, func(better, worse int, deltaNDCG float64) {
+			rho := 1 / (1 + math.Exp(s[better]-s[worse]))
+			query[better].Prediction += rho * deltaNDCG
+			query[worse].Prediction -= rho * deltaNDCG
+			hessians[query[better]] += rho * (1 - rho) * deltaNDCG
+			hessians[query[worse]] += rho * (1 - rho) * deltaNDCG
+		})
+		if q, ok := common.NDCG(labels, s, c.params.NDCGAt); ok {
+			ndcg += q
+			n++
+		}
+	}
+	if n > 0 {
+		ndcg /= n
+	}
+	return hessians, ndcg
+}
+
+func (c *LambdaMART) Train(dataset *core.DataSet) {
+	queries := dataset.Queries()
+	scores := make(map[*core.Sample]float64, len(dataset.Samples))
+	c.dts = make([]*RegressionTree, 0, c.params.TreeCount)
+	for k := 0; k < c.params.TreeCount; k++ {
+		c.dts = append(c.dts, NewRegressionTree(c.params.Tree))
+	}
+	var hessians map[*core.Sample]float64
+	boost(dataset, c.dts, c.params.LearningRate, scores, func(k int) {
+		var ndcg float64
+		hessians, ndcg = c.lambdas(queries, scores)
+		if k%10 == 0 {
+			log.Infof("tree %d NDCG@%d: %.6g", k, c.params.NDCGAt, ndcg)
+		}
+	}, func(dt *RegressionTree) {
+		c.newtonLeaves(dt, dataset, hessians)
+	})
+}
+
+// newtonLeaves sets the value of every node of the tree samples of the data set end
+// in to the sum of their lambdas, held in their Prediction, over the sum of their
+// second derivatives. Other nodes are set to 0.
+func (c *LambdaMART) newtonLeaves(dt *RegressionTree, dataset *core.DataSet, hessians map[*core.Sample]float64) {
+	lambdas := make(map[*TreeNode]float64)
+	weights := make(map[*TreeNode]float64)
+	for _, sample := range dataset.Samples {
+		node, _ := dt.PredictBySingleTree(&dt.tree, sample.ToMapBasedSample())
+		lambdas[node] += sample.Prediction
+		weights[node] += hessians[sample]
+	}
+	for _, node := range dt.tree.nodes {
+		value := 0.0
+		if weights[node] > 0 {
+			value = lambdas[node] / weights[node]
+		}
+		node.prediction.SetValue(0, value)
+	}
+}
+
+// Predict returns the sigmoid of the score of the sample, which orders the samples of a
+// query as the score does.
+func (c *LambdaMART) Predict(sample *core.Sample) float64 {
+	ret := 0.0
+	for _, dt := range c.dts {
+		ret += c.params.LearningRate * dt.Predict(sample)
+	}
+	return utils.Sigmoid(ret)
+}
@@ -0,0 +1,34 @@
+package dt
+
+import (
+	"github.com/pantsing/hector/internal/algorithms/classifier/common"
+	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/hector/internal/params"
+	"testing"
+)
+
+func TestLambdaMART(t *testing.T) {
+	dataset := core.RankingDataSet(30, 8)
+	algo := &LambdaMART{}
+	params.Defaults(algo.Parameters())
+	algo.Init()
+	algo.Train(dataset)
+
+	ndcg := 0.0
+	queries := dataset.Queries()
+	for _, query := range queries {
+		labels := make([]int, len(query))
+		scores := make([]float64, len(query))
+		for i, sample := range query {
+			labels[i], scores[i] = sample.Label, algo.Predict(sample)
+			if scores[i] <= 0 || scores[i] >= 1 {
+				t.Fatalf("prediction %v, want a probability", scores[i])
+			}
+		}
+		q, _ := common.NDCG(labels, scores, 0)
+		ndcg += q
+	}
+	if ndcg /= float64(len(queries)); ndcg < 0.95 {
+		t.Errorf("NDCG is %v, want at least 0.95", ndcg)
+	}
+}
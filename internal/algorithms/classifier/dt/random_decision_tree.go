@@ -1,9 +1,12 @@
 package dt
 
 import (
+	"bufio"
 	"container/list"
 	"fmt"
+	"io"
 	"math/rand"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -18,17 +21,6 @@ func (self *RandomDecisionTree) Command() cli.Command {
 		Name:     "rdt",
 		Usage:    "Random Decision Tree",
 		Category: "DT",
-		Flags: []cli.Flag{
-			cli.IntFlag{
-				Name: "min-leaf-size",
-			},
-			cli.IntFlag{
-				Name: "tree-count",
-			},
-			cli.IntFlag{
-				Name: "max-depth",
-			},
-		},
 	}
 }
 
@@ -89,68 +81,157 @@ func (t *Tree) ToString() []byte {
 	return sb.Bytes()
 }
 
-func (t *Tree) fromString(lines []string) {
-	size, _ := strconv.Atoi(lines[0])
-	t.nodes = make([]*TreeNode, size+1, size+1)
-	for _, line := range lines[1:] {
+func (t *Tree) fromString(lines []string) error {
+	size, err := strconv.Atoi(lines[0])
+	if err != nil {
+		return fmt.Errorf("line 1: %v", err)
+	}
+	t.nodes = make([]*TreeNode, size)
+	for k, line := range lines[1:] {
 		if len(line) == 0 {
 			break
 		}
-		tks := strings.Split(line, "\t")
-		node := TreeNode{}
-		i, _ := strconv.Atoi(tks[0])
-		node.left, _ = strconv.Atoi(tks[1])
-		node.right, _ = strconv.Atoi(tks[2])
-		node.depth, _ = strconv.Atoi(tks[3])
-		node.prediction = core.NewArrayVector()
-		node.prediction.FromString(tks[4])
-		node.sample_count, _ = strconv.Atoi(tks[5])
-		node.feature_split = core.Feature{}
-		node.feature_split.Id, _ = strconv.ParseInt(tks[6], 10, 64)
-		node.feature_split.Value, _ = strconv.ParseFloat(tks[7], 64)
-		t.nodes[i] = &node
+		i, node, err := parseTreeNode(line)
+		if err == nil && (i < 0 || i >= size) {
+			err = fmt.Errorf("node %d of a tree of %d nodes", i, size)
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %v", k+2, err)
+		}
+		t.nodes[i] = node
+	}
+	for i, node := range t.nodes {
+		if node == nil {
+			return fmt.Errorf("node %d is missing", i)
+		}
+	}
+	return nil
+}
+
+func parseTreeNode(line string) (i int, node *TreeNode, err error) {
+	tks := strings.Split(line, "\t")
+	if len(tks) != 8 {
+		return 0, nil, fmt.Errorf("%d fields, want 8", len(tks))
+	}
+	atoi := func(tk string) int {
+		v, e := strconv.Atoi(tk)
+		if e != nil && err == nil {
+			err = e
+		}
+		return v
 	}
+	node = &TreeNode{}
+	i = atoi(tks[0])
+	node.left = atoi(tks[1])
+	node.right = atoi(tks[2])
+	node.depth = atoi(tks[3])
+	node.prediction = core.NewArrayVector()
+	if e := node.prediction.FromString(tks[4]); e != nil && err == nil {
+		err = e
+	}
+	node.sample_count = atoi(tks[5])
+	if err != nil {
+		return
+	}
+	node.feature_split = core.Feature{}
+	if node.feature_split.Id, err = strconv.ParseInt(tks[6], 10, 64); err != nil {
+		return
+	}
+	node.feature_split.Value, err = strconv.ParseFloat(tks[7], 64)
+	return
 }
 
-func (t *Tree) FromString(buf string) {
+func (t *Tree) FromString(buf string) error {
 	lines := strings.Split(buf, "\n")
-	t.fromString(lines)
+	return t.fromString(lines)
+}
+
+// writeTrees writes the trees of an ensemble, each followed by a "#" line.
+func writeTrees(w io.Writer, trees []*Tree) error {
+	for _, tree := range trees {
+		if _, err := w.Write(tree.ToString()); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "\n#\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readTrees reads the trees written by writeTrees.
+func readTrees(r io.Reader) ([]*Tree, error) {
+	trees := []*Tree{}
+	reader := bufio.NewReader(r)
+	text := []string{}
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF && line == "" {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "#" {
+			tree := Tree{}
+			if err := tree.fromString(text); err != nil {
+				return nil, fmt.Errorf("tree %d: %v", len(trees)+1, err)
+			}
+			trees = append(trees, &tree)
+			text = []string{}
+		} else {
+			text = append(text, line)
+		}
+	}
+	for _, line := range text {
+		if line != "" {
+			return nil, fmt.Errorf("tree %d: missing closing # line", len(trees)+1)
+		}
+	}
+	return trees, nil
 }
 
 type RDTParams struct {
-	TreeCount   int
-	MinLeafSize int
-	MaxDepth    int
+	TreeCount   int `flag:"tree-count,tc" default:"10" min:"1" usage:"Number of trees"`
+	MinLeafSize int `flag:"min-leaf-size" default:"10" min:"0" usage:"Nodes with at most this many samples are not split"`
+	MaxDepth    int `flag:"max-depth" default:"10" min:"0" usage:"Maximum depth of a tree"`
 }
 
 type RandomDecisionTree struct {
 	trees  []*Tree
 	params RDTParams
+	rng    *rand.Rand
 }
 
-func (self *RandomDecisionTree) SaveModel(path string) {
-
+func (self *RandomDecisionTree) SaveModel(w io.Writer) error {
+	return writeTrees(w, self.trees)
 }
 
-func (self *RandomDecisionTree) LoadModel(path string) {
-
+func (self *RandomDecisionTree) LoadModel(r io.Reader) (err error) {
+	self.trees, err = readTrees(r)
+	return
 }
 
-func (rdt *RandomDecisionTree) AppendNodeToTree(samples []*core.MapBasedSample, node *TreeNode, queue *list.List, tree *Tree) {
+func (rdt *RandomDecisionTree) AppendNodeToTree(samples []*core.MapBasedSample, node *TreeNode, queue *list.List, tree *Tree, r *rand.Rand) {
 	node.prediction = core.NewArrayVector()
 	for _, k := range node.samples {
 		node.prediction.AddValue(samples[k].Label, 1.0)
 	}
 	node.prediction.Scale(1.0 / node.prediction.Sum())
 
-	random_sample := samples[node.samples[rand.Intn(len(node.samples))]]
+	random_sample := samples[node.samples[r.Intn(len(node.samples))]]
 
 	split := core.Feature{Id: -1, Value: -1.0}
-	for fid, fvalue := range random_sample.Features {
-		if split.Id < 0 || rand.Intn(len(random_sample.Features)) == 0 {
-			split.Id = fid
-			split.Value = fvalue
+	if len(random_sample.Features) > 0 {
+		// Features are sorted so that the split does not depend on the order of the map.
+		fids := make([]int64, 0, len(random_sample.Features))
+		for fid := range random_sample.Features {
+			fids = append(fids, fid)
 		}
+		sort.Slice(fids, func(i, j int) bool { return fids[i] < fids[j] })
+		split.Id = fids[r.Intn(len(fids))]
+		split.Value = random_sample.Features[split.Id]
 	}
 
 	if split.Id < 0 || node.depth > rdt.params.MaxDepth {
@@ -187,13 +268,15 @@ func (rdt *RandomDecisionTree) AppendNodeToTree(samples []*core.MapBasedSample,
 	}
 }
 
-func (rdt *RandomDecisionTree) SingleTreeBuild(samples []*core.MapBasedSample) Tree {
+// SingleTreeBuild builds a tree, drawing the random numbers it needs from r, which is not
+// shared with other goroutines.
+func (rdt *RandomDecisionTree) SingleTreeBuild(samples []*core.MapBasedSample, r *rand.Rand) Tree {
 	tree := Tree{}
 	queue := list.New()
 	root := TreeNode{depth: 0, left: -1, right: -1, prediction: core.NewArrayVector(), samples: []int{}}
 
 	for i := 0; i < len(samples); i++ {
-		k := rand.Intn(len(samples))
+		k := r.Intn(len(samples))
 		root.AddSample(k)
 		root.prediction.AddValue(samples[k].Label, 1.0)
 	}
@@ -209,7 +292,7 @@ func (rdt *RandomDecisionTree) SingleTreeBuild(samples []*core.MapBasedSample) T
 		}
 
 		for _, node := range nodes {
-			rdt.AppendNodeToTree(samples, node, queue, &tree)
+			rdt.AppendNodeToTree(samples, node, queue, &tree, r)
 		}
 	}
 	return tree
@@ -217,35 +300,36 @@ func (rdt *RandomDecisionTree) SingleTreeBuild(samples []*core.MapBasedSample) T
 
 func (rdt *RandomDecisionTree) RandomShuffle(features []core.Feature) {
 	for i := range features {
-		j := rand.Intn(i + 1)
+		j := rdt.rng.Intn(i + 1)
 		features[i], features[j] = features[j], features[i]
 	}
 }
 
 func (rdt *RandomDecisionTree) Train(dataset *core.DataSet) {
+	if rdt.rng == nil {
+		rdt.Seed(1)
+	}
 	samples := []*core.MapBasedSample{}
 	for _, sample := range dataset.Samples {
 		samples = append(samples, sample.ToMapBasedSample())
 	}
 	dataset.Samples = nil
 
-	forest := make(chan *Tree, rdt.params.TreeCount)
+	forest := make([]*Tree, rdt.params.TreeCount)
 	var wait sync.WaitGroup
 	wait.Add(rdt.params.TreeCount)
-	for k := 0; k < rdt.params.TreeCount; k++ {
-		go func() {
-			tree := rdt.SingleTreeBuild(samples)
-			forest <- &tree
+	for k := range forest {
+		r := rand.New(rand.NewSource(rdt.rng.Int63()))
+		go func(k int) {
+			tree := rdt.SingleTreeBuild(samples, r)
+			forest[k] = &tree
 			fmt.Printf(".")
 			wait.Done()
-		}()
+		}(k)
 	}
 	wait.Wait()
 	fmt.Println()
-	close(forest)
-	for tree := range forest {
-		rdt.trees = append(rdt.trees, tree)
-	}
+	rdt.trees = append(rdt.trees, forest...)
 }
 
 func (rdt *RandomDecisionTree) Predict(sample *core.Sample) float64 {
@@ -273,11 +357,21 @@ func (rdt *RandomDecisionTree) PredictMultiClass(sample *core.Sample) *core.Arra
 	return predictions
 }
 
-func (rdt *RandomDecisionTree) Init(ctx *cli.Context) {
-	rdt.trees = []*Tree{}
-	rdt.params.MinLeafSize = ctx.Int("min-leaf-size")
-	rdt.params.TreeCount = ctx.Int("tree-count")
-	rdt.params.MaxDepth = ctx.Int("max-depth")
+func NewRandomDecisionTree(params RDTParams) *RandomDecisionTree {
+	return &RandomDecisionTree{trees: []*Tree{}, params: params, rng: rand.New(rand.NewSource(1))}
+}
+
+// Seed makes the trees draw their random numbers from sources seeded from seed.
+func (rdt *RandomDecisionTree) Seed(seed int64) {
+	rdt.rng = rand.New(rand.NewSource(seed))
+}
+
+func (rdt *RandomDecisionTree) Parameters() interface{} {
+	return &rdt.params
+}
+
+func (rdt *RandomDecisionTree) Init() {
+	*rdt = *NewRandomDecisionTree(rdt.params)
 }
 
 func (rdt *RandomDecisionTree) Clear() {}
@@ -0,0 +1,34 @@
+package dt
+
+import (
+	"bytes"
+	"github.com/pantsing/hector/internal/core"
+	"testing"
+)
+
+func TestRandomDecisionTreeSaveLoad(t *testing.T) {
+	dataset := core.XORDataSet(200)
+	samples := append([]*core.Sample{}, dataset.Samples...)
+	algo := &RandomDecisionTree{params: RDTParams{TreeCount: 5, MinLeafSize: 2, MaxDepth: 5}}
+	algo.Train(dataset)
+	var buf bytes.Buffer
+	if err := algo.SaveModel(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := &RandomDecisionTree{}
+	if err := loaded.LoadModel(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.trees) != len(algo.trees) {
+		t.Fatalf("%d trees after load, want %d", len(loaded.trees), len(algo.trees))
+	}
+	for i, sample := range samples {
+		p, q := algo.PredictMultiClass(sample), loaded.PredictMultiClass(sample)
+		for label := 0; label <= 1; label++ {
+			if p.GetValue(label) != q.GetValue(label) {
+				t.Fatalf("sample %d: prediction of label %d is %v after load, want %v", i, label, q.GetValue(label), p.GetValue(label))
+			}
+		}
+	}
+}
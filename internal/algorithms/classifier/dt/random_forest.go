@@ -1,11 +1,10 @@
 package dt
 
 import (
-	"bufio"
 	"fmt"
+	"io"
 	"log"
-	"os"
-	"strings"
+	"math/rand"
 	"sync"
 
 	"github.com/pantsing/hector/internal/core"
@@ -13,8 +12,9 @@ import (
 )
 
 type RandomForestParams struct {
-	TreeCount    int
-	FeatureCount float64
+	TreeCount    int     `flag:"tree-count,tc" default:"10" min:"1" usage:"Number of trees"`
+	FeatureCount float64 `flag:"feature-count,fc" default:"0.7" min:"0" max:"1" usage:"Probability of a feature to be considered for the splits of a tree"`
+	Tree         CARTParams
 }
 
 type RandomForest struct {
@@ -22,41 +22,19 @@ type RandomForest struct {
 	params              RandomForestParams
 	cart                CART
 	continuous_features bool
+	rng                 *rand.Rand
 }
 
-func (self *RandomForest) SaveModel(path string) {
-	file, _ := os.Create(path)
-	defer file.Close()
-	for _, tree := range self.trees {
-		buf := tree.ToString()
-		file.Write(buf)
-		file.WriteString("\n#\n")
-	}
+func (self *RandomForest) SaveModel(w io.Writer) error {
+	return writeTrees(w, self.trees)
 }
 
-func (self *RandomForest) LoadModel(path string) {
-	file, _ := os.Open(path)
-	defer file.Close()
-
-	self.trees = []*Tree{}
-	reader := bufio.NewReader(file)
-	text := []string{}
-	for {
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			break
-		}
-		line = strings.TrimSpace(line)
-		if line == "#" {
-			tree := Tree{}
-			tree.fromString(text)
-			self.trees = append(self.trees, &tree)
-			text = []string{}
-		} else {
-			text = append(text, line)
-		}
+func (self *RandomForest) LoadModel(r io.Reader) (err error) {
+	if self.trees, err = readTrees(r); err != nil {
+		return
 	}
 	log.Println("rf tree count :", len(self.trees))
+	return
 }
 
 func (dt *RandomForest) Command() cli.Command {
@@ -64,28 +42,37 @@ func (dt *RandomForest) Command() cli.Command {
 		Name:     "rf",
 		Usage:    "RandomForest",
 		Category: "DT",
-		Flags: []cli.Flag{
-			cli.IntFlag{
-				Name: "tree-count,tc",
-			},
-			cli.Float64Flag{
-				Name: "feature-count,fc",
-			},
-		},
 	}
 }
 
-func (dt *RandomForest) Init(ctx *cli.Context) {
-	dt.trees = []*Tree{}
-	dt.cart.Init(ctx)
-	dt.params.TreeCount = ctx.Int("tree-count")
-	dt.params.FeatureCount = ctx.Float64("feature-count")
+func NewRandomForest(params RandomForestParams) *RandomForest {
+	dt := &RandomForest{trees: []*Tree{}, params: params, cart: *NewCART(params.Tree)}
+	dt.Seed(1)
+	return dt
+}
+
+// Seed makes the forest draw its random numbers from a source seeded with seed. Every
+// tree draws from its own source, seeded from it.
+func (dt *RandomForest) Seed(seed int64) {
+	dt.rng = rand.New(rand.NewSource(seed))
+	dt.cart.Seed(dt.rng.Int63())
+}
+
+func (dt *RandomForest) Parameters() interface{} {
+	return &dt.params
+}
+
+func (dt *RandomForest) Init() {
+	*dt = *NewRandomForest(dt.params)
 }
 
 func (rdt *RandomForest) Clear() {
 }
 
 func (dt *RandomForest) Train(dataset *core.DataSet) {
+	if dt.rng == nil {
+		dt.Seed(1)
+	}
 	samples := []*core.MapBasedSample{}
 	feature_weights := make(map[int64]float64)
 	for _, sample := range dataset.Samples {
@@ -105,25 +92,22 @@ func (dt *RandomForest) Train(dataset *core.DataSet) {
 	}
 	dt.cart.continuous_features = dt.continuous_features
 
-	trees := make(chan *Tree, dt.params.TreeCount)
+	trees := make([]*Tree, dt.params.TreeCount)
 	var wait sync.WaitGroup
 	wait.Add(dt.params.TreeCount)
 
-	for i := 0; i < dt.params.TreeCount; i++ {
-
-		go func() {
-			tree := dt.cart.SingleTreeBuild(samples, dt.params.FeatureCount, true)
-			trees <- &tree
+	for i := range trees {
+		r := rand.New(rand.NewSource(dt.rng.Int63()))
+		go func(i int) {
+			tree := dt.cart.SingleTreeBuild(samples, dt.params.FeatureCount, true, r)
+			trees[i] = &tree
 			fmt.Printf(".")
 			wait.Done()
-		}()
+		}(i)
 	}
 	wait.Wait()
 	fmt.Println()
-	close(trees)
-	for tree := range trees {
-		dt.trees = append(dt.trees, tree)
-	}
+	dt.trees = append(dt.trees, trees...)
 }
 
 func (dt *RandomForest) Predict(sample *core.Sample) float64 {
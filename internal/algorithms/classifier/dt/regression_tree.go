@@ -1,33 +1,35 @@
 package dt
 
 import (
-	"bufio"
 	"container/list"
 	"github.com/pantsing/hector/internal/core"
 	"github.com/urfave/cli"
+	"io"
 	"io/ioutil"
-	"os"
 	"sort"
 )
 
+type RegressionTreeParams struct {
+	MaxDepth    int `flag:"max-depth" default:"10" min:"1" usage:"Maximum depth of a tree"`
+	MinLeafSize int `flag:"min-leaf-size" default:"10" min:"0" usage:"Nodes with at most this many samples are not split"`
+}
+
 type RegressionTree struct {
 	tree   Tree
-	params CARTParams
+	params RegressionTreeParams
 }
 
-func (self *RegressionTree) SaveModel(path string) {
-	ioutil.WriteFile(path, self.tree.ToString(), 0600)
+func (self *RegressionTree) SaveModel(w io.Writer) error {
+	_, err := w.Write(self.tree.ToString())
+	return err
 }
 
-func (self *RegressionTree) LoadModel(path string) {
-	file, _ := os.Open(path)
-	defer file.Close()
-	text := ""
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		text += scanner.Text() + "\n"
+func (self *RegressionTree) LoadModel(r io.Reader) error {
+	text, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
 	}
-	self.tree.FromString(string(text))
+	return self.tree.FromString(string(text))
 }
 
 func (dt *RegressionTree) GoLeft(sample *core.MapBasedSample, feature_split core.Feature) bool {
@@ -58,9 +60,9 @@ func (dt *RegressionTree) FindBestSplit(samples []*core.MapBasedSample, node *Tr
 	sum_total2 := 0.0
 	count_total := 0.0
 	for _, k := range node.samples {
-		sum_total += samples[k].Prediction
-		sum_total2 += samples[k].Prediction * samples[k].Prediction
-		count_total += 1.0
+		sum_total += samples[k].Weight * samples[k].Prediction
+		sum_total2 += samples[k].Weight * samples[k].Prediction * samples[k].Prediction
+		count_total += samples[k].Weight
 	}
 
 	feature_sum_right := core.NewVector()
@@ -69,14 +71,14 @@ func (dt *RegressionTree) FindBestSplit(samples []*core.MapBasedSample, node *Tr
 
 	for _, k := range node.samples {
 		for fid, fvalue := range samples[k].Features {
-			feature_count_right.AddValue(fid, 1.0)
-			feature_sum_right.AddValue(fid, samples[k].Prediction)
-			feature_sum_right2.AddValue(fid, samples[k].Prediction * samples[k].Prediction)
+			feature_count_right.AddValue(fid, samples[k].Weight)
+			feature_sum_right.AddValue(fid, samples[k].Weight * samples[k].Prediction)
+			feature_sum_right2.AddValue(fid, samples[k].Weight * samples[k].Prediction * samples[k].Prediction)
 			_, ok := feature_weight_labels[fid]
 			if !ok {
 				feature_weight_labels[fid] = core.NewFeatureGoalDistribution()
 			}
-			feature_weight_labels[fid].AddWeightGoal(fvalue, samples[k].Prediction)
+			feature_weight_labels[fid].AddWeightGoal(fvalue, samples[k].Prediction, samples[k].Weight)
 		}
 	}
 
@@ -118,12 +120,12 @@ func (dt *RegressionTree) AppendNodeToTree(samples []*core.MapBasedSample, node
 	for _, k := range node.samples {
 		if dt.GoLeft(samples[k], node.feature_split) {
 			left_node.samples = append(left_node.samples, k)
-			left_positive += samples[k].Prediction
-			left_total += 1.0
+			left_positive += samples[k].Weight * samples[k].Prediction
+			left_total += samples[k].Weight
 		} else {
 			right_node.samples = append(right_node.samples, k)
-			right_positive += samples[k].Prediction
-			right_total += 1.0
+			right_positive += samples[k].Weight * samples[k].Prediction
+			right_total += samples[k].Weight
 		}
 	}
 	node.samples = nil
@@ -153,8 +155,8 @@ func (dt *RegressionTree) SingleTreeBuild(samples []*core.MapBasedSample, select
 	positive := 0.0
 	for i, sample := range samples {
 		root.AddSample(i)
-		total += 1.0
-		positive += sample.Prediction
+		total += sample.Weight
+		positive += sample.Weight * sample.Prediction
 	}
 	root.sample_count = len(root.samples)
 	root.prediction.SetValue(0, positive / total)
@@ -218,25 +220,19 @@ func (dt *RegressionTree) Command() cli.Command {
 		Name:     "rt",
 		Usage:    "Regression Tree",
 		Category: "DT",
-		Flags: []cli.Flag{
-			cli.IntFlag{
-				Name: "min-leaf-size",
-			},
-			cli.IntFlag{
-				Name: "max-depth",
-			},
-			cli.Float64Flag{
-				Name: "gini",
-			},
-		},
-	}
-}
-
-func (dt *RegressionTree) Init(ctx *cli.Context) {
-	dt.tree = Tree{}
-	dt.params.MinLeafSize = ctx.Int("min-leaf-size")
-	dt.params.MaxDepth = ctx.Int("max-depth")
-	dt.params.GiniThreshold = ctx.Float64("gini")
+	}
+}
+
+func NewRegressionTree(params RegressionTreeParams) *RegressionTree {
+	return &RegressionTree{tree: Tree{}, params: params}
+}
+
+func (dt *RegressionTree) Parameters() interface{} {
+	return &dt.params
+}
+
+func (dt *RegressionTree) Init() {
+	*dt = *NewRegressionTree(dt.params)
 }
 
 func (dt *RegressionTree) Clear() {}
@@ -2,37 +2,65 @@ package fm
 
 import (
 	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/hector/internal/model"
 	"github.com/pantsing/hector/internal/utils"
 	"github.com/urfave/cli"
+	"io"
+	"math/rand"
 )
 
 type FactorizeMachine struct {
 	w      *core.Vector
 	v      []*core.Vector
 	params FactorizeMachineParams
+	rng    *rand.Rand
 }
 
 type FactorizeMachineParams struct {
-	LearningRate   float64
-	Regularization float64
-	FactorNumber   int
+	LearningRate   float64 `flag:"learning-rate,lrate" default:"0.01" min:"0" usage:"Learning rate, multiplied by 0.9 every 10000 samples"`
+	Regularization float64 `flag:"regularization,r" default:"0.01" min:"0" usage:"L2 regularization"`
+	FactorNumber   int     `flag:"factors" default:"10" min:"1" usage:"Number of factors of a feature"`
 }
 
-func (self *FactorizeMachine) SaveModel(path string) {
-
+// SaveModel writes one line per feature: the feature id, its weight and its factors.
+func (self *FactorizeMachine) SaveModel(w io.Writer) error {
+	sb := utils.StringBuilder{}
+	for f, w := range self.w.Data {
+		sb.Int64(f)
+		sb.Write("\t")
+		sb.Float(w)
+		for _, vk := range self.v {
+			sb.Write("\t")
+			sb.Float(vk.GetValue(f))
+		}
+		sb.Write("\n")
+	}
+	_, err := sb.WriteTo(w)
+	return err
 }
 
-func (self *FactorizeMachine) LoadModel(path string) {
-
+func (self *FactorizeMachine) LoadModel(r io.Reader) error {
+	self.w = core.NewVector()
+	self.v = []*core.Vector{}
+	scaner := model.NewScanner(r)
+	for scaner.Scan() {
+		fid := scaner.Int64(0)
+		self.w.SetValue(fid, scaner.Float(1))
+		for k := 2; k < scaner.Fields(); k++ {
+			if k-2 == len(self.v) {
+				self.v = append(self.v, core.NewVector())
+			}
+			self.v[k-2].SetValue(fid, scaner.Float(k))
+		}
+	}
+	if len(self.v) > 0 {
+		self.params.FactorNumber = len(self.v)
+	}
+	return scaner.Err()
 }
 
+// Predict leaves features unseen in training out, so it never changes the model.
 func (c *FactorizeMachine) Predict(sample *core.Sample) float64 {
-	for _, f := range sample.Features {
-		c.w.RandomInit(f.Id, 0.1)
-		for k, _ := range c.v {
-			c.v[k].RandomInit(f.Id, 0.1)
-		}
-	}
 	ret := c.w.DotFeatures(sample.Features)
 	for k, _ := range c.v {
 		a := c.v[k].DotFeatures(sample.Features)
@@ -51,42 +79,57 @@ func (c *FactorizeMachine) Command() cli.Command {
 		Name:     "fm",
 		Usage:    "FactorizeMachine",
 		Category: "FM",
-		Flags: []cli.Flag{
-			cli.IntFlag{
-				Name: "factors",
-			},
-			cli.Float64Flag{
-				Name: "learning-rate,lrate",
-			},
-			cli.Float64Flag{
-				Name: "regularization,r",
-			},
-		},
 	}
 }
 
-func (c *FactorizeMachine) Init(ctx *cli.Context) {
-	c.w = core.NewVector()
-	c.params.FactorNumber = ctx.Int("factors")
-	c.params.LearningRate = ctx.Float64("learning-rate")
-	c.params.Regularization = ctx.Float64("regularization")
-
+func NewFactorizeMachine(params FactorizeMachineParams) *FactorizeMachine {
+	c := &FactorizeMachine{w: core.NewVector(), v: []*core.Vector{}, params: params, rng: rand.New(rand.NewSource(1))}
 	for i := 0; i < c.params.FactorNumber; i++ {
 		c.v = append(c.v, core.NewVector())
 	}
+	return c
+}
+
+// Seed makes Train draw the initial weights and factors from a source seeded with seed.
+func (c *FactorizeMachine) Seed(seed int64) {
+	c.rng = rand.New(rand.NewSource(seed))
+}
+
+func (c *FactorizeMachine) Parameters() interface{} {
+	return &c.params
+}
+
+func (c *FactorizeMachine) Init() {
+	*c = *NewFactorizeMachine(c.params)
+}
+
+func (c *FactorizeMachine) Clear() {
+	rng := c.rng
+	*c = *NewFactorizeMachine(c.params)
+	c.rng = rng
 }
 
-func (c *FactorizeMachine) Clear() {}
+// WarmStart does nothing: Train always continues from the current weights and factors.
+func (c *FactorizeMachine) WarmStart() {}
 
 func (c *FactorizeMachine) Train(dataset *core.DataSet) {
+	if c.rng == nil {
+		c.Seed(1)
+	}
 	n := 0
 	for _, sample := range dataset.Samples {
 		n += 1
 		if n%10000 == 0 {
 			c.params.LearningRate *= 0.9
 		}
+		for _, f := range sample.Features {
+			c.w.RandomInitFrom(f.Id, 0.1, c.rng)
+			for k, _ := range c.v {
+				c.v[k].RandomInitFrom(f.Id, 0.1, c.rng)
+			}
+		}
 		pred := c.Predict(sample)
-		err := sample.LabelDoubleValue() - pred
+		err := sample.GetWeight() * (sample.LabelDoubleValue() - pred)
 
 		vx := []float64{}
 		for _, vf := range c.v {
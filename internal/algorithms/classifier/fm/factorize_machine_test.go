@@ -0,0 +1,33 @@
+package fm
+
+import (
+	"bytes"
+	"github.com/pantsing/hector/internal/core"
+	"testing"
+)
+
+func TestFactorizeMachineSaveLoad(t *testing.T) {
+	dataset := core.XORDataSet(500)
+	algo := &FactorizeMachine{w: core.NewVector(), params: FactorizeMachineParams{LearningRate: 0.01, Regularization: 0.001, FactorNumber: 3}}
+	for i := 0; i < algo.params.FactorNumber; i++ {
+		algo.v = append(algo.v, core.NewVector())
+	}
+	algo.Train(dataset)
+	var buf bytes.Buffer
+	if err := algo.SaveModel(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := &FactorizeMachine{}
+	if err := loaded.LoadModel(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.params.FactorNumber != algo.params.FactorNumber {
+		t.Errorf("factor number %d after load, want %d", loaded.params.FactorNumber, algo.params.FactorNumber)
+	}
+	for i, sample := range dataset.Samples {
+		if p, q := algo.Predict(sample), loaded.Predict(sample); p != q {
+			t.Fatalf("sample %d: prediction %v after load, want %v", i, q, p)
+		}
+	}
+}
@@ -1,14 +1,12 @@
 package lr
 
 import (
-	"bufio"
 	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/hector/internal/model"
 	"github.com/pantsing/hector/internal/utils"
 	"github.com/urfave/cli"
+	"io"
 	"math"
-	"os"
-	"strconv"
-	"strings"
 )
 
 func (algo *EPLogisticRegression) Command() cli.Command {
@@ -16,16 +14,12 @@ func (algo *EPLogisticRegression) Command() cli.Command {
 		Name:     "ep",
 		Usage:    "EPLogisticRegression",
 		Category: "LR",
-		Flags: []cli.Flag{
-			cli.Float64Flag{
-				Name: "beta",
-			},
-		},
 	}
 }
 
 type EPLogisticRegressionParams struct {
-	init_var, beta float64
+	InitVar float64 `flag:"init-var" default:"1" min:"0" usage:"Prior variance of the weights"`
+	Beta    float64 `flag:"beta" default:"1" min:"0" usage:"Variance of the noise added to the score of a sample"`
 }
 
 type EPLogisticRegression struct {
@@ -33,7 +27,7 @@ type EPLogisticRegression struct {
 	params EPLogisticRegressionParams
 }
 
-func (algo *EPLogisticRegression) SaveModel(path string) {
+func (algo *EPLogisticRegression) SaveModel(w io.Writer) error {
 	sb := utils.StringBuilder{}
 	for f, g := range algo.Model {
 		sb.Int64(f)
@@ -43,23 +37,27 @@ func (algo *EPLogisticRegression) SaveModel(path string) {
 		sb.Float(g.Vari)
 		sb.Write("\n")
 	}
-	sb.WriteToFile(path)
+	_, err := sb.WriteTo(w)
+	return err
 }
 
-func (algo *EPLogisticRegression) LoadModel(path string) {
-	file, _ := os.Open(path)
-	defer file.Close()
-
-	scaner := bufio.NewScanner(file)
+func (algo *EPLogisticRegression) LoadModel(r io.Reader) error {
+	scaner := model.NewScanner(r)
 	for scaner.Scan() {
-		line := scaner.Text()
-		tks := strings.Split(line, "\t")
-		fid, _ := strconv.ParseInt(tks[0], 10, 64)
-		mean, _ := strconv.ParseFloat(tks[1], 64)
-		vari, _ := strconv.ParseFloat(tks[2], 64)
-		g := utils.Gaussian{Mean: mean, Vari: vari}
+		fid := scaner.Int64(0)
+		g := utils.Gaussian{Mean: scaner.Float(1), Vari: scaner.Float(2)}
 		algo.Model[fid] = &g
 	}
+	return scaner.Err()
+}
+
+// FeatureWeights returns the means of the weights of the features.
+func (algo *EPLogisticRegression) FeatureWeights() map[int64]float64 {
+	weights := make(map[int64]float64, len(algo.Model))
+	for f, w := range algo.Model {
+		weights[f] = w.Mean
+	}
+	return weights
 }
 
 func (algo *EPLogisticRegression) Predict(sample *core.Sample) float64 {
@@ -70,21 +68,27 @@ func (algo *EPLogisticRegression) Predict(sample *core.Sample) float64 {
 		}
 		wi, ok := algo.Model[feature.Id]
 		if !ok {
-			wi = &(utils.Gaussian{Mean: 0.0, Vari: algo.params.init_var})
+			wi = &(utils.Gaussian{Mean: 0.0, Vari: algo.params.InitVar})
 		}
 		s.Mean += feature.Value * wi.Mean
 		s.Vari += feature.Value * feature.Value * wi.Vari
 	}
 
 	t := s
-	t.Vari += algo.params.beta
+	t.Vari += algo.params.Beta
 	return t.Integral(t.Mean / math.Sqrt(t.Vari))
 }
 
-func (algo *EPLogisticRegression) Init(ctx *cli.Context) {
-	algo.Model = make(map[int64]*utils.Gaussian)
-	algo.params.beta = ctx.Float64("beta")
-	algo.params.init_var = 1.0
+func NewEPLogisticRegression(params EPLogisticRegressionParams) *EPLogisticRegression {
+	return &EPLogisticRegression{Model: make(map[int64]*utils.Gaussian), params: params}
+}
+
+func (algo *EPLogisticRegression) Parameters() interface{} {
+	return &algo.params
+}
+
+func (algo *EPLogisticRegression) Init() {
+	*algo = *NewEPLogisticRegression(algo.params)
 }
 
 func (algo *EPLogisticRegression) Clear() {
@@ -92,6 +96,9 @@ func (algo *EPLogisticRegression) Clear() {
 	algo.Model = make(map[int64]*utils.Gaussian)
 }
 
+// WarmStart does nothing: Train always continues from the current weights.
+func (algo *EPLogisticRegression) WarmStart() {}
+
 func (algo *EPLogisticRegression) Train(dataset *core.DataSet) {
 
 	for _, sample := range dataset.Samples {
@@ -102,7 +109,7 @@ func (algo *EPLogisticRegression) Train(dataset *core.DataSet) {
 			}
 			wi, ok := algo.Model[feature.Id]
 			if !ok {
-				wi = &(utils.Gaussian{Mean: 0.0, Vari: algo.params.init_var})
+				wi = &(utils.Gaussian{Mean: 0.0, Vari: algo.params.InitVar})
 				algo.Model[feature.Id] = wi
 			}
 			s.Mean += feature.Value * wi.Mean
@@ -110,7 +117,7 @@ func (algo *EPLogisticRegression) Train(dataset *core.DataSet) {
 		}
 
 		t := s
-		t.Vari += algo.params.beta
+		t.Vari += algo.params.Beta
 
 		t2 := utils.Gaussian{Mean: 0.0, Vari: 0.0}
 		if sample.Label > 0.0 {
@@ -120,7 +127,7 @@ func (algo *EPLogisticRegression) Train(dataset *core.DataSet) {
 		}
 		t.MultGaussian(&t2)
 		s2 := t
-		s2.Vari += algo.params.beta
+		s2.Vari += algo.params.Beta
 		s0 := s
 		s.MultGaussian(&s2)
 
@@ -128,18 +135,21 @@ func (algo *EPLogisticRegression) Train(dataset *core.DataSet) {
 			if feature.Value == 0.0 {
 				continue
 			}
-			wi0 := utils.Gaussian{Mean: 0.0, Vari: algo.params.init_var}
+			wi0 := utils.Gaussian{Mean: 0.0, Vari: algo.params.InitVar}
 			w2 := utils.Gaussian{Mean: 0.0, Vari: 0.0}
 			wi, _ := algo.Model[feature.Id]
 			w2.Mean = (s.Mean - (s0.Mean - wi.Mean*feature.Value)) / feature.Value
 			w2.Vari = (s.Vari + (s0.Vari - wi.Vari*feature.Value*feature.Value)) / (feature.Value * feature.Value)
+			// The message of a sample of weight w counts as w samples: its precision is
+			// multiplied by w.
+			w2.Vari /= sample.GetWeight()
 			wi.MultGaussian(&w2)
 			wi_vari := wi.Vari
 			wi_new_vari := wi_vari * wi0.Vari / (0.99*wi0.Vari + 0.01*wi.Vari)
 			wi.Vari = wi_new_vari
 			wi.Mean = wi.Vari * (0.99*wi.Mean/wi_vari + 0.01*wi0.Mean/wi.Vari)
-			if wi.Vari < algo.params.init_var*0.01 {
-				wi.Vari = algo.params.init_var * 0.01
+			if wi.Vari < algo.params.InitVar*0.01 {
+				wi.Vari = algo.params.InitVar * 0.01
 			}
 			algo.Model[feature.Id] = wi
 		}
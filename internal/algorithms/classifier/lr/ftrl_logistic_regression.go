@@ -1,62 +1,39 @@
 package lr
 
 import (
-	"bufio"
+	"fmt"
 	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/hector/internal/model"
 	"github.com/pantsing/hector/internal/utils"
 	"github.com/qiniu/log"
 	"github.com/urfave/cli"
+	"io"
 	"math"
-	"os"
-	"strconv"
-	"strings"
 )
 
 func (algo *FTRLLogisticRegression) Command() cli.Command {
 	return cli.Command{
 		Name:  "ftrl",
 		Usage: "FTRL Logistic Regeression",
-		Flags: []cli.Flag{
-			cli.Float64Flag{
-				Name:  "alpha,a",
-				Value: 0.1,
-			},
-			cli.Float64Flag{
-				Name:  "beta,b",
-				Value: 1,
-			},
-			cli.Float64Flag{
-				Name:  "lambda1",
-				Value: 0.1,
-			},
-			cli.Float64Flag{
-				Name:  "lambda2",
-				Value: 0.1,
-			},
-			cli.IntFlag{
-				Name:  "steps",
-				Value: 60,
-			},
-			cli.BoolTFlag{
-				Name: "balance",
-			},
-			cli.Float64Flag{
-				Name:  "subsampleRate,ssr",
-				Value: 1,
-			},
-		},
 	}
 }
 
 type FTRLLogisticRegressionParams struct {
-	Alpha     float64
-	Beta      float64
-	Lambda1   float64
-	Lambda2   float64
-	IsBalance bool    // 正反例样本是否均衡
-	SSR       float64 // 欠采样比例Sub-Sample Ratio (0,1]。默认值为1,表示正反例样本数量均衡,不均衡时表示负例(label=0)样本。
-	SBR       float64 // Samples Balance Ratio 样本集正反例样本比率 (0,1]。默认值为1,表示正反例样本数量均衡。
-	Steps     int     // 最大迭代次数
+	Alpha     float64 `flag:"alpha,a" default:"0.1" min:"0" usage:"Learning rate alpha of the per-coordinate learning rates"`
+	Beta      float64 `flag:"beta,b" default:"1" min:"0" usage:"Learning rate beta of the per-coordinate learning rates"`
+	Lambda1   float64 `flag:"lambda1" default:"0.1" min:"0" usage:"L1 regularization"`
+	Lambda2   float64 `flag:"lambda2" default:"0.1" min:"0" usage:"L2 regularization"`
+	IsBalance bool    `flag:"balance" default:"true" usage:"Positive and negative samples are balanced. Set --balance=false to weight negative samples by 1/ssr. Prefer --neg-sample-rate, which also recalibrates the predictions"` // 正反例样本是否均衡
+	SSR       float64 `flag:"subsampleRate,ssr" default:"1" max:"1" usage:"Rate negative samples were sub-sampled at, in (0,1]"`                                                                                                     // 欠采样比例Sub-Sample Ratio (0,1]。默认值为1,表示正反例样本数量均衡,不均衡时表示负例(label=0)样本。
+	SBR       float64 `flag:"-"`                                                                                                                                                                                                     // Samples Balance Ratio 样本集正反例样本比率 (0,1]。默认值为1,表示正反例样本数量均衡。
+	Steps     int     `flag:"steps" default:"60" min:"1" usage:"Number of passes over the train set"`                                                                                                                                // 最大迭代次数
+}
+
+func (p *FTRLLogisticRegressionParams) Validate() error {
+	if p.SSR <= 0 {
+		return fmt.Errorf("subsampleRate is %v, it must be in (0,1]", p.SSR)
+	}
+	return nil
 }
 
 type FTRLFeatureWeight struct {
@@ -76,7 +53,7 @@ type FTRLLogisticRegression struct {
 	Params FTRLLogisticRegressionParams
 }
 
-func (algo *FTRLLogisticRegression) SaveModel(path string) {
+func (algo *FTRLLogisticRegression) SaveModel(w io.Writer) error {
 	sb := utils.StringBuilder{}
 	for f, g := range algo.Model {
 		sb.Int64(f)
@@ -86,23 +63,27 @@ func (algo *FTRLLogisticRegression) SaveModel(path string) {
 		sb.Float(g.zi)
 		sb.Write("\n")
 	}
-	sb.WriteToFile(path)
+	_, err := sb.WriteTo(w)
+	return err
 }
 
-func (algo *FTRLLogisticRegression) LoadModel(path string) {
-	file, _ := os.Open(path)
-	defer file.Close()
-
-	scaner := bufio.NewScanner(file)
+func (algo *FTRLLogisticRegression) LoadModel(r io.Reader) error {
+	scaner := model.NewScanner(r)
 	for scaner.Scan() {
-		line := scaner.Text()
-		tks := strings.Split(line, "\t")
-		fid, _ := strconv.ParseInt(tks[0], 10, 64)
-		ni, _ := strconv.ParseFloat(tks[1], 64)
-		zi, _ := strconv.ParseFloat(tks[2], 64)
-		g := FTRLFeatureWeight{ni: ni, zi: zi}
-		algo.Model[fid] = g
+		fid := scaner.Int64(0)
+		algo.Model[fid] = FTRLFeatureWeight{ni: scaner.Float(1), zi: scaner.Float(2)}
+	}
+	return scaner.Err()
+}
+
+// FeatureWeights returns the weights of the features, 0 for those the L1
+// regularization zeroes.
+func (algo *FTRLLogisticRegression) FeatureWeights() map[int64]float64 {
+	weights := make(map[int64]float64, len(algo.Model))
+	for f, w := range algo.Model {
+		weights[f] = w.Wi(algo.Params)
 	}
+	return weights
 }
 
 func (algo *FTRLLogisticRegression) Predict(sample *core.Sample) float64 {
@@ -116,19 +97,20 @@ func (algo *FTRLLogisticRegression) Predict(sample *core.Sample) float64 {
 	return utils.Sigmoid(ret)
 }
 
-func (algo *FTRLLogisticRegression) Init(ctx *cli.Context) {
-	algo.Model = make(map[int64]FTRLFeatureWeight)
-	algo.Params.Alpha = ctx.Float64("alpha")
-	algo.Params.Beta = ctx.Float64("beta")
-	algo.Params.Lambda1 = ctx.Float64("lambda1")
-	algo.Params.Lambda2 = ctx.Float64("lambda2")
-	algo.Params.Steps = ctx.Int("steps")
-	algo.Params.IsBalance = ctx.BoolT("balance")
-	algo.Params.SSR = ctx.Float64("subsampleRate")
-	if algo.Params.IsBalance {
-		algo.Params.SSR = 1
-		algo.Params.SBR = 1
+func NewFTRLLogisticRegression(params FTRLLogisticRegressionParams) *FTRLLogisticRegression {
+	if params.IsBalance {
+		params.SSR = 1
+		params.SBR = 1
 	}
+	return &FTRLLogisticRegression{Model: make(map[int64]FTRLFeatureWeight), Params: params}
+}
+
+func (algo *FTRLLogisticRegression) Parameters() interface{} {
+	return &algo.Params
+}
+
+func (algo *FTRLLogisticRegression) Init() {
+	*algo = *NewFTRLLogisticRegression(algo.Params)
 	log.Info(algo.Params)
 }
 
@@ -137,6 +119,9 @@ func (algo *FTRLLogisticRegression) Clear() {
 	algo.Model = make(map[int64]FTRLFeatureWeight)
 }
 
+// WarmStart does nothing: Train always continues from the current weights.
+func (algo *FTRLLogisticRegression) WarmStart() {}
+
 func (algo *FTRLLogisticRegression) Train(dataset *core.DataSet) {
 	n := float64(len(dataset.Samples))
 	labelDist := make(map[int]float64, 2)
@@ -148,25 +133,31 @@ func (algo *FTRLLogisticRegression) Train(dataset *core.DataSet) {
 	log.Infof("SBR:%.9g\t SSR:%.9g", algo.Params.SBR, algo.Params.SSR)
 	for step := 0; step < algo.Params.Steps; step++ {
 		for _, sample := range dataset.Samples {
-			prediction := algo.Predict(sample)
-			err := sample.LabelDoubleValue() - prediction
-			if !algo.Params.IsBalance && sample.Label != 1 {
-				err /= algo.Params.SSR
-			}
-			for _, feature := range sample.Features {
-				model_feature_value, ok := algo.Model[feature.Id]
-				if !ok {
-					model_feature_value = FTRLFeatureWeight{0.0, 0.0}
-				}
-				zi := model_feature_value.zi
-				ni := model_feature_value.ni
-				gi := -1 * err * feature.Value
-				sigma := (math.Sqrt(ni+gi*gi) - math.Sqrt(ni)) / algo.Params.Alpha
-				wi := model_feature_value.Wi(algo.Params)
-				zi += gi - sigma*wi
-				ni += gi * gi
-				algo.Model[feature.Id] = FTRLFeatureWeight{zi: zi, ni: ni}
-			}
+			algo.Learn(sample)
+		}
+	}
+}
+
+// Learn makes a FTRL-proximal update of the weights of the features of sample.
+func (algo *FTRLLogisticRegression) Learn(sample *core.Sample) float64 {
+	prediction := algo.Predict(sample)
+	err := sample.GetWeight() * (sample.LabelDoubleValue() - prediction)
+	if !algo.Params.IsBalance && sample.Label != 1 {
+		err /= algo.Params.SSR
+	}
+	for _, feature := range sample.Features {
+		model_feature_value, ok := algo.Model[feature.Id]
+		if !ok {
+			model_feature_value = FTRLFeatureWeight{0.0, 0.0}
 		}
+		zi := model_feature_value.zi
+		ni := model_feature_value.ni
+		gi := -1 * err * feature.Value
+		sigma := (math.Sqrt(ni+gi*gi) - math.Sqrt(ni)) / algo.Params.Alpha
+		wi := model_feature_value.Wi(algo.Params)
+		zi += gi - sigma*wi
+		ni += gi * gi
+		algo.Model[feature.Id] = FTRLFeatureWeight{zi: zi, ni: ni}
 	}
+	return prediction
 }
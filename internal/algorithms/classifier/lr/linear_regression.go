@@ -1,13 +1,11 @@
 package lr
 
 import (
-	"bufio"
 	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/hector/internal/model"
 	"github.com/pantsing/hector/internal/utils"
 	"github.com/urfave/cli"
-	"os"
-	"strconv"
-	"strings"
+	"io"
 )
 
 func (algo *LinearRegression) Command() cli.Command {
@@ -15,14 +13,6 @@ func (algo *LinearRegression) Command() cli.Command {
 		Name:     "linearRegr",
 		Usage:    "Linear Regression",
 		Category: "LR",
-		Flags: []cli.Flag{
-			cli.Float64Flag{
-				Name: "learning-rate,lrate",
-			},
-			cli.Float64Flag{
-				Name: "regularization,r",
-			},
-		},
 	}
 }
 
@@ -31,7 +21,7 @@ type LinearRegression struct {
 	Params LogisticRegressionParams
 }
 
-func (algo *LinearRegression) SaveModel(path string) {
+func (algo *LinearRegression) SaveModel(w io.Writer) error {
 	sb := utils.StringBuilder{}
 	for f, g := range algo.Model {
 		sb.Int64(f)
@@ -39,27 +29,25 @@ func (algo *LinearRegression) SaveModel(path string) {
 		sb.Float(g)
 		sb.Write("\n")
 	}
-	sb.WriteToFile(path)
+	_, err := sb.WriteTo(w)
+	return err
 }
 
-func (algo *LinearRegression) LoadModel(path string) {
-	file, _ := os.Open(path)
-	defer file.Close()
-
-	scaner := bufio.NewScanner(file)
+func (algo *LinearRegression) LoadModel(r io.Reader) error {
+	scaner := model.NewScanner(r)
 	for scaner.Scan() {
-		line := scaner.Text()
-		tks := strings.Split(line, "\t")
-		fid, _ := strconv.ParseInt(tks[0], 10, 64)
-		fw, _ := strconv.ParseFloat(tks[1], 64)
+		fid, fw := scaner.Int64(0), scaner.Float(1)
 		algo.Model[fid] = fw
 	}
+	return scaner.Err()
+}
+
+func (algo *LinearRegression) Parameters() interface{} {
+	return &algo.Params
 }
 
-func (algo *LinearRegression) Init(ctx *cli.Context) {
+func (algo *LinearRegression) Init() {
 	algo.Model = make(map[int64]float64)
-	algo.Params.LearningRate = ctx.Float64("learning-rate")
-	algo.Params.Regularization = ctx.Float64("regularization")
 }
 
 func (algo *LinearRegression) Train(dataset *core.DataSet) {
@@ -81,6 +69,11 @@ func (algo *LinearRegression) Train(dataset *core.DataSet) {
 	}
 }
 
+// FeatureWeights returns the weights of the features.
+func (algo *LinearRegression) FeatureWeights() map[int64]float64 {
+	return algo.Model
+}
+
 func (algo *LinearRegression) Predict(sample *core.Sample) float64 {
 	ret := 0.0
 	for _, feature := range sample.Features {
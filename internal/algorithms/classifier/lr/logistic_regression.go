@@ -1,13 +1,11 @@
 package lr
 
 import (
-	"bufio"
 	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/hector/internal/model"
 	"github.com/pantsing/hector/internal/utils"
 	"github.com/urfave/cli"
-	"os"
-	"strconv"
-	"strings"
+	"io"
 )
 
 func (algo *LogisticRegression) Command() cli.Command {
@@ -15,31 +13,22 @@ func (algo *LogisticRegression) Command() cli.Command {
 		Name:     "logRegr",
 		Usage:    "Logistic Regression",
 		Category: "LR",
-		Flags: []cli.Flag{
-			cli.Float64Flag{
-				Name:  "learning-rate,lrate",
-				Value: 0.01,
-			},
-			cli.Float64Flag{
-				Name:  "regularization,r",
-				Value: 0.01,
-			},
-		},
 	}
 }
 
 type LogisticRegressionParams struct {
-	LearningRate   float64
-	Regularization float64
-	Steps          int
+	LearningRate   float64 `flag:"learning-rate,lrate" default:"0.01" min:"0" usage:"Learning rate, multiplied by 0.9 after every step"`
+	Regularization float64 `flag:"regularization,r" default:"0.01" min:"0" usage:"L2 regularization"`
+	Steps          int     `flag:"steps" default:"10" min:"1" usage:"Number of passes over the train set"`
 }
 
 type LogisticRegression struct {
 	Model  map[int64]float64
 	Params LogisticRegressionParams
+	warm   bool
 }
 
-func (algo *LogisticRegression) SaveModel(path string) {
+func (algo *LogisticRegression) SaveModel(w io.Writer) error {
 	sb := utils.StringBuilder{}
 	for f, g := range algo.Model {
 		sb.Int64(f)
@@ -47,41 +36,51 @@ func (algo *LogisticRegression) SaveModel(path string) {
 		sb.Float(g)
 		sb.Write("\n")
 	}
-	sb.WriteToFile(path)
+	_, err := sb.WriteTo(w)
+	return err
 }
 
-func (algo *LogisticRegression) LoadModel(path string) {
-	file, _ := os.Open(path)
-	defer file.Close()
+func (algo *LogisticRegression) LoadModel(r io.Reader) error {
 	algo.Model = make(map[int64]float64)
-	scaner := bufio.NewScanner(file)
+	scaner := model.NewScanner(r)
 	for scaner.Scan() {
-		line := scaner.Text()
-		tks := strings.Split(line, "\t")
-		fid, _ := strconv.ParseInt(tks[0], 10, 64)
-		fw, _ := strconv.ParseFloat(tks[1], 64)
+		fid, fw := scaner.Int64(0), scaner.Float(1)
 		algo.Model[fid] = fw
 	}
+	return scaner.Err()
 }
 
-func (algo *LogisticRegression) Init(ctx *cli.Context) {
-	algo.Model = make(map[int64]float64)
-	algo.Params.LearningRate = ctx.Float64("learning-rate")
-	algo.Params.Regularization = ctx.Float64("regularization")
-	algo.Params.Steps = ctx.Int("steps")
+func NewLogisticRegression(params LogisticRegressionParams) *LogisticRegression {
+	return &LogisticRegression{Model: make(map[int64]float64), Params: params}
+}
+
+func (algo *LogisticRegression) Parameters() interface{} {
+	return &algo.Params
+}
+
+func (algo *LogisticRegression) Init() {
+	*algo = *NewLogisticRegression(algo.Params)
 }
 
 func (algo *LogisticRegression) Clear() {
 	algo.Model = nil
 	algo.Model = make(map[int64]float64)
+	algo.warm = false
+}
+
+// WarmStart makes Train keep the loaded weights.
+func (algo *LogisticRegression) WarmStart() {
+	algo.warm = true
 }
 
 func (algo *LogisticRegression) Train(dataset *core.DataSet) {
-	algo.Model = make(map[int64]float64)
+	if !algo.warm {
+		algo.Model = make(map[int64]float64)
+	}
 	for step := 0; step < algo.Params.Steps; step++ {
 		for _, sample := range dataset.Samples {
 			prediction := algo.Predict(sample)
-			err := sample.LabelDoubleValue() - prediction
+			err := sample.GetWeight() * (sample.LabelDoubleValue() - prediction)
 			for _, feature := range sample.Features {
 				model_feature_value, ok := algo.Model[feature.Id]
 				if !ok {
@@ -95,6 +94,11 @@ func (algo *LogisticRegression) Train(dataset *core.DataSet) {
 	}
 }
 
+// FeatureWeights returns the weights of the features.
+func (algo *LogisticRegression) FeatureWeights() map[int64]float64 {
+	return algo.Model
+}
+
 func (algo *LogisticRegression) Predict(sample *core.Sample) float64 {
 	ret := 0.0
 	for _, feature := range sample.Features {
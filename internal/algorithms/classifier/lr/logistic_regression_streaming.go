@@ -1,12 +1,8 @@
 package lr
 
 import (
-	"bufio"
-	"log"
-	"math"
-	"os"
-	"strconv"
-	"strings"
+	"github.com/pantsing/hector/internal/model"
+	"io"
 
 	"github.com/pantsing/hector/internal/core"
 	"github.com/pantsing/hector/internal/utils"
@@ -18,17 +14,6 @@ func (algo *LogisticRegressionStream) Command() cli.Command {
 		Name:     "streamLogRegr",
 		Usage:    "Streaming Logistic Regression",
 		Category: "LR",
-		Flags: []cli.Flag{
-			cli.Float64Flag{
-				Name: "learning-rate,lrate",
-			},
-			cli.Float64Flag{
-				Name: "regularization,r",
-			},
-			cli.IntFlag{
-				Name: "steps",
-			},
-		},
 	}
 }
 
@@ -37,7 +22,7 @@ type LogisticRegressionStream struct {
 	Params LogisticRegressionParams
 }
 
-func (algo *LogisticRegressionStream) SaveModel(path string) {
+func (algo *LogisticRegressionStream) SaveModel(w io.Writer) error {
 	sb := utils.StringBuilder{}
 	for f, g := range algo.Model {
 		sb.Int64(f)
@@ -45,52 +30,65 @@ func (algo *LogisticRegressionStream) SaveModel(path string) {
 		sb.Float(g)
 		sb.Write("\n")
 	}
-	sb.WriteToFile(path)
+	_, err := sb.WriteTo(w)
+	return err
 }
 
-func (algo *LogisticRegressionStream) LoadModel(path string) {
-	file, _ := os.Open(path)
-	defer file.Close()
-
-	scaner := bufio.NewScanner(file)
+func (algo *LogisticRegressionStream) LoadModel(r io.Reader) error {
+	scaner := model.NewScanner(r)
 	for scaner.Scan() {
-		line := scaner.Text()
-		tks := strings.Split(line, "\t")
-		fid, _ := strconv.ParseInt(tks[0], 10, 64)
-		fw, _ := strconv.ParseFloat(tks[1], 64)
+		fid, fw := scaner.Int64(0), scaner.Float(1)
 		algo.Model[fid] = fw
 	}
+	return scaner.Err()
+}
+
+func (algo *LogisticRegressionStream) Parameters() interface{} {
+	return &algo.Params
+}
+
+func NewLogisticRegressionStream(params LogisticRegressionParams) *LogisticRegressionStream {
+	return &LogisticRegressionStream{Model: make(map[int64]float64), Params: params}
+}
+
+func (algo *LogisticRegressionStream) Init() {
+	*algo = *NewLogisticRegressionStream(algo.Params)
 }
 
-func (algo *LogisticRegressionStream) Init(ctx *cli.Context) {
+func (algo *LogisticRegressionStream) Clear() {
+	algo.Model = nil
 	algo.Model = make(map[int64]float64)
-	algo.Params.LearningRate = ctx.Float64("learning-rate")
-	algo.Params.Regularization = ctx.Float64("regularization")
-	algo.Params.Steps = ctx.Int("steps")
 }
 
-func (algo *LogisticRegressionStream) Train(dataset *core.StreamingDataSet) {
+// Train makes Steps passes of Learn over the data set.
+func (algo *LogisticRegressionStream) Train(dataset *core.DataSet) {
 	algo.Model = make(map[int64]float64)
-	totalErr := 0.0
-	n := 0
-	for sample := range dataset.Samples {
-		prediction := algo.Predict(sample)
-		err := sample.LabelDoubleValue() - prediction
-		totalErr += math.Abs(err)
-		n += 1
-		if n%100000 == 0 {
-			log.Println("proc ", n, totalErr/100000.0, sample.LabelDoubleValue(), prediction)
-			totalErr = 0.0
+	for step := 0; step < algo.Params.Steps; step++ {
+		for _, sample := range dataset.Samples {
+			algo.Learn(sample)
 		}
-		for _, feature := range sample.Features {
-			model_feature_value, ok := algo.Model[feature.Id]
-			if !ok {
-				model_feature_value = 0.0
-			}
-			model_feature_value += algo.Params.LearningRate * (err*feature.Value - algo.Params.Regularization*model_feature_value)
-			algo.Model[feature.Id] = model_feature_value
+		algo.Params.LearningRate *= 0.9
+	}
+}
+
+// Learn makes a step of stochastic gradient descent on sample.
+func (algo *LogisticRegressionStream) Learn(sample *core.Sample) float64 {
+	prediction := algo.Predict(sample)
+	err := sample.GetWeight() * (sample.LabelDoubleValue() - prediction)
+	for _, feature := range sample.Features {
+		model_feature_value, ok := algo.Model[feature.Id]
+		if !ok {
+			model_feature_value = 0.0
 		}
+		model_feature_value += algo.Params.LearningRate * (err*feature.Value - algo.Params.Regularization*model_feature_value)
+		algo.Model[feature.Id] = model_feature_value
 	}
+	return prediction
+}
+
+// FeatureWeights returns the weights of the features.
+func (algo *LogisticRegressionStream) FeatureWeights() map[int64]float64 {
+	return algo.Model
 }
 
 func (algo *LogisticRegressionStream) Predict(sample *core.Sample) float64 {
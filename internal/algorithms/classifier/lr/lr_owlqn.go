@@ -1,14 +1,12 @@
 package lr
 
 import (
-	"bufio"
 	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/hector/internal/model"
 	"github.com/pantsing/hector/internal/utils"
 	"github.com/urfave/cli"
+	"io"
 	"math"
-	"os"
-	"strconv"
-	"strings"
 )
 
 func (lr *LROWLQN) Command() cli.Command {
@@ -16,16 +14,11 @@ func (lr *LROWLQN) Command() cli.Command {
 		Name:     "lrowlqn",
 		Usage:    "LROWLQN",
 		Category: "LR",
-		Flags: []cli.Flag{
-			cli.Float64Flag{
-				Name: "regularization, r",
-			},
-		},
 	}
 }
 
 type LROWLQNParams struct {
-	Regularization float64
+	Regularization float64 `flag:"regularization,r" default:"0.01" min:"0" usage:"L1 regularization"`
 }
 
 type LROWLQN struct {
@@ -38,7 +31,7 @@ type LROWLQN struct {
 	lastGrad *core.Vector
 }
 
-func (lr *LROWLQN) SaveModel(path string) {
+func (lr *LROWLQN) SaveModel(w io.Writer) error {
 	sb := utils.StringBuilder{}
 	for key, val := range lr.Model.Data {
 		sb.Int64(key)
@@ -46,26 +39,29 @@ func (lr *LROWLQN) SaveModel(path string) {
 		sb.Float(val)
 		sb.Write("\n")
 	}
-	sb.WriteToFile(path)
+	_, err := sb.WriteTo(w)
+	return err
 }
 
-func (lr *LROWLQN) LoadModel(path string) {
-	file, _ := os.Open(path)
-	defer file.Close()
-
-	scaner := bufio.NewScanner(file)
+func (lr *LROWLQN) LoadModel(r io.Reader) error {
+	scaner := model.NewScanner(r)
 	for scaner.Scan() {
-		line := scaner.Text()
-		tks := strings.Split(line, "\t")
-		key, _ := strconv.ParseInt(tks[0], 10, 64)
-		val, _ := strconv.ParseFloat(tks[1], 64)
-		lr.Model.SetValue(key, val)
+		fid, fw := scaner.Int64(0), scaner.Float(1)
+		lr.Model.SetValue(fid, fw)
 	}
+	return scaner.Err()
 }
 
-func (lr *LROWLQN) Init(ctx *cli.Context) {
-	lr.Model = core.NewVector()
-	lr.Params.Regularization = ctx.Float64("regularization")
+func NewLROWLQN(params LROWLQNParams) *LROWLQN {
+	return &LROWLQN{Model: core.NewVector(), Params: params}
+}
+
+func (lr *LROWLQN) Parameters() interface{} {
+	return &lr.Params
+}
+
+func (lr *LROWLQN) Init() {
+	*lr = *NewLROWLQN(lr.Params)
 }
 
 func (lr *LROWLQN) Clear() {
@@ -159,6 +155,11 @@ func (lr *LROWLQN) getScore(model *core.Vector, sample *core.Sample) float64 {
 	return score
 }
 
+// FeatureWeights returns the weights of the features.
+func (lr *LROWLQN) FeatureWeights() map[int64]float64 {
+	return lr.Model.Data
+}
+
 func (lr *LROWLQN) Predict(sample *core.Sample) float64 {
 	score := lr.getScore(lr.Model, sample)
 	score = 1.0 / (1.0 + math.Exp(-score))
@@ -0,0 +1,146 @@
+package lr
+
+import (
+	"github.com/pantsing/hector/internal/algorithms/classifier/common"
+	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/hector/internal/model"
+	"github.com/pantsing/hector/internal/utils"
+	"github.com/urfave/cli"
+	"io"
+)
+
+func (algo *RankNet) Command() cli.Command {
+	return cli.Command{
+		Name:     "ranknet",
+		Usage:    "RankNet pairwise logistic ranking within qid groups, weighted by the change of NDCG",
+		Category: "LR",
+	}
+}
+
+type RankNetParams struct {
+	LearningRate   float64 `flag:"learning-rate,lrate" default:"0.01" min:"0" usage:"Learning rate, multiplied by 0.9 after every step"`
+	Regularization float64 `flag:"regularization,r" default:"0.01" min:"0" usage:"L2 regularization"`
+	Steps          int     `flag:"steps" default:"10" min:"1" usage:"Number of passes over the train set"`
+	NDCGAt         int     `flag:"ndcg-at" default:"10" min:"0" usage:"Rank k of the NDCG@k the pairs are weighted by the change of, 0 for the whole query"`
+}
+
+// RankNet is a linear scoring function learning, with the SGD loop of
+// LogisticRegression, the logistic loss of the order of every pair of samples of a
+// query with different labels: the pair is a positive sample of the difference of the
+// features of the better sample and those of the worse one. As in LambdaRank, a pair is
+// weighted by the change of the NDCG of the query if the two samples were swapped.
+// Samples are grouped in queries by their Qid.
+type RankNet struct {
+	Model  map[int64]float64
+	Params RankNetParams
+}
+
+func (algo *RankNet) SaveModel(w io.Writer) error {
+	sb := utils.StringBuilder{}
+	for f, g := range algo.Model {
+		sb.Int64(f)
+		sb.Write("\t")
+		sb.Float(g)
+		sb.Write("\n")
+	}
+	_, err := sb.WriteTo(w)
+	return err
+}
+
+func (algo *RankNet) LoadModel(r io.Reader) error {
+	algo.Model = make(map[int64]float64)
+	scaner := model.NewScanner(r)
+	for scaner.Scan() {
+		fid, fw := scaner.Int64(0), scaner.Float(1)
+		algo.Model[fid] = fw
+	}
+	return scaner.Err()
+}
+
+func NewRankNet(params RankNetParams) *RankNet {
+	return &RankNet{Model: make(map[int64]float64), Params: params}
+}
+
+func (algo *RankNet) Parameters() interface{} {
+	return &algo.Params
+}
+
+func (algo *RankNet) Init() {
+	*algo = *NewRankNet(algo.Params)
+}
+
+func (algo *RankNet) Clear() {
+	algo.Model = make(map[int64]float64)
+}
+
+// score returns the linear score of the sample.
+func (algo *RankNet) score(sample *core.Sample) float64 {
+	ret := 0.0
+	for _, feature := range sample.Features {
+		ret += algo.Model[feature.Id] * feature.Value
+	}
+	return ret
+}
+
+// pairs returns the data set of the pairs of the samples of a query, weighted by the
+// change of NDCG at the current scores. Pairs which would not change it are left out.
+func (algo *RankNet) pairs(query []*core.Sample) *core.DataSet {
+	labels := make([]int, len(query))
+	scores := make([]float64, len(query))
+	for i, sample := range query {
+		labels[i], scores[i] = sample.Label, algo.score(sample)
+	}
+	ret := core.NewDataSet()
+	common.RankPairs(labels, scores, algo.Params.NDCGAt, func(better, worse int, deltaNDCG float64) {
+		if deltaNDCG == 0 {
+			return
+		}
+		// The IDs in the order they come, for the sums of the scores to be reproducible.
+		ids := []int64{}
+		diff := make(map[int64]float64)
+		add := func(features []core.Feature, sign float64) {
+			for _, feature := range features {
+				if _, ok := diff[feature.Id]; !ok {
+					ids = append(ids, feature.Id)
+				}
+				diff[feature.Id] += sign * feature.Value
+			}
+		}
+		add(query[better].Features, 1)
+		add(query[worse].Features, -1)
+		pair := core.NewSample()
+		pair.Label, pair.Weight = 1, deltaNDCG
+		for _, id := range ids {
+			if diff[id] != 0 {
+				pair.AddFeature(core.Feature{Id: id, Value: diff[id]})
+			}
+		}
+		ret.AddSample(pair)
+	})
+	return ret
+}
+
+func (algo *RankNet) Train(dataset *core.DataSet) {
+	algo.Model = make(map[int64]float64)
+	queries := dataset.Queries()
+	// One step of SGD on the pairs of every query, whose weights change with the scores.
+	sgd := &LogisticRegression{
+		Model:  algo.Model,
+		Params: LogisticRegressionParams{Regularization: algo.Params.Regularization, Steps: 1},
+		warm:   true,
+	}
+	rate := algo.Params.LearningRate
+	for step := 0; step < algo.Params.Steps; step++ {
+		for _, query := range queries {
+			sgd.Params.LearningRate = rate
+			sgd.Train(algo.pairs(query))
+		}
+		rate *= 0.9
+	}
+}
+
+// Predict returns the sigmoid of the score of the sample, which orders the samples of
+// a query.
+func (algo *RankNet) Predict(sample *core.Sample) float64 {
+	return utils.Sigmoid(algo.score(sample))
+}
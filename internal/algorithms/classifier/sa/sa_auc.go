@@ -4,20 +4,42 @@ import (
 	"fmt"
 	"github.com/pantsing/hector/internal/algorithms/eval"
 	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/hector/internal/model"
+	"github.com/pantsing/hector/internal/utils"
 	"github.com/urfave/cli"
+	"io"
 	"math/rand"
+	"sort"
 )
 
+// SAOptAUCParams is empty, SAOptAUC has no parameters.
+type SAOptAUCParams struct{}
+
 type SAOptAUC struct {
 	Model map[int64]float64
+	rng   *rand.Rand
 }
 
-func (self *SAOptAUC) SaveModel(path string) {
-
+func (self *SAOptAUC) SaveModel(w io.Writer) error {
+	sb := utils.StringBuilder{}
+	for f, g := range self.Model {
+		sb.Int64(f)
+		sb.Write("\t")
+		sb.Float(g)
+		sb.Write("\n")
+	}
+	_, err := sb.WriteTo(w)
+	return err
 }
 
-func (self *SAOptAUC) LoadModel(path string) {
-
+func (self *SAOptAUC) LoadModel(r io.Reader) error {
+	self.Model = make(map[int64]float64)
+	scaner := model.NewScanner(r)
+	for scaner.Scan() {
+		fid, fw := scaner.Int64(0), scaner.Float(1)
+		self.Model[fid] = fw
+	}
+	return scaner.Err()
 }
 
 func (algo *SAOptAUC) Command() cli.Command {
@@ -28,8 +50,21 @@ func (algo *SAOptAUC) Command() cli.Command {
 	}
 }
 
-func (algo *SAOptAUC) Init(ctx *cli.Context) {
-	algo.Model = make(map[int64]float64)
+func NewSAOptAUC() *SAOptAUC {
+	return &SAOptAUC{Model: make(map[int64]float64), rng: rand.New(rand.NewSource(1))}
+}
+
+// Seed makes Train draw the weights tried from a source seeded with seed.
+func (algo *SAOptAUC) Seed(seed int64) {
+	algo.rng = rand.New(rand.NewSource(seed))
+}
+
+func (algo *SAOptAUC) Parameters() interface{} {
+	return &SAOptAUCParams{}
+}
+
+func (algo *SAOptAUC) Init() {
+	*algo = *NewSAOptAUC()
 }
 
 func (algo *SAOptAUC) Clear() {
@@ -46,6 +81,9 @@ func (algo *SAOptAUC) TrainAUC(samples []*core.Sample) float64 {
 }
 
 func (algo *SAOptAUC) Train(dataset *core.DataSet) {
+	if algo.rng == nil {
+		algo.Seed(1)
+	}
 	algo.Model = make(map[int64]float64)
 	samples := []*core.Sample{}
 	for _, sample := range dataset.Samples {
@@ -59,11 +97,12 @@ func (algo *SAOptAUC) Train(dataset *core.DataSet) {
 	for fid, _ := range algo.Model {
 		features = append(features, fid)
 	}
+	sort.Slice(features, func(i, j int) bool { return features[i] < features[j] })
 
 	prev_auc := 0.5
 	for i := 0; i < 5000; i++ {
-		add := rand.Float64()
-		fid := features[rand.Intn(len(features))]
+		add := algo.rng.Float64()
+		fid := features[algo.rng.Intn(len(features))]
 		fweight := algo.Model[fid]
 		algo.Model[fid] = add
 		auc := algo.TrainAUC(samples)
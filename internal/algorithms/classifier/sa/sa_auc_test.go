@@ -0,0 +1,27 @@
+package sa
+
+import (
+	"bytes"
+	"github.com/pantsing/hector/internal/core"
+	"testing"
+)
+
+func TestSAOptAUCSaveLoad(t *testing.T) {
+	dataset := core.LinearDataSet(100)
+	algo := &SAOptAUC{}
+	algo.Train(dataset)
+	var buf bytes.Buffer
+	if err := algo.SaveModel(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := &SAOptAUC{}
+	if err := loaded.LoadModel(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for i, sample := range dataset.Samples {
+		if p, q := algo.Predict(sample), loaded.Predict(sample); p != q {
+			t.Fatalf("sample %d: prediction %v after load, want %v", i, q, p)
+		}
+	}
+}
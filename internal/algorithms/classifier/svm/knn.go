@@ -3,7 +3,10 @@ package svm
 import (
 	"github.com/pantsing/hector/internal/algorithms/eval"
 	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/hector/internal/model"
+	"github.com/pantsing/hector/internal/utils"
 	"github.com/urfave/cli"
+	"io"
 	"math"
 	"math/rand"
 )
@@ -13,30 +16,70 @@ func (self *KNN) Command() cli.Command {
 		Name:     "KNN",
 		Usage:    "K Nearest Neighbour",
 		Category: "SVM",
-		Flags: []cli.Flag{
-			cli.Float64Flag{
-				Name: "k",
-			},
-		},
 	}
 }
 
+type KNNParams struct {
+	K int `flag:"k" default:"10" min:"1" usage:"Number of neighbours voting"`
+}
+
 type KNN struct {
 	sv     []*core.Vector
 	labels []int
 	k      int
+	params KNNParams
+	rng    *rand.Rand
 }
 
-func (self *KNN) SaveModel(path string) {
+// SaveModel writes k on the first line, then one support vector per line after its label.
+func (self *KNN) SaveModel(w io.Writer) error {
+	sb := utils.StringBuilder{}
+	sb.Int(self.k)
+	sb.Write("\n")
+	for i, sv := range self.sv {
+		sb.Int(self.labels[i])
+		sb.Write("\t")
+		sb.WriteBytes(sv.ToString())
+		sb.Write("\n")
+	}
+	_, err := sb.WriteTo(w)
+	return err
+}
 
+func (self *KNN) LoadModel(r io.Reader) error {
+	self.sv = []*core.Vector{}
+	self.labels = []int{}
+	scaner := model.NewScanner(r)
+	if scaner.Scan() {
+		self.k = scaner.Int(0)
+	}
+	for scaner.Scan() {
+		label := scaner.Int(0)
+		sv := core.NewVector()
+		if err := sv.FromString(scaner.String(1)); err != nil {
+			scaner.Errorf("field 2: %v", err)
+		}
+		self.sv = append(self.sv, sv)
+		self.labels = append(self.labels, label)
+	}
+	return scaner.Err()
 }
 
-func (self *KNN) LoadModel(path string) {
+func NewKNN(params KNNParams) *KNN {
+	return &KNN{k: params.K, params: params, rng: rand.New(rand.NewSource(1))}
+}
 
+// Seed makes Train draw the neighbours from a source seeded with seed.
+func (c *KNN) Seed(seed int64) {
+	c.rng = rand.New(rand.NewSource(seed))
 }
 
-func (c *KNN) Init(ctx *cli.Context) {
-	c.k = ctx.Int("k")
+func (c *KNN) Parameters() interface{} {
+	return &c.params
+}
+
+func (c *KNN) Init() {
+	*c = *NewKNN(c.params)
 }
 
 func (c *KNN) Clear() {}
@@ -77,10 +120,13 @@ func (c *KNN) PredictMultiClass(sample *core.Sample) *core.ArrayVector {
 }
 
 func (c *KNN) Train(dataset *core.DataSet) {
+	if c.rng == nil {
+		c.Seed(1)
+	}
 	c.sv = []*core.Vector{}
 	c.labels = []int{}
 	for i := 0; i < 1000; i++ {
-		k := rand.Intn(len(dataset.Samples))
+		k := c.rng.Intn(len(dataset.Samples))
 		c.sv = append(c.sv, dataset.Samples[k].GetFeatureVector())
 		c.labels = append(c.labels, dataset.Samples[k].Label)
 	}
@@ -0,0 +1,33 @@
+package svm
+
+import (
+	"bytes"
+	"github.com/pantsing/hector/internal/core"
+	"testing"
+)
+
+func TestKNNSaveLoad(t *testing.T) {
+	dataset := core.XORDataSet(200)
+	algo := &KNN{k: 5}
+	algo.Train(dataset)
+	var buf bytes.Buffer
+	if err := algo.SaveModel(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := &KNN{}
+	if err := loaded.LoadModel(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.k != algo.k {
+		t.Errorf("k is %d after load, want %d", loaded.k, algo.k)
+	}
+	for i, sample := range dataset.Samples {
+		p, q := algo.PredictMultiClass(sample), loaded.PredictMultiClass(sample)
+		for label := 0; label <= 1; label++ {
+			if p.GetValue(label) != q.GetValue(label) {
+				t.Fatalf("sample %d: prediction of label %d is %v after load, want %v", i, label, q.GetValue(label), p.GetValue(label))
+			}
+		}
+	}
+}
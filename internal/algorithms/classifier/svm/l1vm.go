@@ -1,11 +1,16 @@
 package svm
 
 import (
+	"bytes"
 	"github.com/pantsing/hector/internal/algorithms/classifier/lr"
 	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/hector/internal/model"
+	"github.com/pantsing/hector/internal/utils"
 	"github.com/urfave/cli"
+	"io"
 	"math"
 	"math/rand"
+	"strings"
 )
 
 func Distance(x, y *core.Vector) float64 {
@@ -21,19 +26,78 @@ func RBFKernel(x, y *core.Vector, radius float64) float64 {
 	return ret
 }
 
+type L1VMParams struct {
+	FTRL           lr.FTRLLogisticRegressionParams // FTRL trained on the kernel features
+	Radius         float64                         `flag:"radius" default:"1" min:"0" usage:"Radius of the RBF kernel"`
+	SupportVectors int                             `flag:"sv" default:"100" min:"1" usage:"Number of support vectors"`
+}
+
 type L1VM struct {
 	sv     []*core.Vector
 	ftrl   *lr.FTRLLogisticRegression
 	radius float64
 	count  int
+	params L1VMParams
+	rng    *rand.Rand
 }
 
-func (self *L1VM) SaveModel(path string) {
-
+/*
+SaveModel writes the kernel radius on the first line, then the support vectors as "sv"
+lines and the lines of the FTRL model over their kernel features as "w" lines.
+*/
+func (self *L1VM) SaveModel(w io.Writer) error {
+	sb := utils.StringBuilder{}
+	sb.Float(self.radius)
+	sb.Write("\n")
+	for _, sv := range self.sv {
+		sb.Write("sv\t")
+		sb.WriteBytes(sv.ToString())
+		sb.Write("\n")
+	}
+	var weights bytes.Buffer
+	if err := self.ftrl.SaveModel(&weights); err != nil {
+		return err
+	}
+	for _, line := range strings.SplitAfter(weights.String(), "\n") {
+		if line != "" {
+			sb.Write("w\t")
+			sb.Write(line)
+		}
+	}
+	_, err := sb.WriteTo(w)
+	return err
 }
 
-func (self *L1VM) LoadModel(path string) {
-
+func (self *L1VM) LoadModel(r io.Reader) error {
+	self.sv = []*core.Vector{}
+	if self.ftrl == nil {
+		self.ftrl = &(lr.FTRLLogisticRegression{})
+	}
+	self.ftrl.Model = make(map[int64]lr.FTRLFeatureWeight)
+	var weights bytes.Buffer
+	scaner := model.NewScanner(r)
+	if scaner.Scan() {
+		self.radius = scaner.Float(0)
+	}
+	for scaner.Scan() {
+		switch scaner.String(0) {
+		case "sv":
+			sv := core.NewVector()
+			if err := sv.FromString(scaner.String(1)); err != nil {
+				scaner.Errorf("field 2: %v", err)
+			}
+			self.sv = append(self.sv, sv)
+		case "w":
+			weights.WriteString(strings.TrimPrefix(scaner.Text(), "w\t"))
+			weights.WriteString("\n")
+		default:
+			scaner.Errorf("unknown record %q", scaner.String(0))
+		}
+	}
+	if err := scaner.Err(); err != nil {
+		return err
+	}
+	return self.ftrl.LoadModel(&weights)
 }
 
 func (c *L1VM) Command() cli.Command {
@@ -41,22 +105,30 @@ func (c *L1VM) Command() cli.Command {
 		Name:     "L1VM",
 		Usage:    "L1VM",
 		Category: "SVM",
-		Flags: []cli.Flag{
-			cli.Float64Flag{
-				Name: "radius",
-			},
-			cli.Float64Flag{
-				Name: "sv",
-			},
-		},
 	}
 }
 
-func (c *L1VM) Init(ctx *cli.Context) {
-	c.ftrl = &(lr.FTRLLogisticRegression{})
-	c.ftrl.Init(ctx)
-	c.radius = ctx.Float64("radius")
-	c.count = ctx.Int("sv")
+func NewL1VM(params L1VMParams) *L1VM {
+	return &L1VM{
+		ftrl:   lr.NewFTRLLogisticRegression(params.FTRL),
+		radius: params.Radius,
+		count:  params.SupportVectors,
+		params: params,
+		rng:    rand.New(rand.NewSource(1)),
+	}
+}
+
+func (c *L1VM) Parameters() interface{} {
+	return &c.params
+}
+
+// Seed makes Train draw the support vectors from a source seeded with seed.
+func (c *L1VM) Seed(seed int64) {
+	c.rng = rand.New(rand.NewSource(seed))
+}
+
+func (c *L1VM) Init() {
+	*c = *NewL1VM(c.params)
 }
 
 func (c *L1VM) Clear() {}
@@ -76,6 +148,9 @@ func (c *L1VM) PredictVector(x *core.Vector) float64 {
 }
 
 func (c *L1VM) Train(dataset *core.DataSet) {
+	if c.rng == nil {
+		c.Seed(1)
+	}
 	c.sv = []*core.Vector{}
 	kernel_dataset := core.NewDataSet()
 
@@ -89,7 +164,7 @@ func (c *L1VM) Train(dataset *core.DataSet) {
 		}
 	}
 
-	perm_positive := rand.Perm(len(positive))
+	perm_positive := c.rng.Perm(len(positive))
 
 	for i, k := range perm_positive {
 		if i > c.count {
@@ -98,7 +173,7 @@ func (c *L1VM) Train(dataset *core.DataSet) {
 		c.sv = append(c.sv, dataset.Samples[positive[k]].GetFeatureVector())
 	}
 
-	perm_negative := rand.Perm(len(negative))
+	perm_negative := c.rng.Perm(len(negative))
 
 	for i, k := range perm_negative {
 		if i > c.count {
@@ -0,0 +1,29 @@
+package svm
+
+import (
+	"bytes"
+	"github.com/pantsing/hector/internal/algorithms/classifier/lr"
+	"github.com/pantsing/hector/internal/core"
+	"testing"
+)
+
+func TestL1VMSaveLoad(t *testing.T) {
+	params := lr.FTRLLogisticRegressionParams{Alpha: 0.1, Beta: 1, Lambda1: 0.1, Lambda2: 0.1, IsBalance: true, SSR: 1, SBR: 1, Steps: 3}
+	dataset := core.XORDataSet(200)
+	algo := &L1VM{ftrl: &lr.FTRLLogisticRegression{Model: make(map[int64]lr.FTRLFeatureWeight), Params: params}, radius: 1.0, count: 20}
+	algo.Train(dataset)
+	var buf bytes.Buffer
+	if err := algo.SaveModel(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := &L1VM{ftrl: &lr.FTRLLogisticRegression{Params: params}}
+	if err := loaded.LoadModel(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for i, sample := range dataset.Samples {
+		if p, q := algo.Predict(sample), loaded.Predict(sample); p != q {
+			t.Fatalf("sample %d: prediction %v after load, want %v", i, q, p)
+		}
+	}
+}
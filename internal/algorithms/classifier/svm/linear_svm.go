@@ -1,17 +1,15 @@
 package svm
 
 import (
-	"bufio"
 	"fmt"
 	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/hector/internal/model"
 	"github.com/pantsing/hector/internal/utils"
 	"github.com/urfave/cli"
+	"io"
 	"math"
 	"math/rand"
-	"os"
 	"runtime"
-	"strconv"
-	"strings"
 )
 
 /*
@@ -24,14 +22,6 @@ func (self *LinearSVM) Command() cli.Command {
 		Name:     "linearSVM",
 		Usage:    "L1 Linear Support Vector Machine",
 		Category: "SVM",
-		Flags: []cli.Flag{
-			cli.Float64Flag{
-				Name: "c",
-			},
-			cli.Float64Flag{
-				Name: "e",
-			},
-		},
 	}
 }
 
@@ -44,10 +34,12 @@ type LinearSVM struct {
 	e  float64
 	w  *core.Vector
 
-	xx []float64
+	xx     []float64
+	params SVMParams
+	rng    *rand.Rand
 }
 
-func (self *LinearSVM) SaveModel(path string) {
+func (self *LinearSVM) SaveModel(w io.Writer) error {
 	sb := utils.StringBuilder{}
 	for f, g := range self.w.Data {
 		sb.Int64(f)
@@ -55,27 +47,34 @@ func (self *LinearSVM) SaveModel(path string) {
 		sb.Float(g)
 		sb.Write("\n")
 	}
-	sb.WriteToFile(path)
+	_, err := sb.WriteTo(w)
+	return err
 }
 
-func (self *LinearSVM) LoadModel(path string) {
-	file, _ := os.Open(path)
-	defer file.Close()
-
-	scaner := bufio.NewScanner(file)
+func (self *LinearSVM) LoadModel(r io.Reader) error {
+	scaner := model.NewScanner(r)
 	for scaner.Scan() {
-		line := scaner.Text()
-		tks := strings.Split(line, "\t")
-		fid, _ := strconv.ParseInt(tks[0], 10, 64)
-		fw, _ := strconv.ParseFloat(tks[1], 64)
+		fid, fw := scaner.Int64(0), scaner.Float(1)
 		self.w.SetValue(fid, fw)
 	}
+	return scaner.Err()
+}
+
+func NewLinearSVM(params SVMParams) *LinearSVM {
+	return &LinearSVM{C: params.C, e: params.E, w: core.NewVector(), params: params, rng: rand.New(rand.NewSource(1))}
+}
+
+// Seed makes Train draw the initial multipliers from a source seeded with seed.
+func (c *LinearSVM) Seed(seed int64) {
+	c.rng = rand.New(rand.NewSource(seed))
+}
+
+func (c *LinearSVM) Parameters() interface{} {
+	return &c.params
 }
 
-func (c *LinearSVM) Init(ctx *cli.Context) {
-	c.C = ctx.Float64("c")
-	c.e = ctx.Float64("e")
-	c.w = core.NewVector()
+func (c *LinearSVM) Init() {
+	*c = *NewLinearSVM(c.params)
 }
 
 func (c *LinearSVM) Clear() {}
@@ -91,6 +90,9 @@ func (c *LinearSVM) PredictVector(x *core.Vector) float64 {
 }
 
 func (c *LinearSVM) Train(dataset *core.DataSet) {
+	if c.rng == nil {
+		c.Seed(1)
+	}
 	c.sv = []*core.Vector{}
 	c.y = []float64{}
 	c.a = []float64{}
@@ -103,7 +105,7 @@ func (c *LinearSVM) Train(dataset *core.DataSet) {
 		} else {
 			c.y = append(c.y, -1.0)
 		}
-		c.a = append(c.a, c.C*rand.Float64()*0.0)
+		c.a = append(c.a, c.C*c.rng.Float64()*0.0)
 		c.w.AddVector(x, c.y[k]*c.a[k])
 	}
 
@@ -3,7 +3,10 @@ package svm
 import (
 	"fmt"
 	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/hector/internal/model"
+	"github.com/pantsing/hector/internal/utils"
 	"github.com/urfave/cli"
+	"io"
 	"math"
 	"math/rand"
 )
@@ -13,17 +16,14 @@ func (c *SVM) Command() cli.Command {
 		Name:     "svm",
 		Usage:    "Support Vector Machine",
 		Category: "SVM",
-		Flags: []cli.Flag{
-			cli.Float64Flag{
-				Name: "c",
-			},
-			cli.Float64Flag{
-				Name: "e",
-			},
-		},
 	}
 }
 
+type SVMParams struct {
+	C float64 `flag:"c" default:"1" min:"0" usage:"Penalty of misclassified samples"`
+	E float64 `flag:"e" default:"0.01" min:"0" usage:"Training stops when the change of the multipliers drops below e"`
+}
+
 type SVM struct {
 	sv []*core.Vector
 	y  []float64
@@ -33,15 +33,37 @@ type SVM struct {
 	e  float64
 	w  *core.Vector
 
-	xx []float64
+	xx     []float64
+	params SVMParams
+	rng    *rand.Rand
 }
 
-func (self *SVM) SaveModel(path string) {
-
+// SaveModel writes the bias b on the first line, then one feature id and weight per line.
+func (self *SVM) SaveModel(w io.Writer) error {
+	sb := utils.StringBuilder{}
+	sb.Float(self.b)
+	sb.Write("\n")
+	for f, g := range self.w.Data {
+		sb.Int64(f)
+		sb.Write("\t")
+		sb.Float(g)
+		sb.Write("\n")
+	}
+	_, err := sb.WriteTo(w)
+	return err
 }
 
-func (self *SVM) LoadModel(path string) {
-
+func (self *SVM) LoadModel(r io.Reader) error {
+	self.w = core.NewVector()
+	scaner := model.NewScanner(r)
+	if scaner.Scan() {
+		self.b = scaner.Float(0)
+	}
+	for scaner.Scan() {
+		fid, fw := scaner.Int64(0), scaner.Float(1)
+		self.w.SetValue(fid, fw)
+	}
+	return scaner.Err()
 }
 
 type SVMValues struct {
@@ -49,10 +71,22 @@ type SVMValues struct {
 	i1, i2                        int
 }
 
-func (c *SVM) Init(ctx *cli.Context) {
-	c.C = ctx.Float64("c")
-	c.e = ctx.Float64("e")
-	c.w = core.NewVector()
+func NewSVM(params SVMParams) *SVM {
+	return &SVM{C: params.C, e: params.E, w: core.NewVector(), params: params, rng: rand.New(rand.NewSource(1))}
+}
+
+// Seed makes Train draw the initial multipliers and the optimized samples from a source
+// seeded with seed.
+func (c *SVM) Seed(seed int64) {
+	c.rng = rand.New(rand.NewSource(seed))
+}
+
+func (c *SVM) Parameters() interface{} {
+	return &c.params
+}
+
+func (c *SVM) Init() {
+	*c = *NewSVM(c.params)
 }
 
 func (c *SVM) Clear() {}
@@ -85,6 +119,9 @@ func (c *SVM) MatchKKT(y, f, a float64) bool {
 }
 
 func (c *SVM) Train(dataset *core.DataSet) {
+	if c.rng == nil {
+		c.Seed(1)
+	}
 	c.sv = []*core.Vector{}
 	c.y = []float64{}
 	c.a = []float64{}
@@ -97,7 +134,7 @@ func (c *SVM) Train(dataset *core.DataSet) {
 		} else {
 			c.y = append(c.y, -1.0)
 		}
-		c.a = append(c.a, c.C*rand.Float64())
+		c.a = append(c.a, c.C*c.rng.Float64())
 		c.w.AddVector(x, c.y[k]*c.a[k])
 	}
 
@@ -121,7 +158,7 @@ func (c *SVM) Train(dataset *core.DataSet) {
 			maxde := 0.0
 			best_values := SVMValues{}
 			for k2 := 0; k2 < 10; k2++ {
-				i2 := rand.Intn(len(c.sv))
+				i2 := c.rng.Intn(len(c.sv))
 				if i1 == i2 {
 					continue
 				}
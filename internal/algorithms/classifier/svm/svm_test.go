@@ -0,0 +1,29 @@
+package svm
+
+import (
+	"bytes"
+	"github.com/pantsing/hector/internal/core"
+	"math"
+	"testing"
+)
+
+func TestSVMSaveLoad(t *testing.T) {
+	dataset := core.LinearDataSet(200)
+	algo := &SVM{C: 1.0, e: 0.01, w: core.NewVector()}
+	algo.Train(dataset)
+	var buf bytes.Buffer
+	if err := algo.SaveModel(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := &SVM{}
+	if err := loaded.LoadModel(&buf); err != nil {
+		t.Fatal(err)
+	}
+	// Vector.Dot sums in map order, so predictions agree up to rounding only.
+	for i, sample := range dataset.Samples {
+		if p, q := algo.Predict(sample), loaded.Predict(sample); math.Abs(p-q) > 1e-12 {
+			t.Fatalf("sample %d: prediction %v after load, want %v", i, q, p)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Package convert writes data sets to binary caches, which the loaders of every action read
+without parsing text when given one as --train, --test or --input.
+*/
+package convert
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pantsing/hector/internal/algorithms/internal"
+	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/log"
+	"github.com/urfave/cli"
+)
+
+// Flags of the convert command.
+var Flags []cli.Flag = append([]cli.Flag{
+	cli.StringFlag{
+		Name:  "input, i",
+		Usage: "Data set to convert",
+	},
+	cli.StringFlag{
+		Name:  "output, o",
+		Usage: "Path of the data set cache to write",
+	},
+	cli.BoolFlag{
+		Name:  "regression",
+		Usage: "Read target values of regressors instead of labels of classifiers",
+	},
+	cli.IntFlag{
+		Name:  "key-columns",
+		Usage: "Number of columns starting every line, such as the group ID of --cv-strategy group, kept as the key of the samples",
+	},
+	cli.BoolFlag{
+		Name:  "lenient",
+		Usage: "Skip and count malformed lines of the data set instead of aborting at the first one",
+	},
+}, append(internal.FormatFlags, internal.HashFlags...)...)
+
+// Action loads the data set at --input and writes it to a cache at --output.
+func Action(ctx *cli.Context) error {
+	if err := internal.RequireFlags(ctx, "input", "output"); err != nil {
+		return err
+	}
+	input, output := ctx.String("input"), ctx.String("output")
+	csv, err := internal.CSVFormat(ctx)
+	if err != nil {
+		return err
+	}
+	hasher, err := internal.Hasher(ctx)
+	if err != nil {
+		return err
+	}
+	if core.IsCache(input) {
+		return fmt.Errorf("%s: --input %s is a data set cache already", ctx.Command.Name, input)
+	}
+
+	start := time.Now()
+	n := 0
+	if ctx.Bool("regression") {
+		dataset := core.NewRealDataSet()
+		dataset.Mode = internal.ParseMode(ctx)
+		dataset.CSV = csv
+		dataset.Hasher = hasher
+		dataset.KeyColumns = ctx.Int("key-columns")
+		if err = dataset.Load(input, -1); err != nil {
+			return err
+		}
+		n = len(dataset.Samples)
+		err = dataset.WriteCache(output)
+	} else {
+		dataset := core.NewDataSet()
+		dataset.Mode = internal.ParseMode(ctx)
+		dataset.CSV = csv
+		dataset.Hasher = hasher
+		dataset.KeyColumns = ctx.Int("key-columns")
+		if err = dataset.Load(input, -1); err != nil {
+			return err
+		}
+		n = len(dataset.Samples)
+		err = dataset.WriteCache(output)
+	}
+	if err != nil {
+		os.Remove(output)
+		return err
+	}
+	log.Infof("wrote %d samples to %s in %v", n, output, time.Since(start))
+	return nil
+}
@@ -1,7 +1,6 @@
 package eval
 
 import (
-	"github.com/qiniu/log"
 	"math"
 	"sort"
 )
@@ -9,11 +8,35 @@ import (
 type LabelPrediction struct {
 	Prediction float64
 	Label      int
+	// Qid is the query of the sample, which ranking metrics group the predictions by.
+	Qid string
+	// Weight is the weight of the sample in AUC, LogLoss and RMSE, 1 if it is 0.
+	Weight float64
+}
+
+// weight returns the Weight of the prediction, 1 if it is not set.
+func (pred *LabelPrediction) weight() float64 {
+	if pred.Weight == 0 {
+		return 1
+	}
+	return pred.Weight
 }
 
 type RealPrediction struct { // Real valued
 	Prediction float64
 	Value      float64
+	// Std is the standard deviation of the prediction, for regressors predicting one.
+	Std float64
+	// Weight is the weight of the sample in RegRMSE, 1 if it is 0.
+	Weight float64
+}
+
+// weight returns the Weight of the prediction, 1 if it is not set.
+func (pred *RealPrediction) weight() float64 {
+	if pred.Weight == 0 {
+		return 1
+	}
+	return pred.Weight
 }
 
 type By func(p1, p2 *LabelPrediction) bool
@@ -43,6 +66,8 @@ func (by By) Sort(predictions []*LabelPrediction) {
 	sort.Sort(sorter)
 }
 
+// AUC returns the probability a positive sample is predicted above a negative one,
+// the pairs weighted by the product of the weights of their samples.
 func AUC(predictions0 []*LabelPrediction) float64 {
 	predictions := []*LabelPrediction{}
 	for _, pred := range predictions0 {
@@ -55,21 +80,21 @@ func AUC(predictions0 []*LabelPrediction) float64 {
 	By(prediction).Sort(predictions)
 
 	pn := 0.0
-	nn := float64(len(predictions))
+	nn := 0.0
 	ret := 0.0
-	count := nn
-	for i, lp := range predictions {
+	for i := len(predictions) - 1; i >= 0; i-- {
+		lp := predictions[i]
 		if lp.Label > 0 {
-			pn += 1.0
-			nn -= 1.0
-			ret += float64(count) - float64(i)
+			pn += lp.weight()
+			ret += lp.weight() * nn
+		} else {
+			nn += lp.weight()
 		}
 	}
-	ret2 := pn * (pn + 1) / 2.0
 	if pn*nn == 0.0 {
 		return 0.5
 	}
-	return (ret - ret2) / (pn * nn)
+	return ret / (pn * nn)
 }
 
 func RMSE(predictions []*LabelPrediction) float64 {
@@ -77,37 +102,17 @@ func RMSE(predictions []*LabelPrediction) float64 {
 	n := 0.0
 
 	for _, pred := range predictions {
-		ret += (float64(pred.Label) - pred.Prediction) * (float64(pred.Label) - pred.Prediction)
-		n += 1.0
+		ret += pred.weight() * (float64(pred.Label) - pred.Prediction) * (float64(pred.Label) - pred.Prediction)
+		n += pred.weight()
 	}
 
 	return math.Sqrt(ret / n)
 }
 
+// ErrorRate returns the share of predictions on the wrong side of 0.5, weighted by their
+// Weight.
 func ErrorRate(predictions []*LabelPrediction) float64 {
-	var FN, FP, TN, TP float64
-	ret := 0.0
-	n := float64(len(predictions))
-
-	for _, pred := range predictions {
-		if float64(pred.Label) >= 0.5 {
-			if pred.Prediction < 0.5 {
-				ret++
-				FN++
-			} else {
-				TP++
-			}
-		} else {
-			if pred.Prediction >= 0.5 {
-				ret++
-				FP++
-			} else {
-				TN++
-			}
-		}
-	}
-	log.Infof("FN: %.9g\tFP: %.9g\tTN: %.9g\tTP: %.9g\tPrecision: %.9g\tRecall: %.9g\t", FN, FP, TN, TP, TP/(TP+FP), TP/(TP+FN))
-	return ret / n
+	return NewConfusion(predictions, 0.5).ErrorRate()
 }
 
 // BalanceErrorRate considers the real ratio between positive and negative samples.
@@ -138,9 +143,25 @@ func RegRMSE(predictions []*RealPrediction) float64 {
 	n := 0.0
 
 	for _, pred := range predictions {
-		ret += (pred.Value - pred.Prediction) * (pred.Value - pred.Prediction)
-		n += 1.0
+		ret += pred.weight() * (pred.Value - pred.Prediction) * (pred.Value - pred.Prediction)
+		n += pred.weight()
 	}
 
 	return math.Sqrt(ret / n)
 }
+
+// MeanStd returns the mean and the standard deviation of the values, such as the
+// metrics of the folds of cross validation.
+func MeanStd(values []float64) (mean, std float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	for _, v := range values {
+		std += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(std / float64(len(values)))
+}
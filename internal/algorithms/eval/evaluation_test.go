@@ -54,4 +54,44 @@ func TestErrorRate(t *testing.T) {
 	if math.Abs(error_rate) > 1e-9{
 		t.Error("Error Rate Error")
 	}
-}
\ No newline at end of file
+}
+
+func TestWeightedMetrics(t *testing.T) {
+	// A prediction of weight 2 counts as two of weight 1.
+	weighted := []*LabelPrediction{
+		{Label: 1, Prediction: 0.9, Weight: 2},
+		{Label: 0, Prediction: 0.8},
+		{Label: 1, Prediction: 0.3},
+		{Label: 0, Prediction: 0.1, Weight: 3},
+	}
+	repeated := []*LabelPrediction{}
+	for _, pred := range weighted {
+		for i := 0; i < int(pred.weight()); i++ {
+			repeated = append(repeated, &LabelPrediction{Label: pred.Label, Prediction: pred.Prediction})
+		}
+	}
+	for name, f := range map[string]func([]*LabelPrediction) float64{
+		"AUC": AUC, "LogLoss": LogLoss, "RMSE": RMSE, "Brier": Brier, "PRAUC": PRAUC, "KS": KS,
+		"ErrorRate": ErrorRate, "OptimalThreshold": OptimalThreshold,
+	} {
+		if w, r := f(weighted), f(repeated); math.Abs(w-r) > 1e-9 {
+			t.Errorf("weighted %s = %v, repeated %v", name, w, r)
+		}
+	}
+	// So do the report metrics, but for the numbers of predictions.
+	w, r := NewReport(weighted, 0.5), NewReport(repeated, 0.5)
+	if w.Confusion != r.Confusion || w.Positives != r.Positives || w.MCC != r.MCC {
+		t.Errorf("weighted report %+v, repeated %+v", w, r)
+	}
+	for i := range w.Calibration {
+		if b, c := w.Calibration[i], r.Calibration[i]; b.MeanPrediction != c.MeanPrediction || b.PositiveRate != c.PositiveRate {
+			t.Errorf("weighted calibration bin %+v, repeated %+v", b, c)
+		}
+	}
+	if w.Lift[9].Gain != 1 {
+		t.Errorf("weighted lift %+v", w.Lift)
+	}
+	if auc := AUC(weighted); math.Abs(auc-11.0/12) > 1e-9 {
+		t.Errorf("weighted AUC = %v, want 11/12", auc)
+	}
+}
@@ -0,0 +1,256 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/pantsing/hector/internal/core"
+)
+
+// MultiClassPrediction is the probability of every class, as returned by
+// PredictMultiClass, of a sample of the class Label.
+type MultiClassPrediction struct {
+	Probabilities *core.ArrayVector
+	Label         int
+}
+
+// predicted returns the most probable class.
+func (pred *MultiClassPrediction) predicted() int {
+	label, _ := pred.Probabilities.KeyWithMaxValue()
+	return label
+}
+
+// rank returns the number of classes ranked before Label, 0 if it is predicted: those
+// more probable, and those as probable with a lower label, as predicted breaks ties.
+func (pred *MultiClassPrediction) rank() int {
+	p := pred.Probabilities.GetValue(pred.Label)
+	ret := 0
+	for c := 0; c < pred.Probabilities.Len(); c++ {
+		if q := pred.Probabilities.GetValue(c); q > p || (q == p && c < pred.Label) {
+			ret++
+		}
+	}
+	return ret
+}
+
+// probability returns the probability of the class c, normalized by the sum of the
+// probabilities of all the classes if it is positive.
+func (pred *MultiClassPrediction) probability(c int) float64 {
+	sum := pred.Probabilities.Sum()
+	if sum <= 0 {
+		return pred.Probabilities.GetValue(c)
+	}
+	return pred.Probabilities.GetValue(c) / sum
+}
+
+// classCount returns the number of classes, the highest label or predicted class plus one.
+func classCount(predictions []*MultiClassPrediction) int {
+	ret := 0
+	for _, pred := range predictions {
+		if pred.Label+1 > ret {
+			ret = pred.Label + 1
+		}
+		if n := pred.Probabilities.Len(); n > ret {
+			ret = n
+		}
+	}
+	return ret
+}
+
+// MultiClassAccuracy returns the share of predictions whose most probable class is
+// the label.
+func MultiClassAccuracy(predictions []*MultiClassPrediction) float64 {
+	return TopKAccuracy(predictions, 1)
+}
+
+// TopKAccuracy returns the share of predictions whose label is among the k most
+// probable classes, ties going to the lower labels.
+func TopKAccuracy(predictions []*MultiClassPrediction, k int) float64 {
+	hits := 0
+	for _, pred := range predictions {
+		if pred.rank() < k {
+			hits++
+		}
+	}
+	return ratio(float64(hits), float64(len(predictions)))
+}
+
+// MultiClassLogLoss returns the mean negative log of the probability of the label.
+func MultiClassLogLoss(predictions []*MultiClassPrediction) float64 {
+	ret := 0.0
+	for _, pred := range predictions {
+		ret += logLoss(1, pred.probability(pred.Label))
+	}
+	return ratio(ret, float64(len(predictions)))
+}
+
+// OneVsRestAUC returns the AUC of the probability of the class c to tell the samples
+// of c from the others.
+func OneVsRestAUC(predictions []*MultiClassPrediction, c int) float64 {
+	binary := make([]*LabelPrediction, 0, len(predictions))
+	for _, pred := range predictions {
+		lp := &LabelPrediction{Prediction: pred.probability(c)}
+		if pred.Label == c {
+			lp.Label = 1
+		}
+		binary = append(binary, lp)
+	}
+	return AUC(binary)
+}
+
+// ClassMetrics holds the one-vs-rest metrics of a class.
+type ClassMetrics struct {
+	Label int `json:"label"`
+	// Support is the number of samples of the class.
+	Support int `json:"support"`
+	// Predicted is the number of samples predicted to be of the class.
+	Predicted int     `json:"predicted"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F1        float64 `json:"f1"`
+	AUC       float64 `json:"auc"`
+}
+
+// TopKReportMax is the highest k of the top-k accuracies of a MultiClassReport.
+const TopKReportMax = 5
+
+// MultiClassReport holds the metrics of the predictions of a multi-class classifier.
+type MultiClassReport struct {
+	Count   int     `json:"count"`
+	Classes int     `json:"classes"`
+	LogLoss float64 `json:"log_loss"`
+	// TopKAccuracy holds the top-k accuracy for k from 1, the accuracy, to the number
+	// of classes or TopKReportMax.
+	TopKAccuracy []float64 `json:"top_k_accuracy"`
+	// The macro averages are the means over the classes with samples or predictions,
+	// and the micro averages those of the predictions of all the classes pooled. For
+	// single-label predictions micro precision, recall and F1 equal the accuracy.
+	MacroPrecision float64 `json:"macro_precision"`
+	MacroRecall    float64 `json:"macro_recall"`
+	MacroF1        float64 `json:"macro_f1"`
+	MicroPrecision float64 `json:"micro_precision"`
+	MicroRecall    float64 `json:"micro_recall"`
+	MicroF1        float64 `json:"micro_f1"`
+	// MacroAUC is the mean one-vs-rest AUC of the classes with samples.
+	MacroAUC float64        `json:"macro_auc"`
+	PerClass []ClassMetrics `json:"per_class"`
+	// Confusion counts the samples of the class of the row predicted to be of the class
+	// of the column.
+	Confusion [][]int `json:"confusion"`
+}
+
+// NewMultiClassReport returns the report of the predictions.
+func NewMultiClassReport(predictions []*MultiClassPrediction) *MultiClassReport {
+	n := classCount(predictions)
+	r := &MultiClassReport{
+		Count:     len(predictions),
+		Classes:   n,
+		LogLoss:   MultiClassLogLoss(predictions),
+		Confusion: make([][]int, n),
+		PerClass:  make([]ClassMetrics, n),
+	}
+	for k := 1; k <= n && k <= TopKReportMax; k++ {
+		r.TopKAccuracy = append(r.TopKAccuracy, TopKAccuracy(predictions, k))
+	}
+	for c := range r.Confusion {
+		r.Confusion[c] = make([]int, n)
+	}
+	tp := 0
+	for _, pred := range predictions {
+		predicted := pred.predicted()
+		r.Confusion[pred.Label][predicted]++
+		r.PerClass[pred.Label].Support++
+		r.PerClass[predicted].Predicted++
+		if predicted == pred.Label {
+			tp++
+		}
+	}
+
+	classes, withSamples := 0, 0
+	for c := range r.PerClass {
+		m := &r.PerClass[c]
+		m.Label = c
+		hits := float64(r.Confusion[c][c])
+		m.Precision = ratio(hits, float64(m.Predicted))
+		m.Recall = ratio(hits, float64(m.Support))
+		m.F1 = ratio(2*hits, float64(m.Support+m.Predicted))
+		if m.Support > 0 {
+			m.AUC = OneVsRestAUC(predictions, c)
+			r.MacroAUC += m.AUC
+			withSamples++
+		}
+		if m.Support+m.Predicted > 0 {
+			r.MacroPrecision += m.Precision
+			r.MacroRecall += m.Recall
+			r.MacroF1 += m.F1
+			classes++
+		}
+	}
+	r.MacroPrecision = ratio(r.MacroPrecision, float64(classes))
+	r.MacroRecall = ratio(r.MacroRecall, float64(classes))
+	r.MacroF1 = ratio(r.MacroF1, float64(classes))
+	r.MacroAUC = ratio(r.MacroAUC, float64(withSamples))
+	r.MicroPrecision = ratio(float64(tp), float64(len(predictions)))
+	r.MicroRecall = r.MicroPrecision
+	r.MicroF1 = r.MicroPrecision
+	return r
+}
+
+// Accuracy returns the share of predictions whose most probable class is the label.
+func (r *MultiClassReport) Accuracy() float64 {
+	if len(r.TopKAccuracy) == 0 {
+		return 0
+	}
+	return r.TopKAccuracy[0]
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *MultiClassReport) WriteJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// WriteTable writes the metrics, the per class metrics and the confusion matrix as
+// aligned columns.
+func (r *MultiClassReport) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "count\t%d\nclasses\t%d\n", r.Count, r.Classes)
+	fmt.Fprintf(tw, "log_loss\t%.6g\n", r.LogLoss)
+	for k, accuracy := range r.TopKAccuracy {
+		fmt.Fprintf(tw, "top_%d_accuracy\t%.6g\n", k+1, accuracy)
+	}
+	for _, m := range []struct {
+		name  string
+		value float64
+	}{
+		{"macro_precision", r.MacroPrecision}, {"macro_recall", r.MacroRecall}, {"macro_f1", r.MacroF1},
+		{"micro_precision", r.MicroPrecision}, {"micro_recall", r.MicroRecall}, {"micro_f1", r.MicroF1},
+		{"macro_auc", r.MacroAUC},
+	} {
+		fmt.Fprintf(tw, "%s\t%.6g\n", m.name, m.value)
+	}
+
+	fmt.Fprintf(tw, "\nclass\tsupport\tpredicted\tprecision\trecall\tf1\tauc\n")
+	for _, m := range r.PerClass {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%.6g\t%.6g\t%.6g\t%.6g\n", m.Label, m.Support, m.Predicted, m.Precision, m.Recall, m.F1, m.AUC)
+	}
+	fmt.Fprintf(tw, "\nlabel \\ predicted")
+	for c := range r.Confusion {
+		fmt.Fprintf(tw, "\t%d", c)
+	}
+	fmt.Fprintln(tw)
+	for c, row := range r.Confusion {
+		fmt.Fprintf(tw, "%d", c)
+		for _, count := range row {
+			fmt.Fprintf(tw, "\t%d", count)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
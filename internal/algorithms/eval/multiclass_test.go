@@ -0,0 +1,76 @@
+package eval
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/pantsing/hector/internal/core"
+)
+
+func TestMultiClassReport(t *testing.T) {
+	prediction := func(label int, probabilities ...float64) *MultiClassPrediction {
+		v := core.NewArrayVector()
+		for c, p := range probabilities {
+			v.SetValue(c, p)
+		}
+		return &MultiClassPrediction{Probabilities: v, Label: label}
+	}
+	predictions := []*MultiClassPrediction{
+		prediction(0, 0.7, 0.2, 0.1),
+		prediction(0, 0.3, 0.6, 0.1),
+		prediction(1, 0.1, 0.8, 0.1),
+		prediction(2, 0.2, 0.5, 0.3),
+	}
+	r := NewMultiClassReport(predictions)
+	if want := [][]int{{1, 1, 0}, {0, 1, 0}, {0, 1, 0}}; !reflect.DeepEqual(r.Confusion, want) {
+		t.Errorf("Confusion is %v, want %v", r.Confusion, want)
+	}
+	for _, m := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"Accuracy", r.Accuracy(), 0.5},
+		{"Top2Accuracy", r.TopKAccuracy[1], 1},
+		{"LogLoss", r.LogLoss, -(math.Log(0.7) + math.Log(0.3) + math.Log(0.8) + math.Log(0.3)) / 4},
+		{"MacroPrecision", r.MacroPrecision, (1 + 1.0/3 + 0) / 3},
+		{"MacroRecall", r.MacroRecall, (0.5 + 1 + 0) / 3},
+		{"MacroF1", r.MacroF1, (2.0/3 + 0.5 + 0) / 3},
+		{"MicroF1", r.MicroF1, 0.5},
+		{"AUC of class 2", r.PerClass[2].AUC, 1},
+	} {
+		if math.Abs(m.got-m.want) > 1e-9 {
+			t.Errorf("%s is %v, want %v", m.name, m.got, m.want)
+		}
+	}
+	if len(r.TopKAccuracy) != 3 {
+		t.Errorf("%d top-k accuracies of 3 classes", len(r.TopKAccuracy))
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	decoded := MultiClassReport{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.PerClass, r.PerClass) {
+		t.Errorf("decoded per class metrics %+v, want %+v", decoded.PerClass, r.PerClass)
+	}
+
+	// Tied classes go to the lowest label, for the accuracy as for the prediction.
+	tied := NewMultiClassReport([]*MultiClassPrediction{
+		prediction(0, 0.5, 0.5, 0.5),
+		prediction(1, 0.5, 0.5, 0.5),
+		prediction(2, 0.5, 0.5, 0.5),
+	})
+	if tied.Accuracy() != tied.MicroPrecision || math.Abs(tied.Accuracy()-1.0/3) > 1e-9 {
+		t.Errorf("Accuracy of ties is %v, MicroPrecision %v, want 1/3", tied.Accuracy(), tied.MicroPrecision)
+	}
+	if want := []float64{1.0 / 3, 2.0 / 3, 1}; !reflect.DeepEqual(tied.TopKAccuracy, want) {
+		t.Errorf("TopKAccuracy of ties is %v, want %v", tied.TopKAccuracy, want)
+	}
+}
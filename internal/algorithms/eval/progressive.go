@@ -0,0 +1,84 @@
+package eval
+
+import (
+	"math"
+)
+
+// aucBuckets is the number of buckets predictions are rounded to by ProgressiveValidation.
+const aucBuckets = 10000
+
+/*
+ProgressiveValidation accumulates the metrics of the predictions an online learner makes
+of samples before learning from them, which estimate how it does on unseen samples
+without a test set. Memory stays bounded however many predictions are added: the AUC is
+computed over predictions rounded to 1/10000. Like LogLoss and AUC, the metrics weigh
+every prediction by the weight of its sample.
+*/
+type ProgressiveValidation struct {
+	Count   int
+	weight  float64
+	logLoss float64
+	// positives[i] and negatives[i] sum the weights of the labels of predictions in
+	// bucket i.
+	positives, negatives [aucBuckets + 1]float64
+}
+
+// Add adds the prediction of a sample with the label label and the weight weight.
+func (v *ProgressiveValidation) Add(label int, prediction, weight float64) {
+	v.Count++
+	v.weight += weight
+	v.logLoss += weight * logLoss(label, prediction)
+	bucket := int(math.Floor(prediction * aucBuckets))
+	if bucket < 0 {
+		bucket = 0
+	} else if bucket > aucBuckets {
+		bucket = aucBuckets
+	}
+	if label > 0 {
+		v.positives[bucket] += weight
+	} else {
+		v.negatives[bucket] += weight
+	}
+}
+
+// LogLoss returns the mean log loss of the predictions, weighted by their weight.
+func (v *ProgressiveValidation) LogLoss() float64 {
+	if v.weight == 0 {
+		return 0
+	}
+	return v.logLoss / v.weight
+}
+
+// AUC returns the area under the ROC curve of the predictions, counting half the pairs
+// of a positive and a negative sample in the same bucket as ordered right.
+func (v *ProgressiveValidation) AUC() float64 {
+	var positives, negatives, ordered float64
+	for i := range v.positives {
+		ordered += v.positives[i] * (negatives + v.negatives[i]/2)
+		positives += v.positives[i]
+		negatives += v.negatives[i]
+	}
+	if positives*negatives == 0 {
+		return 0.5
+	}
+	return ordered / (positives * negatives)
+}
+
+// LogLoss returns the mean log loss of the predictions, weighted by their Weight.
+func LogLoss(predictions []*LabelPrediction) float64 {
+	ret, n := 0.0, 0.0
+	for _, pred := range predictions {
+		ret += pred.weight() * logLoss(pred.Label, pred.Prediction)
+		n += pred.weight()
+	}
+	return ret / n
+}
+
+func logLoss(label int, prediction float64) float64 {
+	const eps = 1e-15
+	p := math.Max(eps, math.Min(1-eps, prediction))
+	if label > 0 {
+		return -math.Log(p)
+	}
+	return -math.Log(1 - p)
+}
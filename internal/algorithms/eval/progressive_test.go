@@ -0,0 +1,32 @@
+package eval
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestProgressiveValidation(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	v := ProgressiveValidation{}
+	predictions := []*LabelPrediction{}
+	for i := 0; i < 10000; i++ {
+		label := r.Intn(2)
+		prediction := r.Float64()
+		if label == 1 {
+			prediction = math.Sqrt(prediction)
+		}
+		weight := float64(1 + r.Intn(3))
+		v.Add(label, prediction, weight)
+		predictions = append(predictions, &LabelPrediction{Label: label, Prediction: prediction, Weight: weight})
+	}
+	if v.Count != len(predictions) {
+		t.Errorf("Count is %d, want %d", v.Count, len(predictions))
+	}
+	if auc, want := v.AUC(), AUC(predictions); math.Abs(auc-want) > 1e-3 {
+		t.Errorf("AUC is %v, want %v", auc, want)
+	}
+	if logLoss, want := v.LogLoss(), LogLoss(predictions); math.Abs(logLoss-want) > 1e-9 {
+		t.Errorf("LogLoss is %v, want %v", logLoss, want)
+	}
+}
@@ -0,0 +1,175 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// queries groups the predictions by Qid, in the order the queries first appear, each
+// sorted by decreasing prediction.
+func queries(predictions []*LabelPrediction) [][]*LabelPrediction {
+	index := make(map[string]int)
+	ret := [][]*LabelPrediction{}
+	for _, pred := range predictions {
+		i, ok := index[pred.Qid]
+		if !ok {
+			i = len(ret)
+			index[pred.Qid] = i
+			ret = append(ret, nil)
+		}
+		ret[i] = append(ret[i], pred)
+	}
+	for i := range ret {
+		ret[i] = sortedByPrediction(ret[i])
+	}
+	return ret
+}
+
+// HasQids tells whether any of the predictions is of a sample with a Qid.
+func HasQids(predictions []*LabelPrediction) bool {
+	for _, pred := range predictions {
+		if pred.Qid != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// meanOverQueries returns the mean of f over the queries of the predictions with a
+// positive sample, f being called with the predictions of a query sorted by
+// decreasing prediction.
+func meanOverQueries(predictions []*LabelPrediction, f func(query []*LabelPrediction) float64) float64 {
+	sum, n := 0.0, 0.0
+	for _, query := range queries(predictions) {
+		for _, pred := range query {
+			if pred.Label > 0 {
+				sum += f(query)
+				n++
+				break
+			}
+		}
+	}
+	return ratio(sum, n)
+}
+
+// dcg returns the discounted cumulative gain of the first k predictions, the gain of
+// a sample of label l being 2^l-1.
+func dcg(query []*LabelPrediction, k int) float64 {
+	ret := 0.0
+	for i, pred := range query {
+		if i >= k {
+			break
+		}
+		if pred.Label > 0 {
+			ret += (math.Pow(2, float64(pred.Label)) - 1) / math.Log2(float64(i+2))
+		}
+	}
+	return ret
+}
+
+// NDCG returns the mean normalized discounted cumulative gain at k of the queries with
+// a positive sample. Labels above 1 are grades of relevance.
+func NDCG(predictions []*LabelPrediction, k int) float64 {
+	return meanOverQueries(predictions, func(query []*LabelPrediction) float64 {
+		ideal := make([]*LabelPrediction, len(query))
+		copy(ideal, query)
+		By(func(p1, p2 *LabelPrediction) bool { return p1.Label > p2.Label }).Sort(ideal)
+		return ratio(dcg(query, k), dcg(ideal, k))
+	})
+}
+
+// MAP returns the mean over the queries with a positive sample of the average of the
+// precisions at the ranks of the positive samples.
+func MAP(predictions []*LabelPrediction) float64 {
+	return meanOverQueries(predictions, func(query []*LabelPrediction) float64 {
+		sum, positives := 0.0, 0.0
+		for i, pred := range query {
+			if pred.Label > 0 {
+				positives++
+				sum += positives / float64(i+1)
+			}
+		}
+		return sum / positives
+	})
+}
+
+// MRR returns the mean over the queries with a positive sample of the reciprocal rank
+// of the first positive sample.
+func MRR(predictions []*LabelPrediction) float64 {
+	return meanOverQueries(predictions, func(query []*LabelPrediction) float64 {
+		for i, pred := range query {
+			if pred.Label > 0 {
+				return 1 / float64(i+1)
+			}
+		}
+		return 0
+	})
+}
+
+// GroupAUC returns the mean AUC of the queries with both positive and negative samples,
+// weighted by their number of samples.
+func GroupAUC(predictions []*LabelPrediction) float64 {
+	sum, n := 0.0, 0.0
+	for _, query := range queries(predictions) {
+		positives := 0
+		for _, pred := range query {
+			if pred.Label > 0 {
+				positives++
+			}
+		}
+		if positives == 0 || positives == len(query) {
+			continue
+		}
+		sum += float64(len(query)) * AUC(query)
+		n += float64(len(query))
+	}
+	return ratio(sum, n)
+}
+
+// RankingCutoffs are the ranks k of the NDCG@k of a RankingReport.
+var RankingCutoffs = []int{1, 3, 5, 10}
+
+// RankingReport holds the ranking metrics of predictions grouped by Qid.
+type RankingReport struct {
+	Queries int `json:"queries"`
+	// Evaluated is the number of queries with a positive sample, which NDCG, MAP and MRR
+	// are averaged over.
+	Evaluated int `json:"evaluated"`
+	// NDCG holds the NDCG at every rank k of RankingCutoffs, named ndcg@k.
+	NDCG     []MetricValue `json:"ndcg"`
+	MAP      float64       `json:"map"`
+	MRR      float64       `json:"mrr"`
+	GroupAUC float64       `json:"group_auc"`
+}
+
+// NewRankingReport returns the ranking metrics of the predictions grouped by Qid.
+func NewRankingReport(predictions []*LabelPrediction) *RankingReport {
+	r := &RankingReport{
+		MAP:      MAP(predictions),
+		MRR:      MRR(predictions),
+		GroupAUC: GroupAUC(predictions),
+	}
+	for _, query := range queries(predictions) {
+		r.Queries++
+		for _, pred := range query {
+			if pred.Label > 0 {
+				r.Evaluated++
+				break
+			}
+		}
+	}
+	for _, k := range RankingCutoffs {
+		r.NDCG = append(r.NDCG, MetricValue{Name: fmt.Sprintf("ndcg@%d", k), Value: NDCG(predictions, k)})
+	}
+	return r
+}
+
+// writeTable writes the metrics as lines of a tab separated name and value.
+func (r *RankingReport) writeTable(w io.Writer) {
+	fmt.Fprintf(w, "queries\t%d\nevaluated_queries\t%d\n", r.Queries, r.Evaluated)
+	for _, m := range r.NDCG {
+		fmt.Fprintf(w, "%s\t%.6g\n", m.Name, m.Value)
+	}
+	fmt.Fprintf(w, "map\t%.6g\nmrr\t%.6g\ngroup_auc\t%.6g\n", r.MAP, r.MRR, r.GroupAUC)
+}
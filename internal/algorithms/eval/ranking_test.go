@@ -0,0 +1,45 @@
+package eval
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRankingMetrics(t *testing.T) {
+	predictions := []*LabelPrediction{
+		{Qid: "a", Label: 0, Prediction: 0.9},
+		{Qid: "b", Label: 1, Prediction: 0.7},
+		{Qid: "a", Label: 1, Prediction: 0.8},
+		{Qid: "c", Label: 0, Prediction: 0.5},
+		{Qid: "a", Label: 1, Prediction: 0.1},
+		{Qid: "b", Label: 0, Prediction: 0.2},
+	}
+	// Query a ranks its positive samples 2nd and 3rd, b ranks it 1st, and c has none.
+	ndcgA := (1/math.Log2(3) + 0.5) / (1 + 1/math.Log2(3))
+	r := NewRankingReport(predictions)
+	for _, m := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"NDCG@1", r.NDCG[0].Value, 0.5},
+		{"NDCG@3", r.NDCG[1].Value, (ndcgA + 1) / 2},
+		{"MAP", r.MAP, (7.0/12 + 1) / 2},
+		{"MRR", r.MRR, 0.75},
+		{"GroupAUC", r.GroupAUC, 0.4},
+	} {
+		if math.Abs(m.got-m.want) > 1e-9 {
+			t.Errorf("%s is %v, want %v", m.name, m.got, m.want)
+		}
+	}
+	if r.Queries != 3 || r.Evaluated != 2 {
+		t.Errorf("%d queries and %d evaluated, want 3 and 2", r.Queries, r.Evaluated)
+	}
+	if NewReport(predictions, 0.5).Ranking == nil {
+		t.Error("report of predictions with qids has no ranking metrics")
+	}
+	predictions[0].Qid = ""
+	predictions = predictions[:1]
+	if NewReport(predictions, 0.5).Ranking != nil {
+		t.Error("report of predictions without qids has ranking metrics")
+	}
+}
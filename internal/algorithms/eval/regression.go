@@ -0,0 +1,283 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// residual returns the value minus the prediction.
+func (pred *RealPrediction) residual() float64 {
+	return pred.Value - pred.Prediction
+}
+
+// RegMAE returns the mean absolute error of the predictions.
+func RegMAE(predictions []*RealPrediction) float64 {
+	ret := 0.0
+	for _, pred := range predictions {
+		ret += math.Abs(pred.residual())
+	}
+	return ratio(ret, float64(len(predictions)))
+}
+
+// RegMAPE returns the mean absolute error relative to the value, over the predictions
+// of non zero values.
+func RegMAPE(predictions []*RealPrediction) float64 {
+	ret, n := 0.0, 0.0
+	for _, pred := range predictions {
+		if pred.Value != 0 {
+			ret += math.Abs(pred.residual() / pred.Value)
+			n++
+		}
+	}
+	return ratio(ret, n)
+}
+
+// meanVariance returns the mean and the variance of f over the predictions.
+func meanVariance(predictions []*RealPrediction, f func(*RealPrediction) float64) (mean, variance float64) {
+	n := float64(len(predictions))
+	for _, pred := range predictions {
+		mean += f(pred)
+	}
+	mean = ratio(mean, n)
+	for _, pred := range predictions {
+		variance += (f(pred) - mean) * (f(pred) - mean)
+	}
+	return mean, ratio(variance, n)
+}
+
+func value(pred *RealPrediction) float64 {
+	return pred.Value
+}
+
+// RegR2 returns the coefficient of determination, 1 minus the mean square error over
+// the variance of the values, or 0 if the values do not vary.
+func RegR2(predictions []*RealPrediction) float64 {
+	_, variance := meanVariance(predictions, value)
+	if variance == 0 {
+		return 0
+	}
+	mse := 0.0
+	for _, pred := range predictions {
+		mse += pred.residual() * pred.residual()
+	}
+	return 1 - mse/float64(len(predictions))/variance
+}
+
+// RegExplainedVariance returns 1 minus the variance of the residuals over the variance
+// of the values, or 0 if the values do not vary. Unlike R², it ignores a constant bias
+// of the predictions.
+func RegExplainedVariance(predictions []*RealPrediction) float64 {
+	_, variance := meanVariance(predictions, value)
+	if variance == 0 {
+		return 0
+	}
+	_, residuals := meanVariance(predictions, (*RealPrediction).residual)
+	return 1 - residuals/variance
+}
+
+// RegMedianAE returns the median absolute error of the predictions.
+func RegMedianAE(predictions []*RealPrediction) float64 {
+	if len(predictions) == 0 {
+		return 0
+	}
+	errors := make([]float64, len(predictions))
+	for i, pred := range predictions {
+		errors[i] = math.Abs(pred.residual())
+	}
+	sort.Float64s(errors)
+	n := len(errors)
+	if n%2 == 1 {
+		return errors[n/2]
+	}
+	return (errors[n/2-1] + errors[n/2]) / 2
+}
+
+// PinballLoss returns the mean quantile loss of the predictions taken as the quantile
+// q of the value: q times the residuals above them, and 1-q times those below. The
+// loss at 0.5 is half the mean absolute error.
+func PinballLoss(predictions []*RealPrediction, q float64) float64 {
+	ret := 0.0
+	for _, pred := range predictions {
+		if r := pred.residual(); r >= 0 {
+			ret += q * r
+		} else {
+			ret -= (1 - q) * r
+		}
+	}
+	return ratio(ret, float64(len(predictions)))
+}
+
+// minStd bounds the predictive standard deviations NLPD divides by.
+const minStd = 1e-9
+
+// NLPD returns the mean negative log predictive density of the values under normal
+// distributions of mean Prediction and standard deviation Std. Standard deviations
+// below minStd, or NaN, are taken as minStd.
+func NLPD(predictions []*RealPrediction) float64 {
+	ret := 0.0
+	for _, pred := range predictions {
+		std := pred.Std
+		if !(std > minStd) {
+			std = minStd
+		}
+		z := pred.residual() / std
+		ret += 0.5*math.Log(2*math.Pi) + math.Log(std) + 0.5*z*z
+	}
+	return ratio(ret, float64(len(predictions)))
+}
+
+// HistogramBin counts the residuals in [Lower, Upper).
+type HistogramBin struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+	Count int     `json:"count"`
+}
+
+// ResidualHistogram counts the residuals, the values minus the predictions, in n bins
+// of equal width from the lowest to the highest. The highest is in the last bin.
+func ResidualHistogram(predictions []*RealPrediction, n int) []HistogramBin {
+	if len(predictions) == 0 {
+		return nil
+	}
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, pred := range predictions {
+		low, high = math.Min(low, pred.residual()), math.Max(high, pred.residual())
+	}
+	width := (high - low) / float64(n)
+	if width == 0 {
+		return []HistogramBin{{Lower: low, Upper: high, Count: len(predictions)}}
+	}
+	bins := make([]HistogramBin, n)
+	for i := range bins {
+		bins[i].Lower, bins[i].Upper = low+float64(i)*width, low+float64(i+1)*width
+	}
+	bins[n-1].Upper = high
+	for _, pred := range predictions {
+		i := int((pred.residual() - low) / width)
+		if i >= n {
+			i = n - 1
+		}
+		bins[i].Count++
+	}
+	return bins
+}
+
+// RegressionMetric is a metric of the predictions of a regressor.
+type RegressionMetric struct {
+	Name    string
+	Compute func(predictions []*RealPrediction) float64
+	// NeedsStd tells the metric is computed from the predictive standard deviations.
+	NeedsStd bool
+}
+
+// RegressionMetrics are the metrics ParseRegressionMetrics accepts by name, besides
+// pinball@q for the pinball loss at the quantile q.
+var RegressionMetrics = []RegressionMetric{
+	{Name: "rmse", Compute: RegRMSE},
+	{Name: "mae", Compute: RegMAE},
+	{Name: "mape", Compute: RegMAPE},
+	{Name: "r2", Compute: RegR2},
+	{Name: "explained_variance", Compute: RegExplainedVariance},
+	{Name: "median_ae", Compute: RegMedianAE},
+	{Name: "pinball@0.5", Compute: func(predictions []*RealPrediction) float64 { return PinballLoss(predictions, 0.5) }},
+	{Name: "nlpd", Compute: NLPD, NeedsStd: true},
+}
+
+// ParseRegressionMetrics returns the metrics of a comma separated list of names, or all
+// of RegressionMetrics if the list is empty.
+func ParseRegressionMetrics(list string) ([]RegressionMetric, error) {
+	if strings.TrimSpace(list) == "" {
+		return RegressionMetrics, nil
+	}
+	ret := []RegressionMetric{}
+	for _, name := range strings.Split(list, ",") {
+		m, err := parseRegressionMetric(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, m)
+	}
+	return ret, nil
+}
+
+func parseRegressionMetric(name string) (RegressionMetric, error) {
+	if strings.HasPrefix(name, "pinball@") {
+		q, err := strconv.ParseFloat(strings.TrimPrefix(name, "pinball@"), 64)
+		if err != nil || q <= 0 || q >= 1 {
+			return RegressionMetric{}, fmt.Errorf("metric %q: the quantile is not in (0, 1)", name)
+		}
+		return RegressionMetric{Name: name, Compute: func(predictions []*RealPrediction) float64 {
+			return PinballLoss(predictions, q)
+		}}, nil
+	}
+	names := make([]string, len(RegressionMetrics))
+	for i, m := range RegressionMetrics {
+		if m.Name == name {
+			return m, nil
+		}
+		names[i] = m.Name
+	}
+	return RegressionMetric{}, fmt.Errorf("unknown metric %q, want one of %s or pinball@<quantile>", name, strings.Join(names, ", "))
+}
+
+// MetricValue is the value of a named metric.
+type MetricValue struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+}
+
+// RegressionReport holds the metrics of the predictions of a regressor, and the
+// histogram of their residuals.
+type RegressionReport struct {
+	Count     int            `json:"count"`
+	Metrics   []MetricValue  `json:"metrics"`
+	Residuals []HistogramBin `json:"residuals"`
+}
+
+// NewRegressionReport returns the report of the metrics of the predictions, and of
+// their residuals in 10 bins. Metrics which need the predictive standard deviations
+// are left out unless hasStd.
+func NewRegressionReport(predictions []*RealPrediction, metrics []RegressionMetric, hasStd bool) *RegressionReport {
+	r := &RegressionReport{
+		Count:     len(predictions),
+		Metrics:   []MetricValue{},
+		Residuals: ResidualHistogram(predictions, 10),
+	}
+	for _, m := range metrics {
+		if m.NeedsStd && !hasStd {
+			continue
+		}
+		r.Metrics = append(r.Metrics, MetricValue{Name: m.Name, Value: m.Compute(predictions)})
+	}
+	return r
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *RegressionReport) WriteJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// WriteTable writes the metrics and the residual histogram as aligned columns.
+func (r *RegressionReport) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "count\t%d\n", r.Count)
+	for _, m := range r.Metrics {
+		fmt.Fprintf(tw, "%s\t%.6g\n", m.Name, m.Value)
+	}
+	fmt.Fprintf(tw, "\nresidual\tcount\n")
+	for _, b := range r.Residuals {
+		fmt.Fprintf(tw, "[%.4g, %.4g)\t%d\n", b.Lower, b.Upper, b.Count)
+	}
+	return tw.Flush()
+}
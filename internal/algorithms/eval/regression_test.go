@@ -0,0 +1,51 @@
+package eval
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRegressionMetrics(t *testing.T) {
+	predictions := []*RealPrediction{
+		{Value: 1, Prediction: 2, Std: 1},
+		{Value: 2, Prediction: 2, Std: 1},
+		{Value: 3, Prediction: 2, Std: 1},
+		{Value: 4, Prediction: 6, Std: 1},
+	}
+	// Residuals -1, 0, 1, -2 of values of mean 2.5 and variance 1.25.
+	for _, m := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"MAE", RegMAE(predictions), 1},
+		{"MAPE", RegMAPE(predictions), (1 + 0 + 1.0/3 + 0.5) / 4},
+		{"R2", RegR2(predictions), 1 - 1.5/1.25},
+		{"ExplainedVariance", RegExplainedVariance(predictions), 1 - 1.25/1.25},
+		{"MedianAE", RegMedianAE(predictions), 1},
+		{"Pinball@0.9", PinballLoss(predictions, 0.9), (0.1 + 0 + 0.9 + 0.2) / 4},
+		{"NLPD", NLPD(predictions), 0.5*math.Log(2*math.Pi) + 1.5/2},
+	} {
+		if math.Abs(m.got-m.want) > 1e-9 {
+			t.Errorf("%s is %v, want %v", m.name, m.got, m.want)
+		}
+	}
+
+	bins := ResidualHistogram(predictions, 3)
+	if len(bins) != 3 || bins[0].Count != 1 || bins[1].Count != 1 || bins[2].Count != 2 || bins[2].Upper != 1 {
+		t.Errorf("residual histogram is %+v", bins)
+	}
+
+	metrics, err := ParseRegressionMetrics("mae, pinball@0.9,nlpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRegressionReport(predictions, metrics, false)
+	if len(r.Metrics) != 2 || r.Metrics[1].Name != "pinball@0.9" {
+		t.Errorf("report metrics without standard deviations are %+v", r.Metrics)
+	}
+	for _, list := range []string{"rmse,auc", "pinball@1"} {
+		if _, err := ParseRegressionMetrics(list); err == nil {
+			t.Errorf("ParseRegressionMetrics(%q) returned no error", list)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+)
+
+// Confusion counts the predictions of a binary classifier at a threshold, weighted by
+// their Weight: predictions at or above it are positive.
+type Confusion struct {
+	TP float64 `json:"tp"`
+	FP float64 `json:"fp"`
+	TN float64 `json:"tn"`
+	FN float64 `json:"fn"`
+}
+
+// NewConfusion counts the predictions at the threshold.
+func NewConfusion(predictions []*LabelPrediction, threshold float64) Confusion {
+	c := Confusion{}
+	for _, pred := range predictions {
+		positive := pred.Prediction >= threshold
+		switch {
+		case pred.Label > 0 && positive:
+			c.TP += pred.weight()
+		case pred.Label > 0:
+			c.FN += pred.weight()
+		case positive:
+			c.FP += pred.weight()
+		default:
+			c.TN += pred.weight()
+		}
+	}
+	return c
+}
+
+// ratio returns a/b, or 0 if b is 0.
+func ratio(a, b float64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return a / b
+}
+
+func (c Confusion) Accuracy() float64 {
+	return ratio(c.TP+c.TN, c.TP+c.FP+c.TN+c.FN)
+}
+
+func (c Confusion) ErrorRate() float64 {
+	return ratio(c.FP+c.FN, c.TP+c.FP+c.TN+c.FN)
+}
+
+func (c Confusion) Precision() float64 {
+	return ratio(c.TP, c.TP+c.FP)
+}
+
+func (c Confusion) Recall() float64 {
+	return ratio(c.TP, c.TP+c.FN)
+}
+
+func (c Confusion) F1() float64 {
+	return ratio(2*c.TP, 2*c.TP+c.FP+c.FN)
+}
+
+// MCC returns the Matthews correlation coefficient, or 0 if a row or column of the
+// confusion matrix is empty.
+func (c Confusion) MCC() float64 {
+	tp, fp, tn, fn := c.TP, c.FP, c.TN, c.FN
+	return ratio(tp*tn-fp*fn, math.Sqrt((tp+fp)*(tp+fn)*(tn+fp)*(tn+fn)))
+}
+
+// sortedByPrediction returns a copy of the predictions sorted by decreasing prediction.
+func sortedByPrediction(predictions []*LabelPrediction) []*LabelPrediction {
+	sorted := make([]*LabelPrediction, len(predictions))
+	copy(sorted, predictions)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Prediction > sorted[j].Prediction })
+	return sorted
+}
+
+// sweep calls f with the confusion matrix at every distinct prediction taken as the
+// threshold, from the highest, after the predictions are sorted by sortedByPrediction.
+func sweep(sorted []*LabelPrediction, f func(threshold float64, c Confusion)) {
+	c := Confusion{}
+	for _, pred := range sorted {
+		if pred.Label > 0 {
+			c.FN += pred.weight()
+		} else {
+			c.TN += pred.weight()
+		}
+	}
+	for i := 0; i < len(sorted); {
+		threshold := sorted[i].Prediction
+		for ; i < len(sorted) && sorted[i].Prediction == threshold; i++ {
+			if sorted[i].Label > 0 {
+				c.TP += sorted[i].weight()
+				c.FN -= sorted[i].weight()
+			} else {
+				c.FP += sorted[i].weight()
+				c.TN -= sorted[i].weight()
+			}
+		}
+		f(threshold, c)
+	}
+}
+
+// PRAUC returns the area under the precision recall curve, as the average precision at
+// the recall of every distinct prediction.
+func PRAUC(predictions []*LabelPrediction) float64 {
+	ret, recall := 0.0, 0.0
+	sweep(sortedByPrediction(predictions), func(threshold float64, c Confusion) {
+		ret += (c.Recall() - recall) * c.Precision()
+		recall = c.Recall()
+	})
+	return ret
+}
+
+// KS returns the Kolmogorov-Smirnov statistic: the largest difference between the true
+// and the false positive rates over the thresholds.
+func KS(predictions []*LabelPrediction) float64 {
+	ret := 0.0
+	sweep(sortedByPrediction(predictions), func(threshold float64, c Confusion) {
+		fpr := ratio(c.FP, c.FP+c.TN)
+		ret = math.Max(ret, math.Abs(c.Recall()-fpr))
+	})
+	return ret
+}
+
+// Brier returns the mean square difference between the predictions and the labels,
+// weighted by their Weight.
+func Brier(predictions []*LabelPrediction) float64 {
+	ret, n := 0.0, 0.0
+	for _, pred := range predictions {
+		y := 0.0
+		if pred.Label > 0 {
+			y = 1
+		}
+		ret += pred.weight() * (pred.Prediction - y) * (pred.Prediction - y)
+		n += pred.weight()
+	}
+	return ratio(ret, n)
+}
+
+// OptimalThreshold returns the threshold with the highest F1, or 0.5 if there are no
+// positive samples.
+func OptimalThreshold(predictions []*LabelPrediction) float64 {
+	best, bestF1 := 0.5, 0.0
+	sweep(sortedByPrediction(predictions), func(threshold float64, c Confusion) {
+		if f1 := c.F1(); f1 > bestF1 {
+			best, bestF1 = threshold, f1
+		}
+	})
+	return best
+}
+
+// CalibrationBin holds the predictions in [Lower, Upper). Count is their number, and
+// MeanPrediction and PositiveRate are weighted by their Weight.
+type CalibrationBin struct {
+	Lower          float64 `json:"lower"`
+	Upper          float64 `json:"upper"`
+	Count          int     `json:"count"`
+	MeanPrediction float64 `json:"mean_prediction"`
+	PositiveRate   float64 `json:"positive_rate"`
+}
+
+// Calibration returns the mean prediction and the share of positive samples of the
+// predictions in each of n bins of equal width over [0, 1].
+func Calibration(predictions []*LabelPrediction, n int) []CalibrationBin {
+	bins := make([]CalibrationBin, n)
+	for i := range bins {
+		bins[i].Lower, bins[i].Upper = float64(i)/float64(n), float64(i+1)/float64(n)
+	}
+	weights := make([]float64, n)
+	for _, pred := range predictions {
+		i := int(pred.Prediction * float64(n))
+		if i < 0 {
+			i = 0
+		} else if i >= n {
+			i = n - 1
+		}
+		bins[i].Count++
+		weights[i] += pred.weight()
+		bins[i].MeanPrediction += pred.weight() * pred.Prediction
+		if pred.Label > 0 {
+			bins[i].PositiveRate += pred.weight()
+		}
+	}
+	for i := range bins {
+		bins[i].MeanPrediction = ratio(bins[i].MeanPrediction, weights[i])
+		bins[i].PositiveRate = ratio(bins[i].PositiveRate, weights[i])
+	}
+	return bins
+}
+
+// LiftBin holds a decile of the predictions, the first one holding the highest. Count
+// is the number of its predictions, a tenth of them, and the others are weighted by
+// their Weight.
+type LiftBin struct {
+	Decile       int     `json:"decile"`
+	Count        int     `json:"count"`
+	Positives    float64 `json:"positives"`
+	PositiveRate float64 `json:"positive_rate"`
+	// Lift is PositiveRate over the share of positive samples of all the predictions.
+	Lift float64 `json:"lift"`
+	// Gain is the share of all the positive samples in this and the higher deciles.
+	Gain float64 `json:"gain"`
+}
+
+// Lift returns the lift and gain table of the predictions by decile.
+func Lift(predictions []*LabelPrediction) []LiftBin {
+	sorted := sortedByPrediction(predictions)
+	positives, weight := 0.0, 0.0
+	for _, pred := range sorted {
+		if pred.Label > 0 {
+			positives += pred.weight()
+		}
+		weight += pred.weight()
+	}
+	rate := ratio(positives, weight)
+	bins := make([]LiftBin, 10)
+	cumulative := 0.0
+	for d := range bins {
+		bins[d].Decile = d + 1
+		w := 0.0
+		for _, pred := range sorted[d*len(sorted)/10 : (d+1)*len(sorted)/10] {
+			bins[d].Count++
+			w += pred.weight()
+			if pred.Label > 0 {
+				bins[d].Positives += pred.weight()
+			}
+		}
+		cumulative += bins[d].Positives
+		bins[d].PositiveRate = ratio(bins[d].Positives, w)
+		bins[d].Lift = ratio(bins[d].PositiveRate, rate)
+		bins[d].Gain = ratio(cumulative, positives)
+	}
+	return bins
+}
+
+// Report holds the metrics of the predictions of a binary classifier. Accuracy,
+// Precision, Recall, F1 and MCC are those of Confusion, at Threshold. Count is the
+// number of predictions, and every metric weighs them by their Weight, Positives
+// included.
+type Report struct {
+	Count       int              `json:"count"`
+	Positives   float64          `json:"positives"`
+	AUC         float64          `json:"auc"`
+	PRAUC       float64          `json:"pr_auc"`
+	LogLoss     float64          `json:"log_loss"`
+	Brier       float64          `json:"brier"`
+	KS          float64          `json:"ks"`
+	Threshold   float64          `json:"threshold"`
+	Confusion   Confusion        `json:"confusion"`
+	Accuracy    float64          `json:"accuracy"`
+	Precision   float64          `json:"precision"`
+	Recall      float64          `json:"recall"`
+	F1          float64          `json:"f1"`
+	MCC         float64          `json:"mcc"`
+	Calibration []CalibrationBin `json:"calibration"`
+	Lift        []LiftBin        `json:"lift"`
+	// Ranking holds the ranking metrics of the predictions grouped by query, if any of
+	// them has a Qid.
+	Ranking *RankingReport `json:"ranking,omitempty"`
+}
+
+// NewReport returns the report of the predictions at the threshold.
+func NewReport(predictions []*LabelPrediction, threshold float64) *Report {
+	c := NewConfusion(predictions, threshold)
+	r := &Report{
+		Count:       len(predictions),
+		Positives:   c.TP + c.FN,
+		AUC:         AUC(predictions),
+		PRAUC:       PRAUC(predictions),
+		Brier:       Brier(predictions),
+		KS:          KS(predictions),
+		Threshold:   threshold,
+		Confusion:   c,
+		Accuracy:    c.Accuracy(),
+		Precision:   c.Precision(),
+		Recall:      c.Recall(),
+		F1:          c.F1(),
+		MCC:         c.MCC(),
+		Calibration: Calibration(predictions, 10),
+		Lift:        Lift(predictions),
+	}
+	if len(predictions) > 0 {
+		r.LogLoss = LogLoss(predictions)
+	}
+	if HasQids(predictions) {
+		r.Ranking = NewRankingReport(predictions)
+	}
+	return r
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// WriteTable writes the metrics, the ranking metrics if any, the calibration bins and
+// the lift table as aligned columns.
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "count\t%d\npositives\t%.6g\n", r.Count, r.Positives)
+	for _, m := range []struct {
+		name  string
+		value float64
+	}{
+		{"auc", r.AUC}, {"pr_auc", r.PRAUC}, {"log_loss", r.LogLoss}, {"brier", r.Brier}, {"ks", r.KS},
+		{"threshold", r.Threshold}, {"accuracy", r.Accuracy}, {"precision", r.Precision},
+		{"recall", r.Recall}, {"f1", r.F1}, {"mcc", r.MCC},
+	} {
+		fmt.Fprintf(tw, "%s\t%.6g\n", m.name, m.value)
+	}
+	c := r.Confusion
+	fmt.Fprintf(tw, "confusion\ttp %.6g  fp %.6g  tn %.6g  fn %.6g\n", c.TP, c.FP, c.TN, c.FN)
+	if r.Ranking != nil {
+		r.Ranking.writeTable(tw)
+	}
+
+	fmt.Fprintf(tw, "\ncalibration\tcount\tmean_prediction\tpositive_rate\n")
+	for _, b := range r.Calibration {
+		fmt.Fprintf(tw, "[%.2g, %.2g)\t%d\t%.6g\t%.6g\n", b.Lower, b.Upper, b.Count, b.MeanPrediction, b.PositiveRate)
+	}
+	fmt.Fprintf(tw, "\ndecile\tcount\tpositives\tpositive_rate\tlift\tgain\n")
+	for _, b := range r.Lift {
+		fmt.Fprintf(tw, "%d\t%d\t%.6g\t%.6g\t%.6g\t%.6g\n", b.Decile, b.Count, b.Positives, b.PositiveRate, b.Lift, b.Gain)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,66 @@
+package eval
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	predictions := []*LabelPrediction{
+		{Label: 1, Prediction: 0.9},
+		{Label: 1, Prediction: 0.8},
+		{Label: 0, Prediction: 0.7},
+		{Label: 1, Prediction: 0.6},
+		{Label: 0, Prediction: 0.2},
+	}
+	r := NewReport(predictions, 0.5)
+	for _, m := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"PRAUC", r.PRAUC, 11.0 / 12},
+		{"KS", r.KS, 2.0 / 3},
+		{"Brier", r.Brier, 0.148},
+		{"MCC", r.MCC, 3 / math.Sqrt(24)},
+		{"F1", r.F1, 6.0 / 7},
+		{"OptimalThreshold", OptimalThreshold(predictions), 0.6},
+	} {
+		if math.Abs(m.got-m.want) > 1e-9 {
+			t.Errorf("%s is %v, want %v", m.name, m.got, m.want)
+		}
+	}
+	if want := (Confusion{TP: 3, FP: 1, TN: 1, FN: 0}); r.Confusion != want {
+		t.Errorf("Confusion is %+v, want %+v", r.Confusion, want)
+	}
+
+	count, positives := 0, 0.0
+	for _, b := range r.Lift {
+		count += b.Count
+		positives += b.Positives
+	}
+	if count != 5 || positives != 3 || r.Lift[9].Gain != 1 {
+		t.Errorf("lift deciles hold %d samples and %v positives, and a gain of %v", count, positives, r.Lift[9].Gain)
+	}
+	if b := r.Calibration[8]; b.Count != 1 || b.PositiveRate != 1 {
+		t.Errorf("calibration bin [0.8, 0.9) is %+v", b)
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	decoded := Report{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Confusion != r.Confusion || decoded.LogLoss != r.LogLoss {
+		t.Errorf("decoded report %+v, want %+v", decoded, r)
+	}
+
+	// Metrics of a set without positive samples are 0 instead of NaN, which JSON rejects.
+	if err := NewReport([]*LabelPrediction{{Label: 0, Prediction: 0.3}}, 0.5).WriteJSON(&buf); err != nil {
+		t.Error(err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"github.com/pantsing/hector/internal/core"
+	"os"
 	"sort"
 )
 
@@ -31,7 +32,10 @@ func main() {
 	flag.Parse()
 
 	ds := core.NewDataSet()
-	ds.Load(*path, -1)
+	if err := ds.Load(*path, -1); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 	iv := core.InformationValue(ds)
 	fs := make(FeatureValueList, 0, len(iv))
 	for f, v := range iv {
@@ -0,0 +1,56 @@
+package algorithms
+
+import (
+	"fmt"
+
+	"github.com/pantsing/hector/internal/algorithms/internal"
+	"github.com/pantsing/hector/internal/model"
+	"github.com/urfave/cli"
+)
+
+// inferCommands are the test and predict actions that take the algorithm from the
+// header of the model file instead of the command line.
+func inferCommands() []cli.Command {
+	return []cli.Command{
+		{
+			Name:   internal.ActionTest,
+			Usage:  "Evaluate the model at --model on the labeled --test set with the algorithm that trained it",
+			Flags:  internal.TestFlags,
+			Action: inferAction(internal.ActionTest),
+		},
+		{
+			Name:   internal.ActionPredict,
+			Usage:  "Score the unlabeled --input set with the model at --model and the algorithm that trained it",
+			Flags:  internal.PredictFlags,
+			Action: inferAction(internal.ActionPredict),
+		},
+	}
+}
+
+// inferAction reruns the command line as "<algorithm> <action>" of the algorithm
+// recorded in the model file.
+func inferAction(action string) cli.ActionFunc {
+	return func(ctx *cli.Context) error {
+		if err := internal.RequireFlags(ctx, "model"); err != nil {
+			return err
+		}
+		h, err := model.ReadHeader(ctx.String("model"))
+		if err != nil {
+			return err
+		}
+		if ctx.App.Command(h.Algorithm) == nil {
+			return fmt.Errorf("%s: unknown algorithm %q", ctx.String("model"), h.Algorithm)
+		}
+
+		args := []string{ctx.App.Name, h.Algorithm, action}
+		if h.Task == model.TaskMultiClass {
+			args = append(args, "--multi-class")
+		}
+		for _, name := range ctx.FlagNames() {
+			if ctx.IsSet(name) {
+				args = append(args, "--"+name+"="+ctx.String(name))
+			}
+		}
+		return ctx.App.Run(args)
+	}
+}
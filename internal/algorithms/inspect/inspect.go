@@ -0,0 +1,113 @@
+/*
+Package inspect describes a model file: how the model was trained and, for linear models,
+the features of the largest weights, by name where the names of the hashed features are
+known.
+*/
+package inspect
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pantsing/hector/internal/algorithms"
+	"github.com/pantsing/hector/internal/algorithms/internal"
+	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/hector/internal/model"
+	"github.com/urfave/cli"
+)
+
+// Action writes the description of the model at --model to stdout.
+func Action(ctx *cli.Context) error {
+	if err := internal.RequireFlags(ctx, "model"); err != nil {
+		return err
+	}
+	path := ctx.String("model")
+	h, err := model.ReadHeader(path)
+	if err != nil {
+		return err
+	}
+	algo, ok := algorithms.New(h.Algorithm).(internal.PersistentAlgorithm)
+	if !ok {
+		return fmt.Errorf("%s: unknown algorithm %q", path, h.Algorithm)
+	}
+	if h, err = internal.ReadModel(path, algo, h.Task); err != nil {
+		return err
+	}
+	names := h.FeatureNames
+	if names == nil {
+		names = make(core.FeatureDict)
+	}
+	if dictPath := ctx.String("feature-dict"); dictPath != "" {
+		dict, err := core.LoadFeatureDict(dictPath)
+		if err != nil {
+			return err
+		}
+		for id, name := range dict {
+			names.Add(name, id)
+		}
+	}
+	return Write(os.Stdout, h, algo, names, ctx.Int("top"))
+}
+
+// Write writes the description of the model algo loaded with header h to w, with the
+// top features of the largest absolute weights if it is a linear model, named by names.
+func Write(w io.Writer, h *model.Header, algo internal.Algorithm, names core.FeatureDict, top int) error {
+	fmt.Fprintf(w, "algorithm\t%s\n", h.Algorithm)
+	fmt.Fprintf(w, "task\t%s\n", h.Task)
+	fmt.Fprintf(w, "hector_version\t%s\n", h.HectorVersion)
+	if h.CommandLine != "" {
+		fmt.Fprintf(w, "command_line\t%s\n", h.CommandLine)
+	}
+	params := make([]string, 0, len(h.Params))
+	for name, value := range h.Params {
+		params = append(params, name+"="+value)
+	}
+	sort.Strings(params)
+	fmt.Fprintf(w, "params\t%s\n", strings.Join(params, " "))
+	fmt.Fprintf(w, "global_bias_feature_id\t%d\n", h.GlobalBiasFeatureID)
+	fmt.Fprintf(w, "feature_hash\t%s\n", h.FeatureHash)
+	if h.Format != "" {
+		fmt.Fprintf(w, "format\t%s\n", h.Format)
+	}
+	if h.Columns != "" {
+		fmt.Fprintf(w, "columns\t%s\n", h.Columns)
+	}
+	if h.NegSampleRate > 0 {
+		fmt.Fprintf(w, "neg_sample_rate\t%g\n", h.NegSampleRate)
+	}
+	fmt.Fprintf(w, "feature_names\t%d\n", len(names))
+
+	weighter, ok := algo.(internal.FeatureWeighter)
+	if !ok {
+		return nil
+	}
+	type weight struct {
+		id     int64
+		weight float64
+	}
+	weights := []weight{}
+	for id, w := range weighter.FeatureWeights() {
+		if w != 0 {
+			weights = append(weights, weight{id, w})
+		}
+	}
+	sort.Slice(weights, func(i, j int) bool {
+		if a, b := math.Abs(weights[i].weight), math.Abs(weights[j].weight); a != b {
+			return a > b
+		}
+		return weights[i].id < weights[j].id
+	})
+	fmt.Fprintf(w, "features\t%d\n", len(weights))
+	if top >= 0 && top < len(weights) {
+		weights = weights[:top]
+	}
+	fmt.Fprintln(w)
+	for _, f := range weights {
+		fmt.Fprintf(w, "%s\t%d\t%g\n", names.Name(f.id), f.id, f.weight)
+	}
+	return nil
+}
@@ -0,0 +1,385 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/hector/internal/params"
+	"github.com/urfave/cli"
+)
+
+// Subcommands every supervised algorithm exposes under "hector run <algo>".
+const (
+	ActionTrain   = "train"
+	ActionTest    = "test"
+	ActionPredict = "predict"
+)
+
+// dataSetUsage ends the usage of the flags of data set paths, see core.InputFiles.
+const dataSetUsage = ": a file, a directory or a glob of files, which may be gzip, bzip2 or zstd compressed, zstd needing the zstd command"
+
+var (
+	crossValidationFlag = cli.IntFlag{
+		Name:  "crossValidation,cv",
+		Value: 1,
+		Usage: "Number of cross validation folds on the train set",
+	}
+	trainSetFlag = cli.StringFlag{
+		Name:  "trainSet, train",
+		Usage: "Labeled data set to train on" + dataSetUsage,
+	}
+	testSetFlag = cli.StringFlag{
+		Name:  "testSet, test",
+		Usage: "Labeled data set to evaluate on" + dataSetUsage,
+	}
+	inputFlag = cli.StringFlag{
+		Name:  "input, i",
+		Usage: "Unlabeled data set to predict, one sample of features per line" + dataSetUsage,
+	}
+	predictResultFlag = cli.StringFlag{
+		Name:  "predictResult, predict",
+		Usage: "Write one prediction per line to the path. predict writes to stdout if it is not set",
+	}
+	lenientFlag = cli.BoolFlag{
+		Name:  "lenient",
+		Usage: "Skip and count malformed lines of the data sets instead of aborting at the first one",
+	}
+	configFlag = cli.StringFlag{
+		Name:  "config",
+		Usage: "Read flags not given on the command line from a file of key = value lines",
+	}
+	stdinFlag = cli.BoolFlag{
+		Name:  "stdin",
+		Usage: "Stream the samples to predict from stdin instead of --input",
+	}
+	idFlag = cli.BoolFlag{
+		Name:  "id",
+		Usage: "Lines to predict start with an ID column, written before their prediction",
+	}
+	withLabelFlag = cli.BoolFlag{
+		Name:  "with-label",
+		Usage: "Lines to predict hold a label, after the ID if any, written before their prediction",
+	}
+	reportFlag = cli.StringFlag{
+		Name:  "report",
+		Usage: "Write the evaluation report on the test set to the path as JSON",
+	}
+	thresholdFlag = cli.StringFlag{
+		Name:  "threshold",
+		Value: "0.5",
+		Usage: "Predictions at or above the threshold are positive in the evaluation report, or \"optimal\" for the threshold with the best F1",
+	}
+	globalFlag = cli.IntFlag{
+		Name:  "globalBiasFeatureID,global",
+		Value: 0,
+		Usage: "Global bias feature ID. test and predict take it from the model file unless it is set",
+	}
+)
+
+// FormatFlags tell how the data sets are read, see CSVFormat. test and predict take them
+// from the model file unless --format, --schema or --columns is set.
+var FormatFlags []cli.Flag = []cli.Flag{
+	cli.StringFlag{
+		Name:  "format",
+		Value: "libsvm",
+		Usage: "Format of the data sets: libsvm lines, or csv or tsv with a header line naming the columns",
+	},
+	cli.StringFlag{
+		Name:  "schema",
+		Usage: "File of the roles of the csv or tsv columns, one name:role per line",
+	},
+	cli.StringFlag{
+		Name:  "columns",
+		Usage: "Roles of the csv or tsv columns as name:role,..., the roles being label, weight, id, qid, numeric, categorical or ignore, and * naming the other columns. They default to numeric",
+	},
+}
+
+// CSVFormat returns the format of the CSV or TSV data sets --format, --schema and
+// --columns ask for, with the roles of --columns overriding those of --schema, or nil
+// for libsvm data sets.
+func CSVFormat(ctx *cli.Context) (*core.CSVFormat, error) {
+	var format *core.CSVFormat
+	switch name := ctx.String("format"); name {
+	case "", "libsvm":
+		if ctx.String("schema") != "" || ctx.String("columns") != "" {
+			return nil, fmt.Errorf("%s: --schema and --columns apply to --format csv or tsv", ctx.Command.Name)
+		}
+		return nil, nil
+	case "csv":
+		format = &core.CSVFormat{Comma: ','}
+	case "tsv":
+		format = &core.CSVFormat{Comma: '\t'}
+	default:
+		return nil, fmt.Errorf("%s: unknown --format %q, want libsvm, csv or tsv", ctx.Command.Name, name)
+	}
+	if path := ctx.String("schema"); path != "" {
+		if err := format.LoadSchema(path); err != nil {
+			return nil, fmt.Errorf("%s: --schema: %v", ctx.Command.Name, err)
+		}
+	}
+	if err := format.ParseColumnRoles(ctx.String("columns")); err != nil {
+		return nil, fmt.Errorf("%s: --columns: %v", ctx.Command.Name, err)
+	}
+	return format, nil
+}
+
+// formatFlagValues returns the values of FormatFlags giving f, read by CSVFormat, with
+// the roles of --schema folded into --columns.
+func formatFlagValues(f *core.CSVFormat) map[string]string {
+	if f == nil {
+		return map[string]string{"format": "libsvm", "columns": ""}
+	}
+	format := "csv"
+	if f.Comma == '\t' {
+		format = "tsv"
+	}
+	return map[string]string{"format": format, "columns": f.ColumnRoles()}
+}
+
+// HashFlags tell how feature names are hashed into IDs, see Hasher. test and predict take
+// them from the model file unless they are set.
+var HashFlags []cli.Flag = []cli.Flag{
+	cli.StringFlag{
+		Name:  "hash",
+		Value: "poly",
+		Usage: "Hash function of the feature names which are not integers: " + strings.Join(core.HashFuncs(), ", "),
+	},
+	cli.IntFlag{
+		Name:  "hash-bits",
+		Usage: fmt.Sprintf("Fold the feature IDs into [0,2^k) for k in 1 to %d, bounding the size of the models, or 0 to keep the whole hashes", core.MaxHashBits),
+	},
+	cli.BoolFlag{
+		Name:  "signed-hash",
+		Usage: "Multiply the values of hashed features by a sign drawn from their hashes, so that colliding features cancel out on average",
+	},
+	cli.StringFlag{
+		Name:  "hash-namespace-sep",
+		Usage: "Hash the part of feature names after the first occurrence of the separator, such as = of the categorical features of csv and tsv data sets, seeded with the hash of the namespace before it",
+	},
+}
+
+// Hasher returns the hasher of feature names --hash, --hash-bits, --signed-hash and
+// --hash-namespace-sep ask for.
+func Hasher(ctx *cli.Context) (*core.Hasher, error) {
+	h := &core.Hasher{
+		Func:         ctx.String("hash"),
+		Bits:         ctx.Int("hash-bits"),
+		Signed:       ctx.Bool("signed-hash"),
+		NamespaceSep: ctx.String("hash-namespace-sep"),
+	}
+	if err := h.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", ctx.Command.Name, err)
+	}
+	return h, nil
+}
+
+// hashFlagValues returns the values of HashFlags giving h.
+func hashFlagValues(h *core.Hasher) map[string]string {
+	fn := h.Func
+	if fn == "" {
+		fn = "poly"
+	}
+	return map[string]string{
+		"hash":               fn,
+		"hash-bits":          fmt.Sprint(h.Bits),
+		"signed-hash":        fmt.Sprint(h.Signed),
+		"hash-namespace-sep": h.NamespaceSep,
+	}
+}
+
+// InitModelFlag is accepted by the train action of algorithms implementing WarmStarter.
+var InitModelFlag cli.Flag = cli.StringFlag{
+	Name:  "init-model",
+	Usage: "Continue training the model at the path. Flags not given on the command line are taken from it",
+}
+
+// NegSampleRateFlag is accepted by the train action of binary classifiers. The rate is
+// recorded in the model file, see NegSampleRate.
+var NegSampleRateFlag cli.Flag = cli.Float64Flag{
+	Name:  "neg-sample-rate",
+	Value: 1,
+	Usage: "Train on the negative samples kept at random at the rate in (0,1], and recalibrate the predictions to the base rate of the train set",
+}
+
+// NegSampleRate returns --neg-sample-rate, or an error if it is not in (0,1].
+func NegSampleRate(ctx *cli.Context) (float64, error) {
+	rate := ctx.Float64(flagName(NegSampleRateFlag))
+	if !(rate > 0 && rate <= 1) {
+		return 0, fmt.Errorf("%s: --neg-sample-rate is %v, it must be in (0,1]", ctx.Command.Name, rate)
+	}
+	return rate, nil
+}
+
+var (
+	featureDictFlag = cli.StringFlag{
+		Name:  "feature-dict",
+		Usage: "Write the names of the hashed features of the train set to the path, one ID and name per line",
+	}
+	embedFeatureDictFlag = cli.BoolFlag{
+		Name:  "embed-feature-dict",
+		Usage: "Store the names of the hashed features of the train set in the model file",
+	}
+)
+
+// KeepsFeatureNames tells whether the names of the features of the train set are kept,
+// by --feature-dict or --embed-feature-dict.
+func KeepsFeatureNames(ctx *cli.Context) bool {
+	return ctx.String(flagName(featureDictFlag)) != "" || ctx.Bool(flagName(embedFeatureDictFlag))
+}
+
+// SaveFeatureDict returns the dictionary of the hashed features among names, the
+// FeatureNameIdMap of the train set, and writes it to --feature-dict if it is set. It
+// returns nil unless KeepsFeatureNames.
+func SaveFeatureDict(ctx *cli.Context, names map[int64]string) (core.FeatureDict, error) {
+	if !KeepsFeatureNames(ctx) {
+		return nil, nil
+	}
+	dict := core.NewFeatureDict(names)
+	if path := ctx.String(flagName(featureDictFlag)); path != "" {
+		if err := dict.Save(path); err != nil {
+			return nil, err
+		}
+	}
+	return dict, nil
+}
+
+var TrainFlags []cli.Flag = append([]cli.Flag{
+	crossValidationFlag,
+	trainSetFlag,
+	testSetFlag,
+	predictResultFlag,
+	reportFlag,
+	thresholdFlag,
+	cli.StringFlag{
+		Name:  "modelPath, model",
+		Usage: "Output the trained model to the path.",
+	},
+	configFlag,
+	lenientFlag,
+	globalFlag,
+	cvStrategyFlag,
+	seedFlag,
+	featureDictFlag,
+	embedFeatureDictFlag,
+}, joinFlags(FormatFlags, HashFlags)...)
+
+var TestFlags []cli.Flag = append([]cli.Flag{
+	testSetFlag,
+	predictResultFlag,
+	reportFlag,
+	thresholdFlag,
+	cli.StringFlag{
+		Name:  "modelPath, model",
+		Usage: "Model to evaluate.",
+	},
+	lenientFlag,
+	globalFlag,
+}, joinFlags(FormatFlags, HashFlags)...)
+
+var PredictFlags []cli.Flag = append([]cli.Flag{
+	inputFlag,
+	predictResultFlag,
+	cli.StringFlag{
+		Name:  "modelPath, model",
+		Usage: "Model to predict with.",
+	},
+	stdinFlag,
+	idFlag,
+	withLabelFlag,
+	lenientFlag,
+	globalFlag,
+}, joinFlags(FormatFlags, HashFlags)...)
+
+// Actions holds the handlers of the train, test and predict subcommands.
+type Actions struct {
+	Train   cli.ActionFunc
+	Test    cli.ActionFunc
+	Predict cli.ActionFunc
+	// TrainFlags are extra flags of the train subcommand only.
+	TrainFlags []cli.Flag
+	// TestFlags are extra flags of the test subcommand only.
+	TestFlags []cli.Flag
+}
+
+// ActionCommand turns the command of algo into the parent of its train, test and
+// predict subcommands. Every subcommand accepts the flags of the algorithm parameters,
+// the extra flags and the flags of its own action.
+func ActionCommand(algo Algorithm, extra []cli.Flag, actions Actions) cli.Command {
+	cmd := algo.Command()
+	flags := params.Flags(algo.Parameters())
+	flags = append(flags, extra...)
+
+	cmd.Subcommands = []cli.Command{
+		{
+			Name:   ActionTrain,
+			Usage:  "Train a model on --train, optionally evaluate it on --test, and save it to --model",
+			Flags:  joinFlags(joinFlags(flags, TrainFlags), actions.TrainFlags),
+			Action: actions.Train,
+		},
+		{
+			Name:   ActionTest,
+			Usage:  "Evaluate the model at --model on the labeled --test set",
+			Flags:  joinFlags(joinFlags(flags, TestFlags), actions.TestFlags),
+			Action: actions.Test,
+		},
+		{
+			Name:   ActionPredict,
+			Usage:  "Score the unlabeled --input set, or stdin, with the model at --model",
+			Flags:  joinFlags(flags, PredictFlags),
+			Action: actions.Predict,
+		},
+	}
+	cmd.Flags = nil
+	cmd.Action = nil
+	return cmd
+}
+
+func joinFlags(a, b []cli.Flag) []cli.Flag {
+	ret := make([]cli.Flag, 0, len(a)+len(b))
+	ret = append(ret, a...)
+	return append(ret, b...)
+}
+
+// ParseMode returns the mode data sets are loaded in.
+func ParseMode(ctx *cli.Context) core.ParseMode {
+	if ctx.Bool("lenient") {
+		return core.Lenient
+	}
+	return core.Strict
+}
+
+// RequireFlags returns an error naming the first of the flags that was not given.
+func RequireFlags(ctx *cli.Context, names ...string) error {
+	for _, name := range names {
+		if ctx.String(name) == "" {
+			return fmt.Errorf("%s: --%s is required", ctx.Command.Name, name)
+		}
+	}
+	return nil
+}
+
+// RequireFile returns an error if the file the flag points to does not exist.
+func RequireFile(ctx *cli.Context, name string) error {
+	path := ctx.String(name)
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("%s: --%s: %v", ctx.Command.Name, name, err)
+	}
+	return nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// CreateOutput creates the file at path, or returns stdout if path is empty or "-".
+func CreateOutput(path string) (io.WriteCloser, error) {
+	if path == "" || path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
@@ -5,10 +5,20 @@ import (
 )
 
 type Algorithm interface {
-	//Set parameters
-	Init(ctx *cli.Context)
+	// Parameters returns a pointer to the params struct of the algorithm, whose fields
+	// are declared as described in package params.
+	Parameters() interface{}
+	// Init resets the algorithm for training with its parameters.
+	Init()
 	Command() cli.Command
 	Clear()
 }
 
+// Seeder is an algorithm which draws random numbers. Seed makes it draw them from a
+// source seeded with seed, so that equal seeds train equal models. Algorithms which are
+// not seeded draw from a source seeded with 1.
+type Seeder interface {
+	Seed(seed int64)
+}
+
 var AlogCmdsChecker map[string]struct{} = make(map[string]struct{})
@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/pantsing/hector/internal/algorithms/eval"
+	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/log"
+	"github.com/urfave/cli"
+)
+
+var (
+	cvStrategyFlag = cli.StringFlag{
+		Name:  "cv-strategy",
+		Value: string(core.CVShuffle),
+		Usage: "How --cv splits the train set: shuffle, stratified by label, group by an ID column starting the lines of the train set, time series forward chaining in file order, or sequential",
+	}
+	seedFlag = cli.Int64Flag{
+		Name:  "seed",
+		Value: 1,
+		Usage: "Seed of the random cross validation folds and of the random numbers the algorithm draws",
+	}
+)
+
+// CVFlags choose how --cv splits the train set.
+var CVFlags []cli.Flag = []cli.Flag{cvStrategyFlag, seedFlag}
+
+// CrossValidation returns the cross validation --cv, --cv-strategy and --seed ask for.
+func CrossValidation(ctx *cli.Context) (*core.CrossValidation, error) {
+	strategy, err := core.ParseCVStrategy(ctx.String("cv-strategy"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", ctx.Command.Name, err)
+	}
+	return &core.CrossValidation{Folds: ctx.Int("cv"), Strategy: strategy, Seed: ctx.Int64("seed")}, nil
+}
+
+// LogFolds logs the mean and the standard deviation over the folds of cross validation
+// of every metric, of which values[i] holds the values of names[i].
+func LogFolds(names []string, values [][]float64) {
+	for i, name := range names {
+		mean, std := eval.MeanStd(values[i])
+		log.Infof("AVG. %s: %.20g STD: %.9g", name, mean, std)
+	}
+}
@@ -0,0 +1,210 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/hector/internal/model"
+	"github.com/pantsing/hector/internal/params"
+	"github.com/pantsing/log"
+	"github.com/urfave/cli"
+)
+
+// PersistentAlgorithm is an algorithm whose trained state can be saved to a model file.
+type PersistentAlgorithm interface {
+	Algorithm
+	model.Persistent
+}
+
+// WarmStarter is an algorithm which can continue training a model it loaded.
+type WarmStarter interface {
+	PersistentAlgorithm
+	// WarmStart makes the next Train continue from the loaded model instead of starting
+	// over. Init and Clear start over again.
+	WarmStart()
+}
+
+// FeatureWeighter is a linear model, whose prediction of a sample is a function of the
+// sum of the values of its features times their weights.
+type FeatureWeighter interface {
+	// FeatureWeights returns the weights by feature ID. Callers do not modify them.
+	FeatureWeights() map[int64]float64
+}
+
+func flagName(flag cli.Flag) string {
+	return strings.TrimSpace(strings.Split(flag.GetName(), ",")[0])
+}
+
+// FlagValues returns the value of every flag as given on the command line or defaulted,
+// keyed by the first name of the flag.
+func FlagValues(ctx *cli.Context, flags []cli.Flag) map[string]string {
+	values := make(map[string]string, len(flags))
+	for _, flag := range flags {
+		name := flagName(flag)
+		values[name] = ctx.String(name)
+	}
+	return values
+}
+
+// SaveModel saves algo to --model together with the algorithm name, the task, the
+// algorithm parameters, the global bias feature ID, the Hasher, the format of the data
+// sets and the --neg-sample-rate it was trained with, and with the names of the features,
+// see SaveFeatureDict, if --embed-feature-dict is set.
+func SaveModel(ctx *cli.Context, algo PersistentAlgorithm, task string, names core.FeatureDict) error {
+	hasher, err := Hasher(ctx)
+	if err != nil {
+		return err
+	}
+	csv, err := CSVFormat(ctx)
+	if err != nil {
+		return err
+	}
+	format := formatFlagValues(csv)
+	cmd := algo.Command()
+	h := &model.Header{
+		Algorithm:           cmd.Name,
+		Task:                task,
+		CommandLine:         strings.Join(os.Args, " "),
+		Params:              FlagValues(ctx, params.Flags(algo.Parameters())),
+		GlobalBiasFeatureID: ctx.Int64("global"),
+		FeatureHash:         hasher.String(),
+		Format:              format["format"],
+		Columns:             format["columns"],
+	}
+	if rate := ctx.Float64(flagName(NegSampleRateFlag)); rate > 0 && rate < 1 {
+		h.NegSampleRate = rate
+	}
+	if ctx.Bool(flagName(embedFeatureDictFlag)) {
+		h.FeatureNames = names
+	}
+	return model.Save(ctx.String("model"), h, algo)
+}
+
+func checkHeader(path string, h *model.Header, algo Algorithm, task string) error {
+	if err := h.Check(algo.Command().Name, task); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	if _, err := core.ParseHasher(h.FeatureHash); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// ReadModel loads the model file at path into algo, which is initialized with the
+// default parameters overridden by the ones recorded in the model. It is LoadModel for
+// callers without command line.
+func ReadModel(path string, algo PersistentAlgorithm, task string) (*model.Header, error) {
+	h, body, err := model.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkHeader(path, h, algo, task); err != nil {
+		return nil, err
+	}
+	p := algo.Parameters()
+	params.Defaults(p)
+	for name, value := range h.Params {
+		// Params removed since the model was written are ignored.
+		if params.Set(p, name, value) != nil && params.Has(p, name) {
+			return nil, fmt.Errorf("%s: param %s: invalid value %q", path, name, value)
+		}
+	}
+	if err = params.Validate(p); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	algo.Init()
+	if err = algo.LoadModel(bytes.NewReader(body)); err != nil {
+		return nil, fmt.Errorf("%s: model body: %v", path, err)
+	}
+	return h, nil
+}
+
+// LoadModel initializes algo with the parameters recorded in the model file at --model
+// and loads the model. It refuses models written by another algorithm or for another
+// task. Parameters given explicitly on the command line take precedence over the
+// recorded ones.
+func LoadModel(ctx *cli.Context, algo PersistentAlgorithm, task string) error {
+	return loadModel(ctx, ctx.String("model"), algo, task)
+}
+
+func loadModel(ctx *cli.Context, path string, algo PersistentAlgorithm, task string) error {
+	h, err := model.ReadHeader(path)
+	if err != nil {
+		return err
+	}
+	if err = checkHeader(path, h, algo, task); err != nil {
+		return err
+	}
+
+	hasher, err := core.ParseHasher(h.FeatureHash)
+	if err != nil {
+		return err
+	}
+	values := hashFlagValues(hasher)
+	// The format and the roles of the columns are restored together, unless any of them
+	// is set.
+	if h.Format != "" {
+		if !ctx.IsSet("format") && !ctx.IsSet("schema") && !ctx.IsSet("columns") {
+			values["format"], values["columns"] = h.Format, h.Columns
+		} else if ctx.String("format") != h.Format {
+			log.Warnf("--format=%s overrides %s recorded in %s", ctx.String("format"), h.Format, path)
+		}
+	}
+	for name, value := range h.Params {
+		values[name] = value
+	}
+	values[flagName(globalFlag)] = fmt.Sprint(h.GlobalBiasFeatureID)
+	if h.NegSampleRate > 0 {
+		values[flagName(NegSampleRateFlag)] = fmt.Sprint(h.NegSampleRate)
+	}
+	// Flags removed since the model was written are ignored.
+	for _, flag := range ctx.Command.Flags {
+		name := flagName(flag)
+		value, ok := values[name]
+		if !ok {
+			continue
+		}
+		if ctx.IsSet(name) {
+			if ctx.String(name) != value {
+				log.Warnf("--%s=%s overrides %s recorded in %s", name, ctx.String(name), value, path)
+			}
+			continue
+		}
+		if err = setFlag(ctx, flag, value); err != nil {
+			return fmt.Errorf("%s: --%s: %v", path, name, err)
+		}
+	}
+
+	if err = Configure(ctx, algo); err != nil {
+		return err
+	}
+	_, err = model.Load(path, algo)
+	return err
+}
+
+// ConfigureTraining configures algo for training by Configure, or, if --init-model is
+// set, loads the model file it points to into algo as LoadModel does and makes Train
+// continue from it.
+func ConfigureTraining(ctx *cli.Context, algo Algorithm, task string) error {
+	path := ctx.String("init-model")
+	if path == "" {
+		return Configure(ctx, algo)
+	}
+	warm, ok := algo.(WarmStarter)
+	if !ok {
+		return fmt.Errorf("%s: --init-model is not supported", algo.Command().Name)
+	}
+	if ctx.Int("cv") > 1 {
+		return fmt.Errorf("%s: --cv does not apply to --init-model", algo.Command().Name)
+	}
+	if err := loadModel(ctx, path, warm, task); err != nil {
+		return err
+	}
+	seed(ctx, algo)
+	warm.WarmStart()
+	log.Infof("Continuing the training of %s", path)
+	return nil
+}
@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pantsing/hector/internal/params"
+	"github.com/urfave/cli"
+)
+
+// setFlag sets every alias of flag, as each of them holds its own value.
+func setFlag(ctx *cli.Context, flag cli.Flag, value string) error {
+	for _, alias := range strings.Split(flag.GetName(), ",") {
+		if err := ctx.Set(strings.TrimSpace(alias), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findFlag returns the flag of the command of ctx with the name or alias name.
+func findFlag(ctx *cli.Context, name string) cli.Flag {
+	for _, f := range ctx.Command.Flags {
+		for _, alias := range strings.Split(f.GetName(), ",") {
+			if strings.TrimSpace(alias) == name {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// SetFlags sets the flags of ctx named by the keys of values as if they were given on
+// the command line.
+func SetFlags(ctx *cli.Context, values map[string]string) error {
+	for name, value := range values {
+		flag := findFlag(ctx, name)
+		if flag == nil {
+			return fmt.Errorf("%s: unknown flag --%s", ctx.Command.Name, name)
+		}
+		if err := setFlag(ctx, flag, value); err != nil {
+			return fmt.Errorf("--%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// ReadConfig sets the flags of ctx which were not given on the command line from the
+// config file at --config. Keys are flag names; a key which is not a flag of the
+// command is an error.
+func ReadConfig(ctx *cli.Context) error {
+	path := ctx.String("config")
+	if path == "" {
+		return nil
+	}
+	values, err := params.ReadConfig(path)
+	if err != nil {
+		return err
+	}
+	for key, value := range values {
+		flag := findFlag(ctx, key)
+		if flag == nil {
+			return fmt.Errorf("%s: unknown key %q", path, key)
+		}
+		if ctx.IsSet(flagName(flag)) {
+			continue
+		}
+		if err = setFlag(ctx, flag, value); err != nil {
+			return fmt.Errorf("%s: %s: %v", path, key, err)
+		}
+	}
+	return nil
+}
+
+// Configure binds the parameters of algo to the flags of ctx, validates them and
+// initializes algo for training, seeded with --seed.
+func Configure(ctx *cli.Context, algo Algorithm) error {
+	if err := params.Bind(ctx, algo.Parameters()); err != nil {
+		return fmt.Errorf("%s: %v", algo.Command().Name, err)
+	}
+	algo.Init()
+	seed(ctx, algo)
+	return nil
+}
+
+// Restart starts algo over as Configure left it, such as after a cross validation fold,
+// which Clear does not do for the algorithms like rf whose Train adds to the model.
+func Restart(ctx *cli.Context, algo Algorithm) {
+	algo.Init()
+	seed(ctx, algo)
+}
+
+// seed seeds algo with --seed if it draws random numbers.
+func seed(ctx *cli.Context, algo Algorithm) {
+	if s, ok := algo.(Seeder); ok {
+		s.Seed(ctx.Int64("seed"))
+	}
+}
@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+
+	"github.com/pantsing/hector/internal/core"
+	"github.com/urfave/cli"
+)
+
+// RequireInput returns an error unless the samples to predict come from either --input
+// or --stdin.
+func RequireInput(ctx *cli.Context) error {
+	if ctx.Bool("stdin") {
+		if ctx.String("input") != "" {
+			return fmt.Errorf("%s: --input and --stdin are exclusive", ctx.Command.Name)
+		}
+		return nil
+	}
+	return RequireFlags(ctx, "input")
+}
+
+/*
+StreamPredict reads the samples of --input, or of stdin with --stdin, and writes a line
+of format(sample) per sample to --predict, or to stdout if it is not set. The samples are
+streamed, so memory stays bounded whatever the size of the input. With --id and
+--with-label the ID and the label starting the lines are written before the predictions,
+separated by tabs; CSV and TSV input have them written by their id and label columns.
+The output is flushed whenever the input is drained, so that predictions of a pipe come
+out as its lines come in.
+*/
+func StreamPredict(ctx *cli.Context, format func(sample *core.Sample) string) (err error) {
+	dataset := core.NewStreamingDataSet()
+	dataset.Mode = ParseMode(ctx)
+	if dataset.CSV, err = CSVFormat(ctx); err != nil {
+		return err
+	}
+	if dataset.Hasher, err = Hasher(ctx); err != nil {
+		return err
+	}
+	dataset.Unlabeled = true
+	if ctx.Bool("id") {
+		dataset.KeyColumns++
+	}
+	if ctx.Bool("with-label") {
+		dataset.KeyColumns++
+	}
+
+	w, err := CreateOutput(ctx.String("predict"))
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	loaded := make(chan error, 1)
+	global := ctx.Int64("global")
+	go func() {
+		if ctx.Bool("stdin") {
+			loaded <- dataset.LoadFromStdIn(global)
+		} else {
+			loaded <- dataset.Load(ctx.String("input"), global)
+		}
+	}()
+
+	bw := bufio.NewWriter(w)
+	for sample := range dataset.Samples {
+		if sample.Key != "" {
+			bw.WriteString(sample.Key)
+			bw.WriteByte('\t')
+		}
+		bw.WriteString(format(sample))
+		bw.WriteByte('\n')
+		if len(dataset.Samples) == 0 {
+			if err = bw.Flush(); err != nil {
+				break
+			}
+		}
+	}
+	// Let the loader finish if writing failed.
+	for range dataset.Samples {
+	}
+	if loadErr := <-loaded; loadErr != nil {
+		return loadErr
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,32 @@
+package internal
+
+import (
+	"io"
+	"os"
+
+	"github.com/urfave/cli"
+)
+
+// Report is the evaluation report of predictions.
+type Report interface {
+	WriteTable(w io.Writer) error
+	WriteJSON(w io.Writer) error
+}
+
+// WriteReport writes the report as a table to stderr, and as JSON to --report if it is
+// set.
+func WriteReport(ctx *cli.Context, r Report) error {
+	if err := r.WriteTable(os.Stderr); err != nil {
+		return err
+	}
+	path := ctx.String("report")
+	if path == "" {
+		return nil
+	}
+	w, err := CreateOutput(path)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	return r.WriteJSON(w)
+}
@@ -1,8 +1,10 @@
 package gp
 
 import (
+	"errors"
 	"github.com/pantsing/hector/internal/core"
 	"github.com/urfave/cli"
+	"io"
 	"math"
 )
 
@@ -11,17 +13,12 @@ func (self *GaussianProcess) Command() cli.Command {
 		Name:     "gp",
 		Usage:    "GaussianProcess",
 		Category: "GP",
-		Flags: []cli.Flag{
-			cli.Int64Flag{
-				Name: "dim",
-			},
-		},
 	}
 }
 
 type GaussianProcessParameters struct {
-	Dim   int64
-	Theta float64
+	Dim   int64   `flag:"dim" min:"1" usage:"Number of features, which have to be continuous and numbered 1 to dim"`
+	Theta float64 `flag:"theta" default:"1e-7" min:"0" usage:"Diagonal noise of the approximate covariance inversion"`
 }
 
 type GaussianProcess struct {
@@ -34,12 +31,15 @@ type GaussianProcess struct {
 	TrainingDataCount int64
 }
 
-func (self *GaussianProcess) SaveModel(path string) {
+// The model of a Gaussian process is its training set, which is not saved.
+var errNoModel = errors.New("gp: saving and loading models is not supported")
 
+func (self *GaussianProcess) SaveModel(w io.Writer) error {
+	return errNoModel
 }
 
-func (self *GaussianProcess) LoadModel(path string) {
-
+func (self *GaussianProcess) LoadModel(r io.Reader) error {
+	return errNoModel
 }
 
 /*
@@ -154,21 +154,27 @@ func (algo *GaussianProcess) ExtractTargetValuesAsVector(samples []*core.RealSam
 	return targets
 }
 
-func (algo *GaussianProcess) Init(ctx *cli.Context) {
-	algo.Params = GaussianProcessParameters{}
-	algo.Params.Dim = ctx.Int64("dim") // Pass in dim as a param.. and require feature space to be continous.
-	algo.Params.Theta = 1e-7         // Used by approximate inversion as the diagonal noise
-
+// NewGaussianProcess returns a Gaussian process with a squared exponential covariance over
+// the features 1 to params.Dim, which have to be continuous.
+func NewGaussianProcess(params GaussianProcessParameters) *GaussianProcess {
 	radius := 0.1
 	camp := 40.0
 	cf := CovSEARD{}
 	radiuses := core.NewVector()
-	for i := int64(1); i <= algo.Params.Dim; i++ {
+	for i := int64(1); i <= params.Dim; i++ {
 		radiuses.SetValue(i, radius)
 	}
 	cf.Init(radiuses, camp)
 
-	algo.CovarianceFunc = cf.Cov
+	return &GaussianProcess{Params: params, CovarianceFunc: cf.Cov}
+}
+
+func (algo *GaussianProcess) Parameters() interface{} {
+	return &algo.Params
+}
+
+func (algo *GaussianProcess) Init() {
+	*algo = *NewGaussianProcess(algo.Params)
 }
 
 func (algo *GaussianProcess) Clear() {
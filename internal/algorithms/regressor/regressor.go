@@ -1,124 +1,291 @@
 package regressor
 
 import (
+	"bufio"
+	"fmt"
 	"github.com/pantsing/hector/internal/algorithms/eval"
 	"github.com/pantsing/hector/internal/algorithms/internal"
 	"github.com/pantsing/hector/internal/algorithms/regressor/gp"
 	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/hector/internal/model"
 	"github.com/pantsing/log"
 	"github.com/urfave/cli"
-	"math/rand"
-	"os"
+	"io"
 	"strconv"
-	"time"
+	"strings"
 )
 
 func Commands() []cli.Command {
 	cmds := make([]cli.Command, 0, 1 << 5)
-	for _, alog := range regressorIndex {
+	for name, alog := range regressorIndex {
 		cmd := alog.Command()
 		if _, ok := internal.AlogCmdsChecker[cmd.Name]; ok {
 			continue
 		}
 		internal.AlogCmdsChecker[cmd.Name] = struct{}{}
-		cmd.Action = RegAlgorithmRun
-		cmds = append(cmds, cmd)
+		cmds = append(cmds, internal.ActionCommand(alog, nil, actions(name)))
 	}
 	return cmds
 }
 
+var metricsFlag = cli.StringFlag{
+	Name:  "metrics",
+	Usage: "Comma separated metrics of the evaluation report: rmse, mae, mape, r2, explained_variance, median_ae, pinball@<quantile> and nlpd. All of them if it is not set",
+}
+
+func actions(name string) internal.Actions {
+	return internal.Actions{
+		Train:      func(ctx *cli.Context) error { return RegTrainRun(ctx, name) },
+		Test:       func(ctx *cli.Context) error { return RegTestRun(ctx, name) },
+		Predict:    func(ctx *cli.Context) error { return RegPredictRun(ctx, name) },
+		TrainFlags: []cli.Flag{metricsFlag},
+		TestFlags:  []cli.Flag{metricsFlag},
+	}
+}
+
 type Regressor interface {
 	internal.Algorithm
 	//Train model on a given dataset
 	Train(dataset *core.RealDataSet)
 	//Predict the output of an input sample
 	Predict(sample *core.RealSample) float64
-	SaveModel(path string)
-	LoadModel(path string)
+	SaveModel(w io.Writer) error
+	LoadModel(r io.Reader) error
+}
+
+// StdPredictor is a regressor which also predicts the standard deviation of its
+// predictions, such as a Gaussian process. Its reports include the negative log
+// predictive density.
+type StdPredictor interface {
+	PredictStd(sample *core.RealSample) float64
 }
 
 var regressorIndex map[string]Regressor = map[string]Regressor{
 	"gp": new(gp.GaussianProcess),
 }
 
+// Algorithms returns the regressors keyed by the names their actions dispatch on.
+func Algorithms() map[string]internal.Algorithm {
+	ret := make(map[string]internal.Algorithm, len(regressorIndex))
+	for name, algo := range regressorIndex {
+		ret[name] = algo
+	}
+	return ret
+}
+
 func GetRegressor(method string) Regressor {
-	rand.Seed(time.Now().UTC().UnixNano())
 	return regressorIndex[method]
 }
 
+func loadRealDataSet(path string, global int64, mode core.ParseMode, csv *core.CSVFormat, hasher *core.Hasher, keyColumns int) (*core.RealDataSet, error) {
+	if path == "" {
+		return nil, nil
+	}
+	dataset := core.NewRealDataSet()
+	dataset.Mode = mode
+	dataset.CSV = csv
+	dataset.Hasher = hasher
+	dataset.KeyColumns = keyColumns
+	if err := dataset.Load(path, global); err != nil {
+		return nil, err
+	}
+	return dataset, nil
+}
+
 /* Regression */
-func RegAlgorithmRun(ctx *cli.Context) (err error) {
-	alogName := ctx.Command.Name
-	regressor := GetRegressor(alogName)
 
-	trainSetPath := ctx.String("trainSet")
+// RegTrainRun trains the regressor on --train, reports the --metrics of cross
+// validation or on --test, and saves the trained model to --model if the path is set.
+func RegTrainRun(ctx *cli.Context, alogName string) (err error) {
+	if err = internal.RequireFlags(ctx, "trainSet"); err != nil {
+		return
+	}
 	testSetPath := ctx.String("testSet")
-	predictResultPath := ctx.String("predict")
 	modelPath := ctx.String("model")
 	global := ctx.Int64("global")
+	mode := internal.ParseMode(ctx)
+	csv, err := internal.CSVFormat(ctx)
+	if err != nil {
+		return
+	}
 	cv := ctx.Int("cv")
 
-	regressor.Init(ctx)
+	regressor := GetRegressor(alogName)
+	if err = internal.ReadConfig(ctx); err != nil {
+		return
+	}
+	if err = internal.Configure(ctx, regressor); err != nil {
+		return
+	}
+	metrics, err := parseMetrics(ctx)
+	if err != nil {
+		return
+	}
+	cvs, err := internal.CrossValidation(ctx)
+	if err != nil {
+		return
+	}
+	hasher, err := internal.Hasher(ctx)
+	if err != nil {
+		return
+	}
+
+	trainSet, err := loadRealDataSet(ctx.String("trainSet"), global, mode, csv, hasher, cvs.KeyColumns())
+	if err != nil {
+		return
+	}
+	names, err := internal.SaveFeatureDict(ctx, trainSet.FeatureNameIdMap)
+	if err != nil {
+		return
+	}
+	testSet, err := loadRealDataSet(testSetPath, global, mode, csv, hasher, 0)
+	if err != nil {
+		return
+	}
 
-	var trainSet *core.RealDataSet
-	if trainSetPath != "" {
-		trainSet = core.NewRealDataSet()
-		err = trainSet.Load(trainSetPath, global)
-		if err != nil {
+	if cv > 1 {
+		var folds [][2]*core.RealDataSet
+		if folds, err = cvs.SplitReal(trainSet); err != nil {
+			return
+		}
+		var names []string
+		var values [][]float64
+		for part, fold := range folds {
+			_, predictions := RegAlgorithmRunOnDataSet(regressor, fold[0], fold[1])
+			r := newReport(regressor, predictions, metrics)
+			line := make([]string, len(r.Metrics))
+			for i, m := range r.Metrics {
+				if part == 0 {
+					names = append(names, m.Name)
+					values = append(values, make([]float64, cv))
+				}
+				values[i][part] = m.Value
+				line[i] = fmt.Sprintf("%s: %.9g", m.Name, m.Value)
+			}
+			log.Info(strings.Join(line, " "))
+			internal.Restart(ctx, regressor)
+		}
+		internal.LogFolds(names, values)
+		if testSet == nil && modelPath == "" {
 			return
 		}
 	}
 
-	var testSet *core.RealDataSet
-	if testSetPath != "" {
-		testSet = core.NewRealDataSet()
-		err = testSet.Load(testSetPath, global)
-		if err != nil {
+	_, predictions := RegAlgorithmRunOnDataSet(regressor, trainSet, testSet)
+	if predictions != nil {
+		if err = internal.WriteReport(ctx, newReport(regressor, predictions, metrics)); err != nil {
 			return
 		}
+		if path := ctx.String("predict"); path != "" {
+			if err = writePredictions(path, predictions); err != nil {
+				return
+			}
+		}
 	}
 
-	_, err = os.Stat(modelPath)
-	existModel := !os.IsNotExist(err)
-	if existModel {
-		return err
+	if modelPath != "" {
+		err = internal.SaveModel(ctx, regressor, model.TaskRegression, names)
+	}
+	return
+}
+
+// RegTestRun reports the --metrics of the model at --model on the labeled --test set.
+func RegTestRun(ctx *cli.Context, alogName string) (err error) {
+	if err = internal.RequireFlags(ctx, "model", "testSet"); err != nil {
+		return
+	}
+	if err = internal.RequireFile(ctx, "model"); err != nil {
+		return
 	}
-	regressor.LoadModel(modelPath)
 
-	var predictions []*eval.RealPrediction
-	var rmse float64
-	if cv <= 1 {
-		rmse, predictions = RegAlgorithmRunOnDataSet(regressor, trainSet, testSet)
-		if predictions != nil {
-			log.Infof("RMSE: %.20g\n", rmse)
-		}
-	} else {
-		for part := 0; part < cv; part++ {
-			cvTrainSet, cvTestSet := testSet.CVSplit(cv, part)
-			rmse, predictions = RegAlgorithmRunOnDataSet(regressor, cvTrainSet, cvTestSet)
-			log.Infof("RMSE: %.20g\n", rmse)
-			regressor.Clear()
-		}
+	metrics, err := parseMetrics(ctx)
+	if err != nil {
+		return
+	}
+	regressor := GetRegressor(alogName)
+	if err = internal.LoadModel(ctx, regressor, model.TaskRegression); err != nil {
+		return
 	}
 
-	if trainSet != nil && !existModel {
-		regressor.SaveModel(modelPath)
+	csv, err := internal.CSVFormat(ctx)
+	if err != nil {
+		return
+	}
+	hasher, err := internal.Hasher(ctx)
+	if err != nil {
+		return
 	}
+	testSet, err := loadRealDataSet(ctx.String("testSet"), ctx.Int64("global"), internal.ParseMode(ctx), csv, hasher, 0)
+	if err != nil {
+		return
+	}
+	_, predictions := RegAlgorithmRunOnDataSet(regressor, nil, testSet)
+	if err = internal.WriteReport(ctx, newReport(regressor, predictions, metrics)); err != nil {
+		return
+	}
+	if path := ctx.String("predict"); path != "" {
+		return writePredictions(path, predictions)
+	}
+	return nil
+}
 
-	if predictResultPath != "" {
-		predictResultFile, err := os.Create(predictResultPath)
-		if err != nil {
-			return err
-		}
-		defer predictResultFile.Close()
+// RegPredictRun writes the predicted value of every sample of the unlabeled
+// --input set, or of stdin with --stdin, to --predict, or to stdout if it is not set.
+func RegPredictRun(ctx *cli.Context, alogName string) (err error) {
+	if err = internal.RequireFlags(ctx, "model"); err != nil {
+		return
+	}
+	if err = internal.RequireInput(ctx); err != nil {
+		return
+	}
+	if err = internal.RequireFile(ctx, "model"); err != nil {
+		return
+	}
 
-		for i := range predictions {
-			predictResultFile.WriteString(strconv.FormatFloat(predictions[i].Prediction, 'g', 5, 64) + "\n")
-		}
+	regressor := GetRegressor(alogName)
+	if err = internal.LoadModel(ctx, regressor, model.TaskRegression); err != nil {
+		return
 	}
-	return
+
+	return internal.StreamPredict(ctx, func(sample *core.Sample) string {
+		prediction := regressor.Predict(&core.RealSample{Features: sample.Features})
+		return strconv.FormatFloat(prediction, 'g', 5, 64)
+	})
+}
+
+func writePredictions(path string, predictions []*eval.RealPrediction) error {
+	w, err := internal.CreateOutput(path)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	bw := bufio.NewWriter(w)
+	for i := range predictions {
+		bw.WriteString(strconv.FormatFloat(predictions[i].Prediction, 'g', 5, 64) + "\n")
+	}
+	return bw.Flush()
+}
+
+// parseMetrics returns the metrics of --metrics.
+func parseMetrics(ctx *cli.Context) ([]eval.RegressionMetric, error) {
+	metrics, err := eval.ParseRegressionMetrics(ctx.String("metrics"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: --metrics: %v", ctx.Command.Name, err)
+	}
+	return metrics, nil
+}
+
+// newReport returns the report of the metrics of the predictions of the regressor,
+// with those of the predictive standard deviations if it is a StdPredictor.
+func newReport(regressor Regressor, predictions []*eval.RealPrediction, metrics []eval.RegressionMetric) *eval.RegressionReport {
+	_, hasStd := regressor.(StdPredictor)
+	return eval.NewRegressionReport(predictions, metrics, hasStd)
 }
 
+// RegAlgorithmRunOnDataSet trains the regressor on trainSet if it is not nil, and
+// returns the RMSE and the predictions of testSet, with their standard deviations if
+// the regressor is a StdPredictor.
 func RegAlgorithmRunOnDataSet(regressor Regressor, trainSet, testSet *core.RealDataSet) (float64, []*eval.RealPrediction) {
 	if trainSet != nil {
 		regressor.Train(trainSet)
@@ -128,8 +295,11 @@ func RegAlgorithmRunOnDataSet(regressor Regressor, trainSet, testSet *core.RealD
 	}
 	predictions := []*eval.RealPrediction{}
 	for _, sample := range testSet.Samples {
-		prediction := regressor.Predict(sample)
-		predictions = append(predictions, &eval.RealPrediction{Value: sample.Value, Prediction: prediction})
+		prediction := &eval.RealPrediction{Value: sample.Value, Prediction: regressor.Predict(sample), Weight: sample.Weight}
+		if sp, ok := regressor.(StdPredictor); ok {
+			prediction.Std = sp.PredictStd(sample)
+		}
+		predictions = append(predictions, prediction)
 	}
 	rmse := eval.RegRMSE(predictions)
 	return rmse, predictions
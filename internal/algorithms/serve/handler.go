@@ -0,0 +1,160 @@
+package serve
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pantsing/hector/internal/core"
+)
+
+// maxRequestSize is the largest request body accepted.
+const maxRequestSize = 64 << 20
+
+type instance struct {
+	Features map[string]float64 `json:"features"`
+}
+
+type request struct {
+	Features  map[string]float64 `json:"features"`
+	Instances []instance         `json:"instances"`
+}
+
+// jsonSample turns the features of a JSON request into a sample. Feature names are
+// IDs, or hashed by hasher like the feature names of data sets. The values of names
+// hashed to the same ID add up.
+func jsonSample(features map[string]float64, hasher *core.Hasher, globalBiasFeatureID int64) *core.Sample {
+	sample := core.NewSample()
+	if globalBiasFeatureID >= 0 {
+		sample.AddFeature(core.Feature{Id: globalBiasFeatureID, Value: 1.0})
+	}
+	ids := make([]int64, 0, len(features))
+	values := make(map[int64]float64, len(features))
+	for name, value := range features {
+		id, sign, _ := hasher.FeatureID(name)
+		if _, ok := values[id]; !ok {
+			ids = append(ids, id)
+		}
+		values[id] += sign * value
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		sample.AddFeature(core.Feature{Id: id, Value: values[id]})
+	}
+	return sample
+}
+
+// parseSamples reads the samples of a request body, in JSON if it starts with "{" and
+// in libsvm lines of features otherwise.
+func parseSamples(body []byte, hasher *core.Hasher, globalBiasFeatureID int64, batch bool) ([]*core.Sample, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+		req := request{}
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, fmt.Errorf("bad JSON: %v", err)
+		}
+		if !batch {
+			if req.Features == nil {
+				return nil, errors.New(`want {"features": {...}}`)
+			}
+			return []*core.Sample{jsonSample(req.Features, hasher, globalBiasFeatureID)}, nil
+		}
+		if req.Instances == nil {
+			return nil, errors.New(`want {"instances": [...]}`)
+		}
+		samples := make([]*core.Sample, 0, len(req.Instances))
+		for _, inst := range req.Instances {
+			samples = append(samples, jsonSample(inst.Features, hasher, globalBiasFeatureID))
+		}
+		return samples, nil
+	}
+
+	var samples []*core.Sample
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), core.MaxLineSize)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		sample, err := hasher.ParseSample(line, globalBiasFeatureID, false)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		samples = append(samples, sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !batch && len(samples) != 1 {
+		return nil, fmt.Errorf("want one line of features, got %d", len(samples))
+	}
+	return samples, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func (s *Server) handlePredict(batch bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		if r.Method != http.MethodPost {
+			atomic.AddInt64(&s.stats.errors, 1)
+			writeError(w, http.StatusMethodNotAllowed, errors.New("use POST"))
+			return
+		}
+		// The model is taken once, so a reload does not change it halfway through a batch.
+		m := s.current()
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+		if err == nil {
+			var samples []*core.Sample
+			if samples, err = parseSamples(body, m.hasher, m.header.GlobalBiasFeatureID, batch); err == nil {
+				predictions := make([]Prediction, len(samples))
+				for i, sample := range samples {
+					predictions[i] = m.predict(sample)
+				}
+				if batch {
+					writeJSON(w, http.StatusOK, map[string][]Prediction{"predictions": predictions})
+				} else {
+					writeJSON(w, http.StatusOK, predictions[0])
+				}
+				s.stats.record(len(samples), time.Since(start))
+				return
+			}
+		}
+		atomic.AddInt64(&s.stats.errors, 1)
+		writeError(w, http.StatusBadRequest, err)
+	}
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	m := s.current()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":    "ok",
+		"model":     s.path,
+		"algorithm": m.header.Algorithm,
+		"task":      m.header.Task,
+		"checksum":  m.header.Checksum,
+		"loaded_at": m.loadedAt.Format(time.RFC3339),
+	})
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.stats.snapshot())
+}
@@ -0,0 +1,245 @@
+/*
+Package serve answers prediction requests over HTTP with the model of a model file.
+
+	POST /predict        one sample, as a libsvm line of features or {"features": {"1": 0.7, "3": 0.1}}
+	POST /predict/batch  libsvm lines, or {"instances": [{"features": {...}}, ...]}
+	GET  /health         the model being served
+	GET  /stats          request counters and latencies
+
+The model file is watched and reloaded when it changes. Requests are answered by the
+model loaded when they arrive, so none is dropped while a new model is loaded; a model
+which fails to load leaves the current one in place.
+*/
+package serve
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/pantsing/hector/internal/algorithms"
+	"github.com/pantsing/hector/internal/algorithms/classifier"
+	"github.com/pantsing/hector/internal/algorithms/internal"
+	"github.com/pantsing/hector/internal/algorithms/regressor"
+	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/hector/internal/model"
+	"github.com/pantsing/log"
+	"github.com/urfave/cli"
+)
+
+// Prediction is the answer for one sample. Prediction is the probability of the positive
+// label for binary classifiers, the most probable label for multi-class classifiers,
+// which add the probabilities of all labels, and the predicted value for regressors.
+type Prediction struct {
+	Prediction    float64   `json:"prediction"`
+	Probabilities []float64 `json:"probabilities,omitempty"`
+}
+
+// fileStamp tells whether a file changed since it was loaded.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func stat(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+type loadedModel struct {
+	header *model.Header
+	// hasher hashes the feature names of requests as those of the train set were.
+	hasher   *core.Hasher
+	algo     internal.Algorithm
+	stamp    fileStamp
+	loadedAt time.Time
+}
+
+func (m *loadedModel) predict(sample *core.Sample) Prediction {
+	switch algo := m.algo.(type) {
+	case classifier.MultiClassClassifier:
+		if m.header.Task == model.TaskMultiClass {
+			v := algo.PredictMultiClass(sample)
+			label, _ := v.KeyWithMaxValue()
+			p := Prediction{Prediction: float64(label), Probabilities: make([]float64, v.Len())}
+			for i := range p.Probabilities {
+				p.Probabilities[i] = v.GetValue(i)
+			}
+			return p
+		}
+	case regressor.Regressor:
+		return Prediction{Prediction: algo.Predict(&core.RealSample{Features: sample.Features})}
+	}
+	return Prediction{Prediction: m.algo.(classifier.Classifier).Predict(sample)}
+}
+
+// Server serves the model of a model file.
+type Server struct {
+	path string
+	// algo is the algorithm the model has to be trained by, any if it is empty.
+	algo  string
+	model atomic.Value // *loadedModel
+	stats stats
+
+	reloadMutex sync.Mutex
+	// failed is the stamp of the file last failing to load, which is not retried.
+	failed fileStamp
+}
+
+// NewServer returns a server of the model file at path, which has to be trained by algo
+// unless it is empty.
+func NewServer(path, algo string) (*Server, error) {
+	s := &Server{path: path, algo: algo}
+	stamp, err := stat(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.load(stamp)
+	if err != nil {
+		return nil, err
+	}
+	s.model.Store(m)
+	return s, nil
+}
+
+func (s *Server) current() *loadedModel {
+	return s.model.Load().(*loadedModel)
+}
+
+func (s *Server) load(stamp fileStamp) (*loadedModel, error) {
+	h, err := model.ReadHeader(s.path)
+	if err != nil {
+		return nil, err
+	}
+	name := s.algo
+	if name == "" {
+		name = h.Algorithm
+	}
+	algo := algorithms.New(name)
+	if algo == nil {
+		return nil, fmt.Errorf("%s: unknown algorithm %q", s.path, name)
+	}
+	var ok bool
+	switch h.Task {
+	case model.TaskBinary:
+		_, ok = algo.(classifier.Classifier)
+	case model.TaskMultiClass:
+		_, ok = algo.(classifier.MultiClassClassifier)
+	case model.TaskRegression:
+		_, ok = algo.(regressor.Regressor)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s: %s does not support %s models", s.path, name, h.Task)
+	}
+	if h, err = internal.ReadModel(s.path, algo.(internal.PersistentAlgorithm), h.Task); err != nil {
+		return nil, err
+	}
+	// ReadModel checked the hasher.
+	hasher, _ := core.ParseHasher(h.FeatureHash)
+	if h.Task == model.TaskBinary {
+		algo = classifier.Recalibrate(algo.(classifier.Classifier), h.NegSampleRate)
+	}
+	return &loadedModel{header: h, hasher: hasher, algo: algo, stamp: stamp, loadedAt: time.Now()}, nil
+}
+
+// Reload loads the model file if it changed since it was loaded, and tells whether it
+// did. The served model is replaced only if the new one loads.
+func (s *Server) Reload() (bool, error) {
+	s.reloadMutex.Lock()
+	defer s.reloadMutex.Unlock()
+
+	stamp, err := stat(s.path)
+	if err != nil {
+		return false, err
+	}
+	if stamp == s.current().stamp || stamp == s.failed {
+		return false, nil
+	}
+	m, err := s.load(stamp)
+	if err != nil {
+		s.failed = stamp
+		atomic.AddInt64(&s.stats.reloadErrors, 1)
+		return false, err
+	}
+	s.model.Store(m)
+	atomic.AddInt64(&s.stats.reloads, 1)
+	return true, nil
+}
+
+// Watch reloads the model file every interval until stop is closed.
+func (s *Server) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if reloaded, err := s.Reload(); err != nil {
+				log.Errorf("keeping the current model: %v", err)
+			} else if reloaded {
+				log.Infof("reloaded %s, checksum %s", s.path, s.current().header.Checksum)
+			}
+		}
+	}
+}
+
+// Handler returns the HTTP handler of the endpoints.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/predict", s.handlePredict(false))
+	mux.HandleFunc("/predict/batch", s.handlePredict(true))
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/stats", s.handleStats)
+	return mux
+}
+
+// Action runs hector serve.
+func Action(ctx *cli.Context) error {
+	if err := internal.RequireFlags(ctx, "model"); err != nil {
+		return err
+	}
+	s, err := NewServer(ctx.String("model"), ctx.String("algo"))
+	if err != nil {
+		return err
+	}
+	h := s.current().header
+	log.Infof("serving %s model of %s trained by %s", h.Task, s.path, h.Algorithm)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	if interval := ctx.Duration("reload-interval"); interval > 0 {
+		go s.Watch(interval, stop)
+	}
+
+	server := &http.Server{
+		Addr:    net.JoinHostPort(ctx.String("host"), strconv.Itoa(ctx.Int("port"))),
+		Handler: s.Handler(),
+	}
+	done := make(chan error, 1)
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		// Let the requests in flight finish.
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		done <- server.Shutdown(shutdown)
+	}()
+	log.Infof("listening on %s", server.Addr)
+	if err = server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return <-done
+}
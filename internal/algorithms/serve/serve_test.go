@@ -0,0 +1,134 @@
+package serve
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pantsing/hector/internal/algorithms/classifier/lr"
+	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/hector/internal/model"
+	"github.com/pantsing/hector/internal/params"
+	"github.com/pantsing/hector/internal/utils"
+)
+
+// saveFTRL writes an FTRL model of feature 1 only, whose z is z.
+func saveFTRL(t *testing.T, path string, z float64) {
+	algo := &lr.FTRLLogisticRegression{}
+	params.Defaults(algo.Parameters())
+	algo.Init()
+	if err := algo.LoadModel(strings.NewReader(fmt.Sprintf("1\t1\t%v\n", z))); err != nil {
+		t.Fatal(err)
+	}
+	h := &model.Header{
+		Algorithm:           "ftrl",
+		Task:                model.TaskBinary,
+		Params:              params.Values(algo.Parameters()),
+		GlobalBiasFeatureID: -1,
+		FeatureHash:         utils.HashName,
+	}
+	if err := model.Save(path, h, algo); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func post(t *testing.T, server *httptest.Server, endpoint, body string, v interface{}) int {
+	resp, err := http.Post(server.URL+endpoint, "text/plain", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+func TestServe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hector-serve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ftrl.model")
+	saveFTRL(t, path, -5)
+
+	s, err := NewServer(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	var single, fromJSON Prediction
+	if status := post(t, server, "/predict", "1:1\n", &single); status != http.StatusOK || single.Prediction <= 0.5 {
+		t.Errorf("/predict answered %d %+v, want a positive prediction", status, single)
+	}
+	post(t, server, "/predict", `{"features": {"1": 1}}`, &fromJSON)
+	if fromJSON.Prediction != single.Prediction {
+		t.Errorf("JSON request predicted %+v, libsvm one %+v", fromJSON, single)
+	}
+
+	batch := map[string][]Prediction{}
+	post(t, server, "/predict/batch", `{"instances": [{"features": {"1": 1}}, {"features": {"2": 1}}]}`, &batch)
+	if p := batch["predictions"]; len(p) != 2 || p[0].Prediction != single.Prediction || p[1].Prediction != 0.5 {
+		t.Errorf("/predict/batch answered %+v", batch)
+	}
+
+	failure := map[string]string{}
+	if status := post(t, server, "/predict", "1:1\n2:1\n", &failure); status != http.StatusBadRequest || failure["error"] == "" {
+		t.Errorf("/predict of two samples answered %d %v, want an error", status, failure)
+	}
+
+	// Rewrite the model with the opposite weight, with another modification time.
+	time.Sleep(10 * time.Millisecond)
+	saveFTRL(t, path, 5)
+	os.Chtimes(path, time.Now().Add(time.Second), time.Now().Add(time.Second))
+	if reloaded, err := s.Reload(); !reloaded || err != nil {
+		t.Fatalf("Reload returned %v, %v after the model changed", reloaded, err)
+	}
+	var after Prediction
+	if post(t, server, "/predict", "1:1", &after); after.Prediction >= 0.5 {
+		t.Errorf("prediction is %v after reload, want it below 0.5", after.Prediction)
+	}
+
+	// A broken model file leaves the loaded model in place.
+	ioutil.WriteFile(path, []byte("broken"), 0644)
+	os.Chtimes(path, time.Now().Add(2*time.Second), time.Now().Add(2*time.Second))
+	if reloaded, err := s.Reload(); reloaded || err == nil {
+		t.Errorf("Reload of a broken model returned %v, %v", reloaded, err)
+	}
+	var kept Prediction
+	if post(t, server, "/predict", "1:1", &kept); kept.Prediction != after.Prediction {
+		t.Errorf("prediction is %+v after a failed reload, want %+v", kept, after)
+	}
+
+	snapshot := statsSnapshot{}
+	resp, err := http.Get(server.URL + "/stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	json.NewDecoder(resp.Body).Decode(&snapshot)
+	resp.Body.Close()
+	if snapshot.Requests != 5 || snapshot.Errors != 1 || snapshot.Samples != 6 || snapshot.Reloads != 1 || snapshot.ReloadErrors != 1 {
+		t.Errorf("stats are %+v", snapshot)
+	}
+}
+
+func TestJSONSampleHashesNames(t *testing.T) {
+	sample := jsonSample(map[string]float64{"country=us": 1, "7": 0.5}, nil, 0)
+	id, _ := core.FeatureID("country=us")
+	if len(sample.Features) != 3 || sample.Features[0].Id != 0 {
+		t.Fatalf("features are %v, want the bias and two more", sample.Features)
+	}
+	if sample.Features[1].Id != 7 && sample.Features[2].Id != 7 || sample.Features[1].Id != id && sample.Features[2].Id != id {
+		t.Errorf("features are %v, want 7 and %d", sample.Features, id)
+	}
+}
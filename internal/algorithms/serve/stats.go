@@ -0,0 +1,85 @@
+package serve
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets are the upper bounds of the latency histogram of predict requests.
+var latencyBuckets = [...]time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+}
+
+// stats counts the predict requests. Its fields are updated atomically.
+type stats struct {
+	requests     int64
+	errors       int64
+	samples      int64
+	reloads      int64
+	reloadErrors int64
+	latency      int64 // total, in nanoseconds
+	maxLatency   int64
+	// buckets[i] counts the requests faster than latencyBuckets[i] but not the bucket
+	// before, the last one the slower ones.
+	buckets [len(latencyBuckets) + 1]int64
+}
+
+func (s *stats) record(samples int, latency time.Duration) {
+	atomic.AddInt64(&s.requests, 1)
+	atomic.AddInt64(&s.samples, int64(samples))
+	atomic.AddInt64(&s.latency, int64(latency))
+	for {
+		max := atomic.LoadInt64(&s.maxLatency)
+		if int64(latency) <= max || atomic.CompareAndSwapInt64(&s.maxLatency, max, int64(latency)) {
+			break
+		}
+	}
+	i := 0
+	for i < len(latencyBuckets) && latency > latencyBuckets[i] {
+		i++
+	}
+	atomic.AddInt64(&s.buckets[i], 1)
+}
+
+type bucket struct {
+	Le    string `json:"le"`
+	Count int64  `json:"count"`
+}
+
+type statsSnapshot struct {
+	Requests      int64    `json:"requests"`
+	Errors        int64    `json:"errors"`
+	Samples       int64    `json:"samples"`
+	Reloads       int64    `json:"reloads"`
+	ReloadErrors  int64    `json:"reload_errors"`
+	MeanLatencyMs float64  `json:"mean_latency_ms"`
+	MaxLatencyMs  float64  `json:"max_latency_ms"`
+	Latency       []bucket `json:"latency"`
+}
+
+func (s *stats) snapshot() *statsSnapshot {
+	ret := &statsSnapshot{
+		Requests:     atomic.LoadInt64(&s.requests),
+		Errors:       atomic.LoadInt64(&s.errors),
+		Samples:      atomic.LoadInt64(&s.samples),
+		Reloads:      atomic.LoadInt64(&s.reloads),
+		ReloadErrors: atomic.LoadInt64(&s.reloadErrors),
+		MaxLatencyMs: float64(atomic.LoadInt64(&s.maxLatency)) / float64(time.Millisecond),
+	}
+	if ret.Requests > 0 {
+		ret.MeanLatencyMs = float64(atomic.LoadInt64(&s.latency)) / float64(ret.Requests) / float64(time.Millisecond)
+	}
+	for i := range s.buckets {
+		le := "+Inf"
+		if i < len(latencyBuckets) {
+			le = latencyBuckets[i].String()
+		}
+		ret.Latency = append(ret.Latency, bucket{Le: le, Count: atomic.LoadInt64(&s.buckets[i])})
+	}
+	return ret
+}
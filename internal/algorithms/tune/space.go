@@ -0,0 +1,151 @@
+package tune
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pantsing/hector/internal/params"
+)
+
+// distributions are the ranges a param may be drawn from by random search.
+var distributions = map[string]func(r *rand.Rand, lo, hi float64) string{
+	"uniform": func(r *rand.Rand, lo, hi float64) string {
+		return formatFloat(lo + r.Float64()*(hi-lo))
+	},
+	"loguniform": func(r *rand.Rand, lo, hi float64) string {
+		return formatFloat(math.Exp(math.Log(lo) + r.Float64()*(math.Log(hi)-math.Log(lo))))
+	},
+	"randint": func(r *rand.Rand, lo, hi float64) string {
+		return strconv.Itoa(int(lo) + r.Intn(int(hi)-int(lo)+1))
+	},
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', 6, 64)
+}
+
+// dimension is a param of the search space, which takes one of values or, if dist is
+// set, a value drawn from the distribution dist over [lo, hi].
+type dimension struct {
+	name   string
+	values []string
+	dist   string
+	lo, hi float64
+}
+
+func (d *dimension) draw(r *rand.Rand) string {
+	if d.dist != "" {
+		return distributions[d.dist](r, d.lo, d.hi)
+	}
+	return d.values[r.Intn(len(d.values))]
+}
+
+// parseDimension parses a value of a search space file: a comma separated list of
+// values, or a distribution such as loguniform(0.001, 1).
+func parseDimension(name, value string) (*dimension, error) {
+	d := &dimension{name: name}
+	if open := strings.Index(value, "("); open > 0 && strings.HasSuffix(value, ")") {
+		d.dist = strings.TrimSpace(value[:open])
+		if _, ok := distributions[d.dist]; !ok {
+			return nil, fmt.Errorf("%s: unknown distribution %q", name, d.dist)
+		}
+		bounds := strings.Split(value[open+1:len(value)-1], ",")
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("%s: want %s(lo, hi)", name, d.dist)
+		}
+		var err error
+		if d.lo, err = strconv.ParseFloat(strings.TrimSpace(bounds[0]), 64); err == nil {
+			d.hi, err = strconv.ParseFloat(strings.TrimSpace(bounds[1]), 64)
+		}
+		if err != nil || d.lo > d.hi || (d.dist == "loguniform" && d.lo <= 0) {
+			return nil, fmt.Errorf("%s: bad bounds of %s", name, value)
+		}
+		return d, nil
+	}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			d.values = append(d.values, v)
+		}
+	}
+	if len(d.values) == 0 {
+		return nil, fmt.Errorf("%s: no values", name)
+	}
+	return d, nil
+}
+
+/*
+readSpace reads the search space file at path, whose lines name a param of the params
+struct p and the values it takes:
+
+	alpha = 0.05, 0.1, 0.2
+	lambda1 = loguniform(0.001, 1)
+	max-depth = randint(3, 12)
+
+Distributions are uniform, loguniform and randint. The dimensions are sorted by name.
+*/
+func readSpace(path string, p interface{}) ([]*dimension, error) {
+	values, err := params.ReadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		if !params.Has(p, name) {
+			return nil, fmt.Errorf("%s: unknown param %q", path, name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	dims := make([]*dimension, 0, len(names))
+	for _, name := range names {
+		d, err := parseDimension(name, values[name])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		dims = append(dims, d)
+	}
+	if len(dims) == 0 {
+		return nil, fmt.Errorf("%s: the search space is empty", path)
+	}
+	return dims, nil
+}
+
+// grid returns every combination of the values of the dimensions.
+func grid(dims []*dimension) ([]map[string]string, error) {
+	trials := []map[string]string{{}}
+	for _, d := range dims {
+		if d.dist != "" {
+			return nil, fmt.Errorf("%s: grid search needs a list of values, not %s", d.name, d.dist)
+		}
+		next := make([]map[string]string, 0, len(trials)*len(d.values))
+		for _, trial := range trials {
+			for _, v := range d.values {
+				t := make(map[string]string, len(trial)+1)
+				for name, value := range trial {
+					t[name] = value
+				}
+				t[d.name] = v
+				next = append(next, t)
+			}
+		}
+		trials = next
+	}
+	return trials, nil
+}
+
+// random returns n combinations of values drawn from the dimensions.
+func random(dims []*dimension, n int, r *rand.Rand) []map[string]string {
+	trials := make([]map[string]string, n)
+	for i := range trials {
+		trials[i] = make(map[string]string, len(dims))
+		for _, d := range dims {
+			trials[i][d.name] = d.draw(r)
+		}
+	}
+	return trials
+}
@@ -0,0 +1,359 @@
+/*
+Package tune searches the params of a classifier for the ones with the best metric in
+cross validation.
+
+	hector tune ftrl --train train.tsv --space ftrl.space --cv 5 --metric logloss --model best.tsv
+
+The search space file names the params searched and the values they take; the other
+params take the values of the flags. Grid search tries every combination of the values,
+random search --trials combinations drawn from them. The folds of all trials are trained
+in parallel, and the trials are written ranked by the metric.
+*/
+package tune
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pantsing/hector/internal/algorithms"
+	"github.com/pantsing/hector/internal/algorithms/classifier"
+	"github.com/pantsing/hector/internal/algorithms/eval"
+	"github.com/pantsing/hector/internal/algorithms/internal"
+	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/hector/internal/model"
+	"github.com/pantsing/hector/internal/params"
+	"github.com/pantsing/log"
+	"github.com/urfave/cli"
+)
+
+// metric is a metric trials are ranked by.
+type metric struct {
+	name           string
+	higherIsBetter bool
+	compute        func(predictions []*eval.LabelPrediction) float64
+}
+
+var metrics = []metric{
+	{"auc", true, eval.AUC},
+	{"logloss", false, eval.LogLoss},
+	{"rmse", false, eval.RMSE},
+}
+
+func findMetric(name string) (int, error) {
+	names := make([]string, len(metrics))
+	for i, m := range metrics {
+		if m.name == name {
+			return i, nil
+		}
+		names[i] = m.name
+	}
+	return 0, fmt.Errorf("unknown metric %q, want one of %s", name, strings.Join(names, ", "))
+}
+
+// Flags are the flags of tune besides the params of the algorithm.
+var Flags []cli.Flag = append([]cli.Flag{
+	cli.StringFlag{
+		Name:  "trainSet, train",
+		Usage: "Labeled data set to cross validate on",
+	},
+	cli.StringFlag{
+		Name:  "space",
+		Usage: "Search space file of key = values lines. Values are a comma separated list, or uniform, loguniform or randint(lo, hi)",
+	},
+	cli.StringFlag{
+		Name:  "search",
+		Value: "grid",
+		Usage: "grid or random",
+	},
+	cli.IntFlag{
+		Name:  "trials",
+		Value: 20,
+		Usage: "Number of combinations tried by random search",
+	},
+	cli.IntFlag{
+		Name:  "crossValidation,cv",
+		Value: 5,
+		Usage: "Number of cross validation folds",
+	},
+	cli.StringFlag{
+		Name:  "metric",
+		Value: "auc",
+		Usage: "Metric the trials are ranked by: auc, logloss or rmse",
+	},
+	cli.IntFlag{
+		Name:  "parallel",
+		Value: runtime.NumCPU(),
+		Usage: "Number of folds trained at once",
+	},
+	cli.StringFlag{
+		Name:  "results",
+		Usage: "Write the ranked trials to the path, or to stdout if it is not set",
+	},
+	cli.StringFlag{
+		Name:  "modelPath, model",
+		Usage: "Train the best trial on the whole train set and save it to the path",
+	},
+	cli.StringFlag{
+		Name:  "config",
+		Usage: "Read flags not given on the command line from a file of key = value lines",
+	},
+	cli.BoolFlag{
+		Name:  "lenient",
+		Usage: "Skip and count malformed lines of the train set instead of aborting at the first one",
+	},
+	cli.IntFlag{
+		Name:  "globalBiasFeatureID,global",
+		Value: 0,
+		Usage: "Global bias feature ID",
+	},
+}, append(append(internal.CVFlags, internal.FormatFlags...), internal.HashFlags...)...)
+
+// Commands returns a tune command for every classifier.
+func Commands() []cli.Command {
+	var cmds []cli.Command
+	for name, algo := range classifier.Algorithms() {
+		name := name
+		cmd := algo.Command()
+		cmds = append(cmds, cli.Command{
+			Name:     cmd.Name,
+			Usage:    "Tune " + cmd.Usage,
+			Category: cmd.Category,
+			Flags:    append(params.Flags(algo.Parameters()), Flags...),
+			Action:   func(ctx *cli.Context) error { return Run(ctx, name) },
+		})
+	}
+	sort.Sort(cli.CommandsByName(cmds))
+	return cmds
+}
+
+// trial is a combination of params, and its metrics averaged over the folds.
+type trial struct {
+	id     int
+	params map[string]string
+	// folds[i] holds the metrics of the i-th fold, in the order of metrics.
+	folds [][]float64
+	err   error
+}
+
+func (t *trial) mean(m int) float64 {
+	sum := 0.0
+	for _, fold := range t.folds {
+		sum += fold[m]
+	}
+	return sum / float64(len(t.folds))
+}
+
+func (t *trial) std(m int) float64 {
+	mean, sum := t.mean(m), 0.0
+	for _, fold := range t.folds {
+		sum += (fold[m] - mean) * (fold[m] - mean)
+	}
+	return math.Sqrt(sum / float64(len(t.folds)))
+}
+
+// newClassifier returns a new instance of the classifier name with the params base
+// overridden by values, seeded with seed.
+func newClassifier(name string, base, values map[string]string, seed int64) (classifier.Classifier, error) {
+	c := algorithms.New(name).(classifier.Classifier)
+	p := c.Parameters()
+	for _, m := range []map[string]string{base, values} {
+		for k, v := range m {
+			if err := params.Set(p, k, v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := params.Validate(p); err != nil {
+		return nil, err
+	}
+	c.Init()
+	if s, ok := c.(internal.Seeder); ok {
+		s.Seed(seed)
+	}
+	return c, nil
+}
+
+// evaluate cross validates the trials on the folds, parallel at once.
+func evaluate(name string, base map[string]string, trials []*trial, folds [][2]*core.DataSet, parallel int, seed int64) {
+	type job struct {
+		trial *trial
+		fold  int
+	}
+	jobs := make(chan job)
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				c, _ := newClassifier(name, base, j.trial.params, seed)
+				// Some algorithms write to the samples they train on.
+				_, predictions := classifier.AlgorithmRunOnDataSet(c, folds[j.fold][0].Clone(), folds[j.fold][1])
+				values := make([]float64, len(metrics))
+				for m := range metrics {
+					values[m] = metrics[m].compute(predictions)
+				}
+				j.trial.folds[j.fold] = values
+			}
+		}()
+	}
+	for _, t := range trials {
+		if t.err != nil {
+			continue
+		}
+		for fold := range folds {
+			jobs <- job{t, fold}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// Run tunes the classifier name on --train and writes the ranked trials to --results.
+func Run(ctx *cli.Context, name string) (err error) {
+	if err = internal.RequireFlags(ctx, "trainSet", "space"); err != nil {
+		return
+	}
+	if err = internal.ReadConfig(ctx); err != nil {
+		return
+	}
+	m, err := findMetric(ctx.String("metric"))
+	if err != nil {
+		return
+	}
+	cv := ctx.Int("cv")
+	if cv < 2 {
+		return fmt.Errorf("%s: --cv is %d, it must be at least 2", ctx.Command.Name, cv)
+	}
+	cvs, err := internal.CrossValidation(ctx)
+	if err != nil {
+		return
+	}
+	parallel := ctx.Int("parallel")
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	base := algorithms.New(name)
+	if err = params.Bind(ctx, base.Parameters()); err != nil {
+		return fmt.Errorf("%s: %v", ctx.Command.Name, err)
+	}
+	baseValues := params.Values(base.Parameters())
+	dims, err := readSpace(ctx.String("space"), base.Parameters())
+	if err != nil {
+		return
+	}
+	var combinations []map[string]string
+	switch ctx.String("search") {
+	case "grid":
+		if combinations, err = grid(dims); err != nil {
+			return fmt.Errorf("%s: %v", ctx.String("space"), err)
+		}
+	case "random":
+		combinations = random(dims, ctx.Int("trials"), rand.New(rand.NewSource(cvs.Seed)))
+	default:
+		return fmt.Errorf("%s: unknown search %q, want grid or random", ctx.Command.Name, ctx.String("search"))
+	}
+
+	trials := make([]*trial, len(combinations))
+	for i, values := range combinations {
+		trials[i] = &trial{id: i + 1, params: values, folds: make([][]float64, cv)}
+		if _, trials[i].err = newClassifier(name, baseValues, values, cvs.Seed); trials[i].err != nil {
+			log.Warnf("skipping trial %d: %v", i+1, trials[i].err)
+		}
+	}
+
+	dataset := core.NewDataSet()
+	dataset.Mode = internal.ParseMode(ctx)
+	if dataset.CSV, err = internal.CSVFormat(ctx); err != nil {
+		return
+	}
+	if dataset.Hasher, err = internal.Hasher(ctx); err != nil {
+		return
+	}
+	dataset.KeyColumns = cvs.KeyColumns()
+	if err = dataset.Load(ctx.String("trainSet"), ctx.Int64("global")); err != nil {
+		return
+	}
+	folds, err := cvs.Split(dataset)
+	if err != nil {
+		return
+	}
+
+	log.Infof("Tuning %s: %d trials of %d folds", name, len(trials), cv)
+	evaluate(name, baseValues, trials, folds, parallel, cvs.Seed)
+
+	ranked := make([]*trial, 0, len(trials))
+	for _, t := range trials {
+		if t.err == nil {
+			ranked = append(ranked, t)
+		}
+	}
+	if len(ranked) == 0 {
+		return fmt.Errorf("%s: no valid trial", ctx.Command.Name)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if metrics[m].higherIsBetter {
+			return ranked[i].mean(m) > ranked[j].mean(m)
+		}
+		return ranked[i].mean(m) < ranked[j].mean(m)
+	})
+	if err = writeResults(ctx.String("results"), dims, ranked, m); err != nil {
+		return
+	}
+	best := ranked[0]
+	log.Infof("Best trial %d: %s %.9g %v", best.id, metrics[m].name, best.mean(m), best.params)
+
+	if ctx.String("model") == "" {
+		return
+	}
+	c, err := newClassifier(name, baseValues, best.params, cvs.Seed)
+	if err != nil {
+		return
+	}
+	c.Train(dataset)
+	// The model records the params of the best trial as flags.
+	if err = internal.SetFlags(ctx, best.params); err != nil {
+		return
+	}
+	return internal.SaveModel(ctx, c, model.TaskBinary, nil)
+}
+
+// writeResults writes a tab separated line per trial: its rank, ID and params, the mean
+// of every metric, and the standard deviation of the metric m over the folds.
+func writeResults(path string, dims []*dimension, ranked []*trial, m int) error {
+	w, err := internal.CreateOutput(path)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	bw := bufio.NewWriter(w)
+	columns := []string{"rank", "trial"}
+	for _, d := range dims {
+		columns = append(columns, d.name)
+	}
+	for _, metric := range metrics {
+		columns = append(columns, metric.name)
+	}
+	columns = append(columns, metrics[m].name+"-std")
+	bw.WriteString(strings.Join(columns, "\t") + "\n")
+	for rank, t := range ranked {
+		columns = []string{fmt.Sprint(rank + 1), fmt.Sprint(t.id)}
+		for _, d := range dims {
+			columns = append(columns, t.params[d.name])
+		}
+		for i := range metrics {
+			columns = append(columns, formatFloat(t.mean(i)))
+		}
+		columns = append(columns, formatFloat(t.std(m)))
+		bw.WriteString(strings.Join(columns, "\t") + "\n")
+	}
+	return bw.Flush()
+}
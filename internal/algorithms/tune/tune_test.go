@@ -0,0 +1,62 @@
+package tune
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/pantsing/hector/internal/core"
+)
+
+func TestGridAndRandom(t *testing.T) {
+	a, _ := parseDimension("max-depth", "2, 4,6")
+	b, _ := parseDimension("tree-count", "5,10")
+	if trials, err := grid([]*dimension{a, b}); err != nil || len(trials) != 6 {
+		t.Errorf("grid returned %d trials and %v, want 6", len(trials), err)
+	}
+
+	c, err := parseDimension("learning-rate", "loguniform(0.01, 1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = grid([]*dimension{a, c}); err == nil {
+		t.Error("grid of a distribution returned no error")
+	}
+	for _, trial := range random([]*dimension{a, c}, 100, rand.New(rand.NewSource(1))) {
+		v, _ := strconv.ParseFloat(trial["learning-rate"], 64)
+		if v < 0.01 || v > 1 || trial["max-depth"] == "" {
+			t.Fatalf("random drew %v", trial)
+		}
+	}
+
+	for _, value := range []string{"normal(0, 1)", "uniform(1)", "loguniform(0, 1)", "randint(5, 2)", " , "} {
+		if _, err := parseDimension("learning-rate", value); err == nil {
+			t.Errorf("parseDimension of %q returned no error", value)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	dataset := core.NewDataSet()
+	for i := 0; i < 200; i++ {
+		sample := core.NewSample()
+		sample.Label = i % 2
+		sample.AddFeature(core.Feature{Id: 1, Value: float64(sample.Label) + rand.Float64()})
+		dataset.AddSample(sample)
+	}
+	folds := make([][2]*core.DataSet, 3)
+	for part := range folds {
+		folds[part][0], folds[part][1] = dataset.CVSplit(len(folds), part)
+	}
+	trials := []*trial{
+		{id: 1, params: map[string]string{"tree-count": "1"}, folds: make([][]float64, len(folds))},
+		{id: 2, params: map[string]string{"tree-count": "3"}, folds: make([][]float64, len(folds))},
+	}
+	base := map[string]string{"learning-rate": "0.1", "max-depth": "3", "min-leaf-size": "5"}
+	evaluate("gbdt", base, trials, folds, 4, 1)
+	for _, trial := range trials {
+		if auc := trial.mean(0); auc < 0.9 {
+			t.Errorf("trial %d has AUC %v", trial.id, auc)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package convert
+
+import (
+	"github.com/pantsing/hector/internal/algorithms/convert"
+	"github.com/urfave/cli"
+)
+
+func Command() cli.Command {
+	return cli.Command{
+		Name:   "convert",
+		Usage:  "Write a data set to a binary cache which loads without parsing",
+		Flags:  convert.Flags,
+		Action: convert.Action,
+	}
+}
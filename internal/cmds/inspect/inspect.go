@@ -0,0 +1,29 @@
+package inspect
+
+import (
+	"github.com/pantsing/hector/internal/algorithms/inspect"
+	"github.com/urfave/cli"
+)
+
+func Command() cli.Command {
+	return cli.Command{
+		Name:  "inspect",
+		Usage: "Describe a model and list the features of the largest weights of linear models by name",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "modelPath, model",
+				Usage: "Model to describe",
+			},
+			cli.StringFlag{
+				Name:  "feature-dict",
+				Usage: "Feature dictionary written by train --feature-dict, naming the features the model file does not",
+			},
+			cli.IntFlag{
+				Name:  "top",
+				Value: 20,
+				Usage: "Number of features to list. -1 lists all",
+			},
+		},
+		Action: inspect.Action,
+	}
+}
@@ -0,0 +1,40 @@
+package serve
+
+import (
+	"time"
+
+	"github.com/pantsing/hector/internal/algorithms/serve"
+	"github.com/urfave/cli"
+)
+
+func Command() cli.Command {
+	return cli.Command{
+		Name:  "serve",
+		Usage: "Serve the predictions of a model over HTTP",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "modelPath, model",
+				Usage: "Model to serve",
+			},
+			cli.StringFlag{
+				Name:  "algo",
+				Usage: "Algorithm the model has to be trained by. Any if it is not set",
+			},
+			cli.StringFlag{
+				Name:  "host",
+				Usage: "Address to listen on. All if it is not set",
+			},
+			cli.IntFlag{
+				Name:  "port",
+				Value: 8080,
+				Usage: "Port to listen on",
+			},
+			cli.DurationFlag{
+				Name:  "reload-interval",
+				Value: 5 * time.Second,
+				Usage: "Interval of checking the model file for changes. 0 disables reloading",
+			},
+		},
+		Action: serve.Action,
+	}
+}
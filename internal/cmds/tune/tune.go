@@ -0,0 +1,14 @@
+package tune
+
+import (
+	"github.com/pantsing/hector/internal/algorithms/tune"
+	"github.com/urfave/cli"
+)
+
+func Command() cli.Command {
+	return cli.Command{
+		Name:        "tune",
+		Usage:       "Search the params of a classifier with the best metric in cross validation",
+		Subcommands: tune.Commands(),
+	}
+}
@@ -17,6 +17,11 @@ func NewArrayVector() *ArrayVector {
 	return &v
 }
 
+// Len returns the number of elements of v.
+func (v *ArrayVector) Len() int {
+	return len(v.data)
+}
+
 func (v *ArrayVector) ToString() []byte {
 	sb := utils.StringBuilder{}
 	for _, value := range v.data {
@@ -26,15 +31,19 @@ func (v *ArrayVector) ToString() []byte {
 	return sb.Bytes()
 }
 
-func (v *ArrayVector) FromString(buf string) {
+func (v *ArrayVector) FromString(buf string) error {
 	tks := strings.Split(buf, "|")
 	for _, tk := range tks {
 		if len(tk) == 0 {
 			continue
 		}
-		value, _ := strconv.ParseFloat(tk, 64)
+		value, err := strconv.ParseFloat(tk, 64)
+		if err != nil {
+			return err
+		}
 		v.data = append(v.data, value)
 	}
+	return nil
 }
 
 func (v *ArrayVector) Expand(size int) {
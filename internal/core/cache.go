@@ -0,0 +1,335 @@
+package core
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+/*
+Data set caches hold the samples of a data set in a binary file which loads without
+parsing, written by WriteCache and by hector convert. The loaders of DataSet, RealDataSet
+and StreamingDataSet read a cache instead of text when the file at their path starts with
+cacheMagic. The file holds, little endian:
+
+	magic      "HECTORDS"
+	version    uint32, cacheVersion
+	kind       uint32, cacheClassification or cacheRegression
+	hash       string, the Hasher of the feature IDs, see Hasher.String
+	n          uint64, number of samples
+	nf         uint64, number of features of all samples
+	names      uint64, number of feature names
+	labels     n float64, labels or target values
+	weights    n float64
+	ends       n uint64, end of the features of each sample in the features
+	features   nf (int64 ID, float64 value)
+	keys       n (key, qid)
+	names      names (int64 ID, name)
+
+Strings are written as their uvarint length and their bytes. The features are those of
+samples loaded without a global bias feature, which the loaders add as they do to text.
+Loaders hashing feature names with another Hasher reject the cache.
+*/
+const (
+	cacheMagic   = "HECTORDS"
+	cacheVersion = 2
+
+	cacheClassification = 0
+	cacheRegression     = 1
+)
+
+var errTruncatedCache = errors.New("truncated data set cache")
+
+// cacheSample is a sample of a data set cache.
+type cacheSample struct {
+	label, weight float64
+	key, qid      string
+	features      []Feature
+}
+
+// sample returns the classification sample of s, labeled 0 unless labeled.
+func (s *cacheSample) sample(labeled bool) *Sample {
+	sample := &Sample{Features: s.features, Key: s.key, Qid: s.qid, Weight: s.weight}
+	if labeled {
+		sample.Label = int(s.label)
+	}
+	return sample
+}
+
+// realSample returns the regression sample of s, of value 0 unless labeled.
+func (s *cacheSample) realSample(labeled bool) *RealSample {
+	sample := &RealSample{Features: s.features, Key: s.key, Qid: s.qid, Weight: s.weight}
+	if labeled {
+		sample.Value = s.label
+	}
+	return sample
+}
+
+// IsCache tells whether the file at path is a data set cache.
+func IsCache(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	magic := make([]byte, len(cacheMagic))
+	_, err = io.ReadFull(file, magic)
+	return err == nil && string(magic) == cacheMagic
+}
+
+// cacheWriter writes the fields of a data set cache, keeping the first error.
+type cacheWriter struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (w *cacheWriter) u32(v uint32) {
+	binary.LittleEndian.PutUint32(w.buf[:], v)
+	w.write(w.buf[:4])
+}
+
+func (w *cacheWriter) u64(v uint64) {
+	binary.LittleEndian.PutUint64(w.buf[:], v)
+	w.write(w.buf[:8])
+}
+
+func (w *cacheWriter) str(s string) {
+	w.write(w.buf[:binary.PutUvarint(w.buf[:], uint64(len(s)))])
+	if w.err == nil {
+		_, w.err = w.w.WriteString(s)
+	}
+}
+
+func (w *cacheWriter) write(b []byte) {
+	if w.err == nil {
+		_, w.err = w.w.Write(b)
+	}
+}
+
+// writeCache writes a cache of kind of the n samples sample returns, whose features were
+// hashed by hasher, and of names, to the file at path.
+func writeCache(path string, kind uint32, hasher *Hasher, n int, sample func(i int) cacheSample, names map[int64]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := &cacheWriter{w: bufio.NewWriterSize(file, 1<<20)}
+
+	nf := 0
+	for i := 0; i < n; i++ {
+		nf += len(sample(i).features)
+	}
+	w.write([]byte(cacheMagic))
+	w.u32(cacheVersion)
+	w.u32(kind)
+	w.str(hasher.String())
+	w.u64(uint64(n))
+	w.u64(uint64(nf))
+	w.u64(uint64(len(names)))
+	for i := 0; i < n; i++ {
+		w.u64(math.Float64bits(sample(i).label))
+	}
+	for i := 0; i < n; i++ {
+		w.u64(math.Float64bits(sample(i).weight))
+	}
+	end := 0
+	for i := 0; i < n; i++ {
+		end += len(sample(i).features)
+		w.u64(uint64(end))
+	}
+	for i := 0; i < n; i++ {
+		for _, f := range sample(i).features {
+			w.u64(uint64(f.Id))
+			w.u64(math.Float64bits(f.Value))
+		}
+	}
+	for i := 0; i < n; i++ {
+		s := sample(i)
+		w.str(s.key)
+		w.str(s.qid)
+	}
+	for id, name := range names {
+		w.u64(uint64(id))
+		w.str(name)
+	}
+	if w.err == nil {
+		w.err = w.w.Flush()
+	}
+	if w.err != nil {
+		return w.err
+	}
+	return file.Close()
+}
+
+// WriteCache writes the samples of d and its FeatureNameIdMap to a data set cache at
+// path. d is expected to be loaded without a global bias feature.
+func (d *DataSet) WriteCache(path string) error {
+	return writeCache(path, cacheClassification, d.Hasher, len(d.Samples), func(i int) cacheSample {
+		s := d.Samples[i]
+		return cacheSample{label: float64(s.Label), weight: s.Weight, key: s.Key, qid: s.Qid, features: s.Features}
+	}, d.FeatureNameIdMap)
+}
+
+// WriteCache writes the samples of d to a data set cache at path. d is expected to be
+// loaded without a global bias feature.
+func (d *RealDataSet) WriteCache(path string) error {
+	return writeCache(path, cacheRegression, d.Hasher, len(d.Samples), func(i int) cacheSample {
+		s := d.Samples[i]
+		return cacheSample{label: s.Value, weight: s.Weight, key: s.Key, qid: s.Qid, features: s.Features}
+	}, nil)
+}
+
+// cacheReader reads the fields of a data set cache from its mapped bytes.
+type cacheReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *cacheReader) next(n int) []byte {
+	if r.err != nil || n < 0 || n > len(r.data)-r.pos {
+		r.err = errTruncatedCache
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *cacheReader) u32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *cacheReader) u64() uint64 {
+	if b := r.next(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *cacheReader) str() string {
+	if r.err != nil {
+		return ""
+	}
+	n, size := binary.Uvarint(r.data[r.pos:])
+	if size <= 0 || n > uint64(len(r.data)) {
+		r.err = errTruncatedCache
+		return ""
+	}
+	r.pos += size
+	return string(r.next(int(n)))
+}
+
+// section returns the n fields of size bytes starting at the current position.
+func (r *cacheReader) section(n uint64, size int) []byte {
+	if n > uint64(len(r.data))/uint64(size) {
+		r.err = errTruncatedCache
+		return nil
+	}
+	return r.next(int(n) * size)
+}
+
+/*
+readCache calls add with every sample of the data set cache at path, in order, and
+returns its feature names. The features must have been hashed by hasher. The features of
+the samples are slices of one array, with the global bias feature, unless
+globalBiasFeatureID is negative, where the text loaders put it: first for classification
+and last for regression. Classification loaders reject caches of regression data sets,
+whose target values are not labels. The file is memory mapped while it is read.
+*/
+func readCache(path string, hasher *Hasher, globalBiasFeatureID int64, classification bool, add func(s *cacheSample)) (names map[int64]string, err error) {
+	data, unmap, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer unmap()
+
+	r := &cacheReader{data: data}
+	if string(r.next(len(cacheMagic))) != cacheMagic {
+		return nil, fmt.Errorf("%s: not a data set cache", path)
+	}
+	if version := r.u32(); r.err == nil && version != cacheVersion {
+		return nil, fmt.Errorf("%s: data set cache of version %d, want %d", path, version, cacheVersion)
+	}
+	kind := r.u32()
+	hash := r.str()
+	n, nf, nn := r.u64(), r.u64(), r.u64()
+	labels := r.section(n, 8)
+	weights := r.section(n, 8)
+	ends := r.section(n, 8)
+	features := r.section(nf, 16)
+	if r.err != nil {
+		return nil, fmt.Errorf("%s: %v", path, r.err)
+	}
+	if kind != cacheClassification && kind != cacheRegression {
+		return nil, fmt.Errorf("%s: corrupt data set cache", path)
+	}
+	if classification && kind == cacheRegression {
+		return nil, fmt.Errorf("%s: cache of a regression data set, whose target values are not labels", path)
+	}
+	if hash != hasher.String() {
+		return nil, fmt.Errorf("%s: features were hashed with %q, not %q", path, hash, hasher.String())
+	}
+
+	bias := 0
+	if globalBiasFeatureID >= 0 {
+		bias = 1
+	}
+	all := make([]Feature, int(nf)+int(n)*bias)
+	start, pos := uint64(0), 0
+	for i := 0; i < int(n); i++ {
+		end := binary.LittleEndian.Uint64(ends[8*i:])
+		if end < start || end > nf {
+			return nil, fmt.Errorf("%s: corrupt data set cache", path)
+		}
+		fs := all[pos : pos+int(end-start)+bias : pos+int(end-start)+bias]
+		pos += len(fs)
+		j := 0
+		if bias > 0 && classification {
+			fs[0] = Feature{globalBiasFeatureID, 1.0}
+			j = 1
+		}
+		for k := start; k < end; k++ {
+			b := features[16*k:]
+			fs[j] = Feature{int64(binary.LittleEndian.Uint64(b)), math.Float64frombits(binary.LittleEndian.Uint64(b[8:]))}
+			j++
+		}
+		if bias > 0 && !classification {
+			fs[j] = Feature{globalBiasFeatureID, 1.0}
+		}
+		start = end
+		s := &cacheSample{
+			label:    math.Float64frombits(binary.LittleEndian.Uint64(labels[8*i:])),
+			weight:   math.Float64frombits(binary.LittleEndian.Uint64(weights[8*i:])),
+			key:      r.str(),
+			qid:      r.str(),
+			features: fs,
+		}
+		if r.err != nil {
+			return nil, fmt.Errorf("%s: %v", path, r.err)
+		}
+		add(s)
+	}
+	names = make(map[int64]string)
+	for i := uint64(0); i < nn && r.err == nil; i++ {
+		id := int64(r.u64())
+		names[id] = r.str()
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("%s: %v", path, r.err)
+	}
+	return names, nil
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pantsing/hector/internal/utils"
+)
+
+func TestCacheRoundTrip(t *testing.T) {
+	path, cleanup := writeDataFile(t, "1\tqid:3\tweight:2\t1:0.5\tcountry:1\n0\t\n1\t7:-1.5\n")
+	defer cleanup()
+	text := NewDataSet()
+	if err := text.Load(path, -1); err != nil {
+		t.Fatal(err)
+	}
+	cache := filepath.Join(filepath.Dir(path), "data.hc")
+	if err := text.WriteCache(cache); err != nil {
+		t.Fatal(err)
+	}
+	if !IsCache(cache) || IsCache(path) {
+		t.Fatal("IsCache does not tell the cache from the text")
+	}
+
+	for _, global := range []int64{-1, 0} {
+		want, got := NewDataSet(), NewDataSet()
+		if err := want.Load(path, global); err != nil {
+			t.Fatal(err)
+		}
+		if err := got.Load(cache, global); err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Samples) != len(want.Samples) {
+			t.Fatalf("loaded %d samples from the cache, want %d", len(got.Samples), len(want.Samples))
+		}
+		for i, s := range got.Samples {
+			if !bytes.Equal(s.ToString(true), want.Samples[i].ToString(true)) || s.Qid != want.Samples[i].Qid {
+				t.Errorf("sample %d of the cache is %q, want %q", i, s.ToString(true), want.Samples[i].ToString(true))
+			}
+		}
+		if got.FeatureNameIdMap[utils.Hash("country")] != "country" {
+			t.Error("feature names not cached")
+		}
+	}
+
+	// Appending to the features of a sample leaves those of the next one alone.
+	d := NewDataSet()
+	if err := d.Load(cache, -1); err != nil {
+		t.Fatal(err)
+	}
+	d.Samples[1].Features = append(d.Samples[1].Features, Feature{9, 9})
+	if f := d.Samples[2].Features; len(f) != 1 || f[0] != (Feature{7, -1.5}) {
+		t.Errorf("third sample has features %v", f)
+	}
+
+	if err := NewRealDataSet().Load(cache, -1); err != nil {
+		t.Errorf("RealDataSet.Load of a classification cache: %v", err)
+	}
+}
+
+func TestCacheErrors(t *testing.T) {
+	path, cleanup := writeDataFile(t, "1.5\t1:1\n")
+	defer cleanup()
+	d := NewRealDataSet()
+	if err := d.Load(path, -1); err != nil {
+		t.Fatal(err)
+	}
+	cache := filepath.Join(filepath.Dir(path), "data.hc")
+	if err := d.WriteCache(cache); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewDataSet().Load(cache, -1); err == nil {
+		t.Error("DataSet.Load of a regression cache returned no error")
+	}
+
+	data, err := ioutil.ReadFile(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(cache, data[:len(data)-3], 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = NewRealDataSet().Load(cache, -1); err == nil {
+		t.Error("Load of a truncated cache returned no error")
+	}
+}
+
+// benchmarkDataSet writes a text data set of n samples of 40 features, and its cache, to
+// a temporary directory.
+func benchmarkDataSet(b *testing.B, n int) (text, cache string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "hector-core")
+	if err != nil {
+		b.Fatal(err)
+	}
+	rng := rand.New(rand.NewSource(1))
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&buf, "%d", i%2)
+		for j := 0; j < 40; j++ {
+			fmt.Fprintf(&buf, "\t%d:%.6g", rng.Intn(100000), rng.Float64())
+		}
+		buf.WriteByte('\n')
+	}
+	text, cache = filepath.Join(dir, "data.tsv"), filepath.Join(dir, "data.hc")
+	if err = ioutil.WriteFile(text, buf.Bytes(), 0600); err != nil {
+		b.Fatal(err)
+	}
+	d := NewDataSet()
+	if err = d.Load(text, -1); err != nil {
+		b.Fatal(err)
+	}
+	if err = d.WriteCache(cache); err != nil {
+		b.Fatal(err)
+	}
+	return text, cache, func() { os.RemoveAll(dir) }
+}
+
+// BenchmarkLoad compares loading a data set from text and from its cache.
+func BenchmarkLoad(b *testing.B) {
+	text, cache, cleanup := benchmarkDataSet(b, 20000)
+	defer cleanup()
+	for _, path := range []struct{ name, path string }{{"text", text}, {"cache", cache}} {
+		b.Run(path.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := NewDataSet().Load(path.path, 0); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,333 @@
+package core
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ColumnRole tells what a column of a CSV or TSV data set holds.
+type ColumnRole string
+
+const (
+	// RoleLabel is the label of a classification data set, or the target value of a
+	// regression data set.
+	RoleLabel ColumnRole = "label"
+	// RoleWeight is the Weight of the sample.
+	RoleWeight ColumnRole = "weight"
+	// RoleID columns are kept in the Key of the sample, joined by tabs.
+	RoleID ColumnRole = "id"
+	// RoleQid is the Qid of the sample.
+	RoleQid ColumnRole = "qid"
+	// RoleNumeric columns are features named by the column, of the value of the cell.
+	RoleNumeric ColumnRole = "numeric"
+	// RoleCategorical columns are features named column=value, of value 1.
+	RoleCategorical ColumnRole = "categorical"
+	// RoleIgnore columns are not read.
+	RoleIgnore ColumnRole = "ignore"
+)
+
+var columnRoles = []ColumnRole{RoleLabel, RoleWeight, RoleID, RoleQid, RoleNumeric, RoleCategorical, RoleIgnore}
+
+/*
+CSVFormat reads data sets from CSV or TSV files whose first line is a header naming the
+columns. Every column has a role, given by Roles for the columns named there and by
+Default, or RoleNumeric if it is empty, for the others.
+
+Feature IDs are those of the names of libsvm lines: numeric columns are named by the
+column, categorical ones by column=value, and names which are not integers are hashed.
+Empty cells and numeric cells of 0 give no feature. Data sets loaded unlabeled need no
+label column; the cells of the label column, if they have one, are kept in the Key after
+the ID columns.
+*/
+type CSVFormat struct {
+	// Comma separates the fields: ',' for CSV, '\t' for TSV.
+	Comma   rune
+	Roles   map[string]ColumnRole
+	Default ColumnRole
+}
+
+// ParseColumnRoles parses the roles of columns given as name:role items separated by
+// commas or new lines, such as "click:label,user:id,country:categorical". Blank items
+// and lines starting with # are skipped, and the name * stands for every column not
+// named.
+func (f *CSVFormat) ParseColumnRoles(spec string) error {
+	if f.Roles == nil {
+		f.Roles = make(map[string]ColumnRole)
+	}
+	for _, line := range strings.Split(spec, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		for _, item := range strings.Split(line, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			i := strings.LastIndex(item, ":")
+			if i < 0 {
+				return fmt.Errorf("column %q has no role, want name:role", item)
+			}
+			name, role := strings.TrimSpace(item[:i]), ColumnRole(strings.TrimSpace(item[i+1:]))
+			if !validRole(role) {
+				return fmt.Errorf("column %q: unknown role %q, want one of %s", name, role, roleNames())
+			}
+			if name == "*" {
+				f.Default = role
+			} else {
+				f.Roles[name] = role
+			}
+		}
+	}
+	return nil
+}
+
+// ColumnRoles returns the roles of f as ParseColumnRoles takes them, sorted by column
+// name, followed by the role of the columns not named if it is set.
+func (f *CSVFormat) ColumnRoles() string {
+	items := make([]string, 0, len(f.Roles)+1)
+	for name, role := range f.Roles {
+		items = append(items, name+":"+string(role))
+	}
+	sort.Strings(items)
+	if f.Default != "" {
+		items = append(items, "*:"+string(f.Default))
+	}
+	return strings.Join(items, ",")
+}
+
+// LoadSchema reads the roles of columns from the schema file at path, which holds them
+// as ParseColumnRoles takes them, usually one name:role per line.
+func (f *CSVFormat) LoadSchema(path string) error {
+	spec, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err = f.ParseColumnRoles(string(spec)); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+func validRole(role ColumnRole) bool {
+	for _, r := range columnRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func roleNames() string {
+	names := make([]string, len(columnRoles))
+	for i, r := range columnRoles {
+		names[i] = string(r)
+	}
+	return strings.Join(names, ", ")
+}
+
+// headerError reports a malformed header line, which aborts loading in every mode.
+type headerError struct {
+	error
+}
+
+// csvRecord holds the cells of a line of a CSV data set by role.
+type csvRecord struct {
+	label    string
+	weight   float64
+	key, qid string
+	features []Feature
+}
+
+// csvReader parses the lines of a CSV data set, the first of which is the header.
+type csvReader struct {
+	format  *CSVFormat
+	labeled bool
+	// feature turns the names and values of features into Features, see
+	// Hasher.features.
+	feature func(name string, value float64) Feature
+	columns []string
+	roles   []ColumnRole
+}
+
+func (f *CSVFormat) reader(labeled bool, feature func(name string, value float64) Feature) *csvReader {
+	return &csvReader{format: f, labeled: labeled, feature: feature}
+}
+
+func (r *csvReader) split(line string) ([]string, error) {
+	if r.format.Comma == '\t' {
+		return strings.Split(line, "\t"), nil
+	}
+	cr := csv.NewReader(strings.NewReader(line))
+	cr.Comma = r.format.Comma
+	cr.FieldsPerRecord = -1
+	return cr.Read()
+}
+
+// header sets the columns and their roles from the header line.
+func (r *csvReader) header(line string) error {
+	columns, err := r.split(line)
+	if err != nil {
+		return headerError{fmt.Errorf("header: %v", err)}
+	}
+	roles := make([]ColumnRole, len(columns))
+	count := make(map[ColumnRole]int)
+	for i, name := range columns {
+		name = strings.TrimSpace(name)
+		columns[i] = name
+		role, ok := r.format.Roles[name]
+		if !ok {
+			role = r.format.Default
+		}
+		if role == "" {
+			role = RoleNumeric
+		}
+		roles[i] = role
+		count[role]++
+	}
+	for name := range r.format.Roles {
+		found := false
+		for _, column := range columns {
+			found = found || column == name
+		}
+		if !found {
+			return headerError{fmt.Errorf("header has no column %q", name)}
+		}
+	}
+	for _, role := range []ColumnRole{RoleLabel, RoleWeight, RoleQid} {
+		if count[role] > 1 {
+			return headerError{fmt.Errorf("header has %d %s columns, want at most one", count[role], role)}
+		}
+	}
+	if r.labeled && count[RoleLabel] == 0 {
+		return headerError{fmt.Errorf("header has no label column")}
+	}
+	r.columns, r.roles = columns, roles
+	return nil
+}
+
+// read parses a line into a record, or returns nil for the header.
+func (r *csvReader) read(line string) (*csvRecord, error) {
+	if r.roles == nil {
+		return nil, r.header(line)
+	}
+	cells, err := r.split(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(cells) != len(r.roles) {
+		return nil, fmt.Errorf("%d fields, the header has %d", len(cells), len(r.roles))
+	}
+	rec := &csvRecord{}
+	keys := []string{}
+	label := ""
+	for i, cell := range cells {
+		cell = strings.TrimSpace(cell)
+		switch r.roles[i] {
+		case RoleLabel:
+			label = cell
+		case RoleWeight:
+			if cell == "" {
+				continue
+			}
+			if rec.weight, err = parseWeight(cell); err != nil {
+				return nil, err
+			}
+		case RoleID:
+			keys = append(keys, cell)
+		case RoleQid:
+			rec.qid = cell
+		case RoleNumeric:
+			if cell == "" {
+				continue
+			}
+			value, err := strconv.ParseFloat(cell, 64)
+			if err != nil {
+				return nil, fmt.Errorf("column %q: bad value %q", r.columns[i], cell)
+			}
+			if value != 0 {
+				rec.features = append(rec.features, r.feature(r.columns[i], value))
+			}
+		case RoleCategorical:
+			if cell != "" {
+				rec.features = append(rec.features, r.feature(r.columns[i]+"="+cell, 1))
+			}
+		}
+	}
+	if r.labeled {
+		rec.label = label
+	} else if label != "" {
+		keys = append(keys, label)
+	}
+	rec.key = strings.Join(keys, "\t")
+	return rec, nil
+}
+
+// sample returns the classification sample of the record, with the global bias
+// feature first unless globalBiasFeatureID is negative.
+func (rec *csvRecord) sample(globalBiasFeatureID int64, labeled bool) (*Sample, error) {
+	sample := &Sample{Features: make([]Feature, 0, len(rec.features)+1), Key: rec.key, Qid: rec.qid, Weight: rec.weight}
+	if labeled {
+		label, err := strconv.Atoi(rec.label)
+		if err != nil {
+			return nil, fmt.Errorf("bad label %q", rec.label)
+		}
+		sample.Label = label
+	}
+	if globalBiasFeatureID >= 0 {
+		sample.Features = append(sample.Features, Feature{globalBiasFeatureID, 1.0})
+	}
+	sample.Features = append(sample.Features, rec.features...)
+	return sample, nil
+}
+
+// realSample returns the regression sample of the record, with the global bias feature
+// last unless globalBiasFeatureID is negative.
+func (rec *csvRecord) realSample(globalBiasFeatureID int64, labeled bool) (*RealSample, error) {
+	sample := &RealSample{Features: rec.features, Key: rec.key, Qid: rec.qid, Weight: rec.weight}
+	if labeled {
+		value, err := strconv.ParseFloat(rec.label, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad target value %q", rec.label)
+		}
+		sample.Value = value
+	}
+	if sample.Features == nil {
+		sample.Features = []Feature{}
+	}
+	if globalBiasFeatureID >= 0 {
+		sample.Features = append(sample.Features, Feature{globalBiasFeatureID, 1.0})
+	}
+	return sample, nil
+}
+
+// sampleParser returns the parser of the lines of a classification data set: CSV lines
+// read by format if it is not nil, and libsvm lines after keyColumns key columns
+// otherwise. The parser returns a nil sample for the header of a CSV file.
+func sampleParser(format *CSVFormat, keyColumns int, globalBiasFeatureID int64, labeled bool, feature func(name string, value float64) Feature) func(line string) (*Sample, error) {
+	if format != nil {
+		reader := format.reader(labeled, feature)
+		return func(line string) (*Sample, error) {
+			rec, err := reader.read(line)
+			if rec == nil {
+				return nil, err
+			}
+			return rec.sample(globalBiasFeatureID, labeled)
+		}
+	}
+	return func(line string) (*Sample, error) {
+		key, line, err := cutColumns(line, keyColumns)
+		if err != nil {
+			return nil, err
+		}
+		sample, err := parseSample(line, globalBiasFeatureID, labeled, feature)
+		if err != nil {
+			return nil, err
+		}
+		sample.Key = key
+		return sample, nil
+	}
+}
@@ -0,0 +1,109 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pantsing/hector/internal/utils"
+)
+
+func csvFormat(t *testing.T, comma rune, spec string) *CSVFormat {
+	f := &CSVFormat{Comma: comma}
+	if err := f.ParseColumnRoles(spec); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestLoadCSV(t *testing.T) {
+	path, cleanup := writeDataFile(t, "user,click,age,country,w,note\n"+
+		"u1,1,0.5,\"fr,paris\",2,x\n"+
+		"\n"+
+		"u2,0,0,us,,y\n")
+	defer cleanup()
+
+	d := NewDataSet()
+	d.CSV = csvFormat(t, ',', "user:id,click:label,country:categorical\nw:weight\n# comment\nnote:ignore")
+	if err := d.Load(path, -1); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Samples) != 2 {
+		t.Fatalf("loaded %d samples, want 2", len(d.Samples))
+	}
+	s := d.Samples[0]
+	if s.Label != 1 || s.Key != "u1" || s.Weight != 2 {
+		t.Errorf("first sample %+v", s)
+	}
+	want := []Feature{{utils.Hash("age"), 0.5}, {utils.Hash("country=fr,paris"), 1}}
+	if len(s.Features) != len(want) || s.Features[0] != want[0] || s.Features[1] != want[1] {
+		t.Errorf("features %v, want %v", s.Features, want)
+	}
+	// The age of 0 gives no feature and the empty weight is unset.
+	s = d.Samples[1]
+	if s.Label != 0 || s.GetWeight() != 1 || len(s.Features) != 1 || s.Features[0].Id != utils.Hash("country=us") {
+		t.Errorf("second sample %+v", s)
+	}
+	if d.FeatureNameIdMap[utils.Hash("country=us")] != "country=us" {
+		t.Error("categorical feature name not recorded")
+	}
+}
+
+func TestColumnRoles(t *testing.T) {
+	f := csvFormat(t, ',', "user:id,click:label\n*:categorical\nw:weight")
+	if got, want := f.ColumnRoles(), "click:label,user:id,w:weight,*:categorical"; got != want {
+		t.Errorf("ColumnRoles is %q, want %q", got, want)
+	}
+	parsed := csvFormat(t, ',', f.ColumnRoles())
+	if !reflect.DeepEqual(parsed, f) {
+		t.Errorf("parsed %+v, want %+v", parsed, f)
+	}
+}
+
+func TestLoadTSVUnlabeled(t *testing.T) {
+	path, cleanup := writeDataFile(t, "id\t3\ty\n7\t1.5\t2.5\n")
+	defer cleanup()
+
+	d := NewRealDataSet()
+	d.CSV = csvFormat(t, '\t', "id:id,y:label")
+	if err := d.LoadUnlabeled(path, 9); err != nil {
+		t.Fatal(err)
+	}
+	s := d.Samples[0]
+	// Numeric column names are feature IDs, and the label is kept after the ID.
+	if s.Key != "7\t2.5" || len(s.Features) != 2 || s.Features[0] != (Feature{3, 1.5}) || s.Features[1] != (Feature{9, 1}) {
+		t.Errorf("sample %+v", s)
+	}
+}
+
+func TestLoadCSVErrors(t *testing.T) {
+	for _, c := range []struct{ text, spec string }{
+		{"a,b\n1,2\n", "c:label"},
+		{"a,b\n1,2\n", "b:numeric"},
+		{"a,b\n1,2\n", "a:label,b:label"},
+	} {
+		path, cleanup := writeDataFile(t, c.text)
+		d := NewDataSet()
+		d.Mode = Lenient
+		d.CSV = csvFormat(t, ',', c.spec)
+		if err := d.Load(path, -1); err == nil {
+			t.Errorf("loading %q with %q returned no error", c.text, c.spec)
+		}
+		cleanup()
+	}
+
+	path, cleanup := writeDataFile(t, "a,b\n1,2\n1,x\n0,3,4\n")
+	defer cleanup()
+	d := NewDataSet()
+	d.Mode = Lenient
+	d.CSV = csvFormat(t, ',', "a:label")
+	if err := d.Load(path, -1); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Samples) != 1 || d.SkippedLines != 2 {
+		t.Errorf("loaded %d samples and skipped %d lines, want 1 and 2", len(d.Samples), d.SkippedLines)
+	}
+
+	if err := new(CSVFormat).ParseColumnRoles("a:label,b"); err == nil {
+		t.Error("ParseColumnRoles of a column without a role returned no error")
+	}
+}
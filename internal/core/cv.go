@@ -0,0 +1,209 @@
+package core
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// CVStrategy tells how cross validation splits a data set into folds.
+type CVStrategy string
+
+const (
+	// CVShuffle assigns the samples to folds at random.
+	CVShuffle CVStrategy = "shuffle"
+	// CVStratified assigns the samples to folds at random, keeping the share of every
+	// label, or of every range of target values of a RealDataSet, the same in all folds.
+	CVStratified CVStrategy = "stratified"
+	// CVGroup assigns all the samples of a group, named by their Key, to the same fold.
+	CVGroup CVStrategy = "group"
+	// CVTime splits the samples, in the order they were loaded, into Folds+1 blocks. The
+	// i-th fold tests on block i+1 after training on the blocks before it.
+	CVTime CVStrategy = "time"
+	// CVSequential assigns sample i to fold i%Folds.
+	CVSequential CVStrategy = "sequential"
+)
+
+// CVStrategies are the strategies ParseCVStrategy accepts.
+var CVStrategies = []CVStrategy{CVShuffle, CVStratified, CVGroup, CVTime, CVSequential}
+
+// ParseCVStrategy returns the strategy named name.
+func ParseCVStrategy(name string) (CVStrategy, error) {
+	for _, s := range CVStrategies {
+		if string(s) == name {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("unknown cross validation strategy %q, want shuffle, stratified, group, time or sequential", name)
+}
+
+// CrossValidation splits data sets into Folds pairs of train and test sets. The random
+// strategies draw from a source seeded with Seed, so equal seeds give equal folds.
+type CrossValidation struct {
+	Folds    int
+	Strategy CVStrategy
+	Seed     int64
+}
+
+// KeyColumns is the number of key columns the lines of data sets split by cv start
+// with: the group ID for CVGroup.
+func (cv *CrossValidation) KeyColumns() int {
+	if cv.Strategy == CVGroup {
+		return 1
+	}
+	return 0
+}
+
+// Split returns the train and test sets of every fold of d.
+func (cv *CrossValidation) Split(d *DataSet) ([][2]*DataSet, error) {
+	folds, err := cv.split(len(d.Samples),
+		func(i int) float64 { return float64(d.Samples[i].Label) },
+		func(i int) string { return d.Samples[i].Key })
+	if err != nil {
+		return nil, err
+	}
+	ret := make([][2]*DataSet, len(folds))
+	for f, fold := range folds {
+		ret[f] = [2]*DataSet{NewDataSet(), NewDataSet()}
+		for k, indices := range fold {
+			for _, i := range indices {
+				ret[f][k].AddSample(d.Samples[i])
+			}
+		}
+	}
+	return ret, nil
+}
+
+// SplitReal returns the train and test sets of every fold of d. CVStratified keeps
+// the distribution of the target values the same in all folds.
+func (cv *CrossValidation) SplitReal(d *RealDataSet) ([][2]*RealDataSet, error) {
+	folds, err := cv.split(len(d.Samples),
+		func(i int) float64 { return d.Samples[i].Value },
+		func(i int) string { return d.Samples[i].Key })
+	if err != nil {
+		return nil, err
+	}
+	ret := make([][2]*RealDataSet, len(folds))
+	for f, fold := range folds {
+		ret[f] = [2]*RealDataSet{NewRealDataSet(), NewRealDataSet()}
+		for k, indices := range fold {
+			for _, i := range indices {
+				ret[f][k].AddSample(d.Samples[i])
+			}
+		}
+	}
+	return ret, nil
+}
+
+// split returns the indices of the train and the test samples of every fold of n
+// samples, given the label and the group of sample i.
+func (cv *CrossValidation) split(n int, label func(int) float64, group func(int) string) ([][2][]int, error) {
+	k := cv.Folds
+	if k < 2 {
+		return nil, fmt.Errorf("%d cross validation folds, want at least 2", k)
+	}
+	if cv.Strategy == CVTime {
+		return forwardChaining(n, k)
+	}
+	if n < k {
+		return nil, fmt.Errorf("%d samples for %d cross validation folds", n, k)
+	}
+
+	r := rand.New(rand.NewSource(cv.Seed))
+	fold := make([]int, n)
+	switch cv.Strategy {
+	case CVShuffle:
+		for j, i := range r.Perm(n) {
+			fold[i] = j % k
+		}
+	case CVStratified:
+		// Sorting by label deals every label, or every run of k close target values,
+		// round robin to the folds. Ties are in random order.
+		order := r.Perm(n)
+		sort.SliceStable(order, func(a, b int) bool { return label(order[a]) < label(order[b]) })
+		offset := r.Intn(k)
+		for j, i := range order {
+			fold[i] = (j + offset) % k
+		}
+	case CVGroup:
+		if err := groupFolds(fold, k, group, r); err != nil {
+			return nil, err
+		}
+	case CVSequential:
+		for i := range fold {
+			fold[i] = i % k
+		}
+	default:
+		return nil, fmt.Errorf("unknown cross validation strategy %q", cv.Strategy)
+	}
+
+	ret := make([][2][]int, k)
+	for i, f := range fold {
+		for part := range ret {
+			if part == f {
+				ret[part][1] = append(ret[part][1], i)
+			} else {
+				ret[part][0] = append(ret[part][0], i)
+			}
+		}
+	}
+	return ret, nil
+}
+
+// groupFolds assigns the groups, in random order from the largest, to the fold with the
+// fewest samples so far.
+func groupFolds(fold []int, k int, group func(int) string, r *rand.Rand) error {
+	members := make(map[string][]int)
+	for i := range fold {
+		g := group(i)
+		if g == "" {
+			return fmt.Errorf("sample %d has no group", i+1)
+		}
+		members[g] = append(members[g], i)
+	}
+	if len(members) < k {
+		return fmt.Errorf("%d groups for %d cross validation folds", len(members), k)
+	}
+	groups := make([]string, 0, len(members))
+	for g := range members {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+	r.Shuffle(len(groups), func(i, j int) { groups[i], groups[j] = groups[j], groups[i] })
+	sort.SliceStable(groups, func(i, j int) bool { return len(members[groups[i]]) > len(members[groups[j]]) })
+
+	sizes := make([]int, k)
+	for _, g := range groups {
+		smallest := 0
+		for f := range sizes {
+			if sizes[f] < sizes[smallest] {
+				smallest = f
+			}
+		}
+		for _, i := range members[g] {
+			fold[i] = smallest
+		}
+		sizes[smallest] += len(members[g])
+	}
+	return nil
+}
+
+// forwardChaining splits n samples into k+1 blocks of consecutive samples. Fold i trains
+// on blocks 0 to i and tests on block i+1.
+func forwardChaining(n, k int) ([][2][]int, error) {
+	if n < k+1 {
+		return nil, fmt.Errorf("%d samples for %d time series folds, want at least %d", n, k, k+1)
+	}
+	ret := make([][2][]int, k)
+	for f := range ret {
+		end, testEnd := (f+1)*n/(k+1), (f+2)*n/(k+1)
+		for i := 0; i < testEnd; i++ {
+			if i < end {
+				ret[f][0] = append(ret[f][0], i)
+			} else {
+				ret[f][1] = append(ret[f][1], i)
+			}
+		}
+	}
+	return ret, nil
+}
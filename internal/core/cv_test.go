@@ -0,0 +1,82 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestCrossValidationSplit(t *testing.T) {
+	// 60 samples sorted by label, in 12 groups of 5.
+	d := NewDataSet()
+	for i := 0; i < 60; i++ {
+		sample := NewSample()
+		if i >= 40 {
+			sample.Label = 1
+		}
+		sample.Key = fmt.Sprint("g", i/5)
+		sample.AddFeature(Feature{Id: int64(i), Value: 1})
+		d.AddSample(sample)
+	}
+	ids := func(d *DataSet) []int64 {
+		ret := []int64{}
+		for _, sample := range d.Samples {
+			ret = append(ret, sample.Features[0].Id)
+		}
+		return ret
+	}
+
+	for _, strategy := range []CVStrategy{CVShuffle, CVStratified, CVGroup, CVSequential} {
+		cv := &CrossValidation{Folds: 4, Strategy: strategy, Seed: 7}
+		folds, err := cv.Split(d)
+		if err != nil {
+			t.Fatalf("%s: %v", strategy, err)
+		}
+		again, _ := cv.Split(d)
+		tested := make(map[int64]int)
+		for f, fold := range folds {
+			if len(fold[0].Samples)+len(fold[1].Samples) != 60 {
+				t.Errorf("%s: fold %d has %d train and %d test samples", strategy, f, len(fold[0].Samples), len(fold[1].Samples))
+			}
+			if !reflect.DeepEqual(ids(fold[1]), ids(again[f][1])) {
+				t.Errorf("%s: fold %d differs with the same seed", strategy, f)
+			}
+			positives := 0
+			groups := make(map[string]bool)
+			for _, sample := range fold[1].Samples {
+				tested[sample.Features[0].Id]++
+				positives += sample.Label
+				groups[sample.Key] = true
+			}
+			if strategy == CVStratified && positives != 5 {
+				t.Errorf("stratified fold %d tests on %d positive samples, want 5", f, positives)
+			}
+			if strategy == CVGroup {
+				for _, sample := range fold[0].Samples {
+					if groups[sample.Key] {
+						t.Errorf("group fold %d trains and tests on group %s", f, sample.Key)
+					}
+				}
+			}
+		}
+		if len(tested) != 60 {
+			t.Errorf("%s: %d samples are tested, want 60", strategy, len(tested))
+		}
+	}
+
+	cv := &CrossValidation{Folds: 3, Strategy: CVTime}
+	folds, err := cv.Split(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for f, fold := range folds {
+		train, test := ids(fold[0]), ids(fold[1])
+		if len(train) != 15*(f+1) || len(test) != 15 || train[len(train)-1] >= test[0] {
+			t.Errorf("time fold %d trains on %v and tests on %v", f, train, test)
+		}
+	}
+
+	if _, err = (&CrossValidation{Folds: 13, Strategy: CVGroup}).Split(d); err == nil {
+		t.Error("Split into more folds than groups returned no error")
+	}
+}
@@ -1,7 +1,6 @@
 package core
 
 import (
-	"bufio"
 	"fmt"
 	"log"
 	"os"
@@ -10,7 +9,6 @@ import (
 	"strings"
 
 	"github.com/pantsing/hector/internal/utils"
-	"io"
 )
 
 type CombinedFeature []string
@@ -25,6 +23,9 @@ func FindCategory(split []float64, value float64) int {
 type RawDataSet struct {
 	Samples     []*RawSample
 	FeatureKeys map[string]bool
+	// Mode tells Load what to do with malformed lines, which it counts in SkippedLines.
+	Mode         ParseMode
+	SkippedLines int
 }
 
 func NewRawDataSet() *RawDataSet {
@@ -43,7 +44,6 @@ func (d *RawDataSet) AddSample(sample *RawSample) {
 
 func (d *RawDataSet) ToDataSet(splits map[string][]float64, combinations []CombinedFeature) *DataSet {
 	out_data := NewDataSet()
-	fm := make(map[string]int64)
 	for _, sample := range d.Samples {
 		out_sample := NewSample()
 		out_sample.Label = sample.Label
@@ -60,7 +60,7 @@ func (d *RawDataSet) ToDataSet(splits map[string][]float64, combinations []Combi
 					} else {
 						fvalue = utils.ParseFloat64(fvalue_str)
 					}
-					fm[fkey] = utils.Hash(fkey)
+					out_data.FeatureNameIdMap[utils.Hash(fkey)] = fkey
 					out_sample.AddFeature(Feature{Id: utils.Hash(fkey), Value: fvalue})
 				}
 			}
@@ -73,54 +73,25 @@ func (d *RawDataSet) ToDataSet(splits map[string][]float64, combinations []Combi
 				fkey += sample.GetFeatureValue(ckey)
 				fkey += "_"
 			}
-			fm[fkey] = utils.Hash(fkey)
+			out_data.FeatureNameIdMap[utils.Hash(fkey)] = fkey
 			out_sample.AddFeature(Feature{Id: utils.Hash(fkey), Value: 1.0})
 		}
 		out_data.AddSample(out_sample)
 	}
-	f, _ := os.Create("features.tsv")
-	defer f.Close()
-	w := bufio.NewWriter(f)
-	for k, v := range fm {
-		w.WriteString(k + "\t" + strconv.FormatInt(v, 10) + "\n")
-	}
-
 	return out_data
 }
 
-func (d *RawDataSet) Load(path string) error {
-	file, err := os.Open(path)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-	ch := make(chan string, 1000)
-	go func() {
-		reader := bufio.NewReader(file)
-		for {
-			line, err := reader.ReadString('\n')
-			if err != nil {
-				break
-			}
-			ch <- line
-		}
-		close(ch)
-	}()
-
+func (d *RawDataSet) Load(path string) (err error) {
 	n := 0
-	for line := range ch {
-		n += 1
-		if n%10000 == 0 {
-			fmt.Println(n, len(ch))
-		}
+	parse := func(line string) error {
 		line = strings.Replace(line, " ", "\t", -1)
-		tks := strings.Split(line, "\t")
+		tks := strings.Split(strings.TrimSpace(line), "\t")
 		sample := NewRawSample()
 		for i, tk := range tks {
 			if i == 0 {
 				label, err := strconv.ParseInt(tk, 10, 16)
 				if err != nil {
-					break
+					return fmt.Errorf("bad label %q", tk)
 				}
 				if label > 0 {
 					sample.Label = 1.0
@@ -128,19 +99,50 @@ func (d *RawDataSet) Load(path string) error {
 					sample.Label = 0.0
 				}
 			} else {
-				kv := strings.Split(tk, ":")
+				if tk == "" {
+					continue
+				}
+				kv := strings.SplitN(tk, ":", 2)
+				if len(kv) != 2 {
+					return fmt.Errorf("feature %q has no value", tk)
+				}
 				sample.Features[kv[0]] = kv[1]
 				d.FeatureKeys[kv[0]] = true
 			}
 		}
 		d.AddSample(sample)
+		n += 1
+		if n%10000 == 0 {
+			fmt.Println(n)
+		}
+		return nil
 	}
-	return nil
+	d.SkippedLines, err = readPath(path, d.Mode, func() func(line string) error { return parse })
+	return err
 }
 
 /*Streaming*/
 type StreamingDataSet struct {
 	Samples chan *Sample
+	// Mode tells Load what to do with malformed lines, which it counts in SkippedLines.
+	Mode         ParseMode
+	SkippedLines int
+	// Unlabeled tells that the lines hold no label before their features.
+	Unlabeled bool
+	// KeyColumns is the number of columns starting every line, such as an ID, which are
+	// kept in the Key of its sample instead of being parsed.
+	KeyColumns int
+	// CSV, if set, is the format of the CSV or TSV file Load reads instead of libsvm
+	// lines. KeyColumns is then ignored.
+	CSV *CSVFormat
+	// Sampler, if set, drops the samples it does not keep.
+	Sampler *NegativeSampler
+	// Hasher hashes the feature names, see Hasher.
+	Hasher *Hasher
+	// FeatureNameIdMap, if set, records the names of the features loaded by their IDs,
+	// and HashStats counts them. They are read once Load returns.
+	FeatureNameIdMap map[int64]string
+	HashStats        HashStats
 }
 
 func NewStreamingDataSet() *StreamingDataSet {
@@ -156,72 +158,104 @@ func (d *StreamingDataSet) AddSample(sample *Sample) {
 	d.Samples <- sample
 }
 
-func (d *StreamingDataSet) Load(path string, globalBiasFeatureID int64) error {
-	file, err := os.Open(path)
-	defer file.Close()
+// Load sends the samples of the files at path, see InputFiles, to d.Samples, and closes
+// it when done.
+func (d *StreamingDataSet) Load(path string, globalBiasFeatureID int64) (err error) {
+	defer close(d.Samples)
+	if IsCache(path) {
+		var names map[int64]string
+		names, err = readCache(path, d.Hasher, globalBiasFeatureID, true, func(s *cacheSample) {
+			sample := s.sample(!d.Unlabeled)
+			if d.Sampler == nil || d.Sampler.Keep(sample) {
+				d.AddSample(sample)
+			}
+		})
+		d.addNames(names)
+		return
+	}
+	d.SkippedLines, err = readPath(path, d.Mode, d.parser(globalBiasFeatureID))
+	if err == nil {
+		d.logHashStats(path)
+	}
+	return
+}
+
+// LoadFromStdIn sends the samples read from stdin, which may be compressed, to
+// d.Samples, and closes it when done.
+func (d *StreamingDataSet) LoadFromStdIn(globalBiasFeatureID int64) (err error) {
+	defer close(d.Samples)
+	r, err := decompress(os.Stdin, "<stdin>")
 	if err != nil {
-		log.Fatalln("load file fail: ", err)
+		return fmt.Errorf("<stdin>: %v", err)
 	}
-	reader := bufio.NewReader(file)
-	for {
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			break
-		}
-		sample := d.parser(line, globalBiasFeatureID)
-		d.AddSample(sample)
+	defer r.Close()
+	d.SkippedLines, err = readLines(r, "<stdin>", d.Mode, d.parser(globalBiasFeatureID)())
+	if err == nil {
+		d.logHashStats("<stdin>")
 	}
-	close(d.Samples)
-	return nil
+	return
 }
 
-func (d *StreamingDataSet) LoadFromStdIn(globalBiasFeatureID int64) error {
-	reader := bufio.NewReader(os.Stdin)
-	for {
-		line, err := reader.ReadString('\n')
-		if err == io.EOF {
-			break
+func (d *StreamingDataSet) parser(globalBiasFeatureID int64) func() func(line string) error {
+	feature := d.Hasher.features(d.FeatureNameIdMap, &d.HashStats, globalBiasFeatureID)
+	return func() func(line string) error {
+		parse := sampleParser(d.CSV, d.KeyColumns, globalBiasFeatureID, !d.Unlabeled, feature)
+		return func(line string) error {
+			sample, err := parse(line)
+			if sample == nil {
+				return err
+			}
+			if d.Sampler == nil || d.Sampler.Keep(sample) {
+				d.AddSample(sample)
+			}
+			return nil
 		}
-		sample := d.parser(line, globalBiasFeatureID)
-		d.AddSample(sample)
 	}
-	close(d.Samples)
-	return nil
 }
 
-func (d *StreamingDataSet) parser(line string, globalBiasFeatureID int64) (sample *Sample) {
-	tks := strings.Split(strings.TrimSpace(line), "\t")
-	sample = &Sample{Features: make([]Feature, 0, 20), Label: 0}
-	if globalBiasFeatureID >= 0 {
-		sample.Features = append(sample.Features, Feature{globalBiasFeatureID, 1.0})
+// logHashStats logs the HashStats of the text read from path, which are only counted
+// if FeatureNameIdMap is set.
+func (d *StreamingDataSet) logHashStats(path string) {
+	if d.FeatureNameIdMap != nil {
+		logHashStats(path, d.HashStats)
 	}
-	for i, tk := range tks {
-		if i == 0 {
-			label, _ := strconv.Atoi(tk)
-			sample.Label = label
-		} else {
-			if strings.TrimSpace(tk) == "" {
-				continue
-			}
-			kv := strings.Split(tk, ":")
-			feature_id, err := strconv.ParseInt(kv[0], 10, 64)
-			if err != nil {
-				log.Println("wrong feature: ", tk)
-				return nil
-			}
-			feature_value := 1.0
-			if len(kv) > 1 {
-				feature_value, err = strconv.ParseFloat(kv[1], 64)
-				if err != nil {
-					log.Println("wrong value: ", tk)
-					return nil
-				}
-			}
-			feature := Feature{feature_id, feature_value}
-			sample.Features = append(sample.Features, feature)
+}
+
+func (d *StreamingDataSet) addNames(names map[int64]string) {
+	if d.FeatureNameIdMap == nil {
+		return
+	}
+	for id, name := range names {
+		d.FeatureNameIdMap[id] = name
+	}
+}
+
+// FeatureDict returns the dictionary of the hashed features of d, empty unless
+// FeatureNameIdMap is set.
+func (d *StreamingDataSet) FeatureDict() FeatureDict {
+	return NewFeatureDict(d.FeatureNameIdMap)
+}
+
+// cutColumns splits the first n columns of line, separated by blanks, from the rest.
+// They are returned joined by tabs.
+func cutColumns(line string, n int) (columns, rest string, err error) {
+	rest = line
+	for i := 0; i < n; i++ {
+		rest = strings.TrimLeft(rest, " \t")
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			end = len(rest)
+		}
+		if end == 0 {
+			return "", "", fmt.Errorf("want %d columns before the features", n)
 		}
+		if i > 0 {
+			columns += "\t"
+		}
+		columns += rest[:end]
+		rest = rest[end:]
 	}
-	return sample
+	return columns, rest, nil
 }
 
 /* DataSet */
@@ -229,6 +263,19 @@ type DataSet struct {
 	Samples          []*Sample
 	FeatureNameIdMap map[int64]string
 	max_label        int
+	// Mode tells Load what to do with malformed lines, which it counts in SkippedLines.
+	Mode         ParseMode
+	SkippedLines int
+	// KeyColumns is the number of columns starting every line, such as the group ID of
+	// group cross validation, which are kept in the Key of its sample.
+	KeyColumns int
+	// CSV, if set, is the format of the CSV or TSV file Load reads instead of libsvm
+	// lines. KeyColumns is then ignored.
+	CSV *CSVFormat
+	// Hasher hashes the feature names, see Hasher. HashStats counts the names Load
+	// recorded in FeatureNameIdMap.
+	Hasher    *Hasher
+	HashStats HashStats
 }
 
 func NewDataSet() *DataSet {
@@ -246,74 +293,61 @@ func (d *DataSet) AddSample(sample *Sample) {
 }
 
 func (d *DataSet) Load(path string, globalBiasFeatureID int64) error {
-	fm := make(map[string]int64)
-
-	ch := make(chan string, 1000)
-	go func() {
-		file, err := os.Open(path)
-		defer file.Close()
-		defer close(ch)
-		if err != nil {
-			log.Println("load file fail: ", err)
-			return
-		}
+	return d.load(path, globalBiasFeatureID, true)
+}
 
-		scanner := bufio.NewScanner(file)
+// LoadUnlabeled loads a data set whose lines hold features only, as scored by predict.
+// Labels of the loaded samples are 0.
+func (d *DataSet) LoadUnlabeled(path string, globalBiasFeatureID int64) error {
+	return d.load(path, globalBiasFeatureID, false)
+}
 
-		for scanner.Scan() {
-			line := strings.Replace(scanner.Text(), " ", "\t", -1)
-			ch <- line
+func (d *DataSet) load(path string, globalBiasFeatureID int64, labeled bool) (err error) {
+	if IsCache(path) {
+		names, err := readCache(path, d.Hasher, globalBiasFeatureID, true, func(s *cacheSample) {
+			d.AddSample(s.sample(labeled))
+		})
+		if err != nil {
+			return err
 		}
-	}()
-
-	for line := range ch {
-		tks := strings.Split(strings.TrimSpace(line), "\t")
-		sample := Sample{Features: make([]Feature, 0, 20), Label: 0}
-		if globalBiasFeatureID >= 0 {
-			sample.Features = append(sample.Features, Feature{globalBiasFeatureID, 1.0})
+		for id, name := range names {
+			d.FeatureNameIdMap[id] = name
 		}
-		for i, tk := range tks {
-			if i == 0 {
-				label, _ := strconv.Atoi(tk)
-				sample.Label = label
-				if d.max_label < label {
-					d.max_label = label
-				}
-			} else {
-				kv := strings.Split(tk, ":")
-				feature_id, err := strconv.ParseInt(kv[0], 10, 64)
-				if err != nil {
-					feature_id = utils.Hash(kv[0])
-					fm[kv[0]] = feature_id
-				}
-				d.FeatureNameIdMap[feature_id] = kv[0]
-				feature_value := 1.0
-				if len(kv) > 1 {
-					feature_value, err = strconv.ParseFloat(kv[1], 64)
-					if err != nil {
-						break
-					}
-				}
-				feature := Feature{feature_id, feature_value}
-				sample.Features = append(sample.Features, feature)
+		log.Println("dataset size : ", len(d.Samples))
+		return nil
+	}
+	feature := d.Hasher.features(d.FeatureNameIdMap, &d.HashStats, globalBiasFeatureID)
+	d.SkippedLines, err = readPath(path, d.Mode, func() func(line string) error {
+		parse := sampleParser(d.CSV, d.KeyColumns, globalBiasFeatureID, labeled, feature)
+		return func(line string) error {
+			sample, err := parse(line)
+			if sample == nil {
+				return err
 			}
+			d.AddSample(sample)
+			return nil
 		}
-		//if globalBiasFeatureID >= 0 {
-		//	sample.Features = append(sample.Features, Feature{globalBiasFeatureID, 1.0})
-		//}
-		d.AddSample(&sample)
-	}
-	f, _ := os.Create("features.tsv")
-	defer f.Close()
-	w := bufio.NewWriter(f)
-	for k, v := range fm {
-		w.WriteString(k + "\t" + strconv.FormatInt(v, 10) + "\n")
+	})
+	if err != nil {
+		return err
 	}
-
 	log.Println("dataset size : ", len(d.Samples))
+	logHashStats(path, d.HashStats)
 	return nil
 }
 
+// logHashStats logs stats of the data set read from path if it had hashed names.
+func logHashStats(path string, stats HashStats) {
+	if stats.Names > 0 {
+		log.Printf("%s: %v", path, stats)
+	}
+}
+
+// FeatureDict returns the dictionary of the hashed features of d.
+func (d *DataSet) FeatureDict() FeatureDict {
+	return NewFeatureDict(d.FeatureNameIdMap)
+}
+
 func RemoveLowFreqFeatures(dataset *DataSet, threshold float64) {
 	freq := NewVector()
 
@@ -344,6 +378,33 @@ func (d *DataSet) Split(f func(int) bool) *DataSet {
 	return out_data
 }
 
+// Clone returns a data set of copies of the samples of d, which algorithms writing to
+// the samples they train on may use while d is used elsewhere.
+func (d *DataSet) Clone() *DataSet {
+	ret := NewDataSet()
+	for _, sample := range d.Samples {
+		ret.AddSample(sample.Clone())
+	}
+	return ret
+}
+
+// Queries returns the samples grouped by Qid, in the order the queries first appear.
+// Samples without a Qid form one query.
+func (d *DataSet) Queries() [][]*Sample {
+	index := make(map[string]int)
+	ret := [][]*Sample{}
+	for _, sample := range d.Samples {
+		i, ok := index[sample.Qid]
+		if !ok {
+			i = len(ret)
+			index[sample.Qid] = i
+			ret = append(ret, nil)
+		}
+		ret[i] = append(ret[i], sample)
+	}
+	return ret
+}
+
 func (d *DataSet) CVSplit(cvTotal, cvPart int) (trainSet *DataSet, testSet *DataSet) {
 	trainSet = NewDataSet()
 	testSet = NewDataSet()
@@ -361,11 +422,26 @@ func (d *DataSet) CVSplit(cvTotal, cvPart int) (trainSet *DataSet, testSet *Data
 /* Real valued DataSet */
 type RealDataSet struct {
 	Samples []*RealSample
+	// FeatureNameIdMap holds the names of the features by their IDs, which Hasher
+	// hashes, or folds if they are integers, and HashStats counts.
+	FeatureNameIdMap map[int64]string
+	Hasher           *Hasher
+	HashStats        HashStats
+	// Mode tells Load what to do with malformed lines, which it counts in SkippedLines.
+	Mode         ParseMode
+	SkippedLines int
+	// KeyColumns is the number of columns starting every line, which are kept in the
+	// Key of its sample.
+	KeyColumns int
+	// CSV, if set, is the format of the CSV or TSV file Load reads instead of libsvm
+	// lines. KeyColumns is then ignored.
+	CSV *CSVFormat
 }
 
 func NewRealDataSet() *RealDataSet {
 	ret := RealDataSet{}
 	ret.Samples = []*RealSample{}
+	ret.FeatureNameIdMap = make(map[int64]string)
 	return &ret
 }
 
@@ -374,48 +450,105 @@ func (d *RealDataSet) AddSample(sample *RealSample) {
 }
 
 func (d *RealDataSet) Load(path string, globalBiasFeatureID int64) error {
-	file, err := os.Open(path)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
+	return d.load(path, globalBiasFeatureID, true)
+}
 
-	scanner := bufio.NewScanner(file)
+// LoadUnlabeled loads a data set whose lines hold features only, as scored by predict.
+func (d *RealDataSet) LoadUnlabeled(path string, globalBiasFeatureID int64) error {
+	return d.load(path, globalBiasFeatureID, false)
+}
 
-	for scanner.Scan() {
-		line := strings.Replace(scanner.Text(), " ", "\t", -1)
-		tks := strings.Split(line, "\t")
-		sample := RealSample{Features: []Feature{}, Value: 0.0}
+func (d *RealDataSet) load(path string, globalBiasFeatureID int64, labeled bool) (err error) {
+	if IsCache(path) {
+		names, err := readCache(path, d.Hasher, globalBiasFeatureID, false, func(s *cacheSample) {
+			d.AddSample(s.realSample(labeled))
+		})
+		for id, name := range names {
+			d.FeatureNameIdMap[id] = name
+		}
+		return err
+	}
+	if d.CSV != nil {
+		feature := d.Hasher.features(d.FeatureNameIdMap, &d.HashStats, globalBiasFeatureID)
+		d.SkippedLines, err = readPath(path, d.Mode, func() func(line string) error {
+			reader := d.CSV.reader(labeled, feature)
+			return func(line string) error {
+				rec, err := reader.read(line)
+				if rec == nil {
+					return err
+				}
+				sample, err := rec.realSample(globalBiasFeatureID, labeled)
+				if err != nil {
+					return err
+				}
+				d.AddSample(sample)
+				return nil
+			}
+		})
+		if err == nil {
+			logHashStats(path, d.HashStats)
+		}
+		return err
+	}
+	feature := d.Hasher.features(d.FeatureNameIdMap, &d.HashStats, globalBiasFeatureID)
+	parse := func(line string) error {
+		key, line, err := cutColumns(line, d.KeyColumns)
+		if err != nil {
+			return err
+		}
+		line = strings.Replace(line, " ", "\t", -1)
+		tks := strings.Split(strings.TrimSpace(line), "\t")
+		sample := RealSample{Features: []Feature{}, Value: 0.0, Key: key}
 		for i, tk := range tks {
-			if i == 0 {
-				value := utils.ParseFloat64(tk)
+			if i == 0 && labeled {
+				value, err := strconv.ParseFloat(tk, 64)
+				if err != nil {
+					return fmt.Errorf("bad target value %q", tk)
+				}
 				sample.Value = value
 			} else {
-				kv := strings.Split(tk, ":")
-				feature_id, err := strconv.ParseInt(kv[0], 10, 64)
-				if err != nil {
-					break
+				if tk == "" {
+					continue
+				}
+				if strings.HasPrefix(tk, "qid:") {
+					if sample.Qid = tk[len("qid:"):]; sample.Qid == "" {
+						return fmt.Errorf("empty qid")
+					}
+					continue
 				}
+				if strings.HasPrefix(tk, "weight:") {
+					if sample.Weight, err = parseWeight(tk); err != nil {
+						return err
+					}
+					continue
+				}
+				kv := strings.Split(tk, ":")
 				feature_value := 1.0
 				if len(kv) > 1 {
 					feature_value, err = strconv.ParseFloat(kv[1], 64)
 					if err != nil {
-						break
+						return fmt.Errorf("wrong value %q", tk)
 					}
 				}
-				feature := Feature{feature_id, feature_value}
-				sample.Features = append(sample.Features, feature)
+				sample.Features = append(sample.Features, feature(kv[0], feature_value))
 			}
 		}
 		if globalBiasFeatureID >= 0 {
 			sample.Features = append(sample.Features, Feature{globalBiasFeatureID, 1.0})
 		}
 		d.AddSample(&sample)
+		return nil
 	}
-	if scanner.Err() != nil {
-		return scanner.Err()
+	d.SkippedLines, err = readPath(path, d.Mode, func() func(line string) error { return parse })
+	if err == nil {
+		logHashStats(path, d.HashStats)
 	}
-	return nil
+	return err
+}
+
+// FeatureDict returns the dictionary of the hashed features of d.
+func (d *RealDataSet) FeatureDict() FeatureDict {
+	return NewFeatureDict(d.FeatureNameIdMap)
 }
 
 func (d *RealDataSet) CVSplit(cvTotal, cvPart int) (trainSet *RealDataSet, testSet *RealDataSet) {
@@ -426,7 +559,7 @@ func (d *RealDataSet) CVSplit(cvTotal, cvPart int) (trainSet *RealDataSet, testS
 		if i%cvTotal == cvPart {
 			testSet.AddSample(sample)
 		} else {
-			testSet.AddSample(sample)
+			trainSet.AddSample(sample)
 		}
 	}
 	return
@@ -0,0 +1,147 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeDataFile(t *testing.T, text string) (string, func()) {
+	dir, err := ioutil.TempDir("", "hector-core")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "data.tsv")
+	if err = ioutil.WriteFile(path, []byte(text), 0600); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestDataSetLoadMissingFile(t *testing.T) {
+	if err := NewDataSet().Load("/nonexistent/hector/data.tsv", -1); err == nil {
+		t.Error("Load of a missing file returned no error")
+	}
+}
+
+func TestRealDataSetLoadModes(t *testing.T) {
+	path, cleanup := writeDataFile(t, "1.5\t1:1\n\n2.5\t2:x\nabc\t1:1\n3.5\t3:1\n")
+	defer cleanup()
+
+	strict := NewRealDataSet()
+	err := strict.Load(path, -1)
+	lineErr, ok := err.(*LineError)
+	if !ok {
+		t.Fatalf("strict Load returned %v, want a *LineError", err)
+	}
+	if lineErr.Path != path || lineErr.Line != 3 {
+		t.Errorf("strict Load failed at %s:%d, want %s:3", lineErr.Path, lineErr.Line, path)
+	}
+
+	lenient := NewRealDataSet()
+	lenient.Mode = Lenient
+	if err = lenient.Load(path, -1); err != nil {
+		t.Fatal(err)
+	}
+	if len(lenient.Samples) != 2 || lenient.SkippedLines != 2 {
+		t.Errorf("lenient Load kept %d samples and skipped %d lines, want 2 and 2", len(lenient.Samples), lenient.SkippedLines)
+	}
+}
+
+func TestStreamingDataSetKeyColumns(t *testing.T) {
+	path, cleanup := writeDataFile(t, "a 1\t1:0.5 2:1\nb\t0 3:1\nc\n")
+	defer cleanup()
+
+	d := NewStreamingDataSet()
+	d.Mode = Lenient
+	d.Unlabeled = true
+	d.KeyColumns = 2
+	if err := d.Load(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	var samples []*Sample
+	for sample := range d.Samples {
+		samples = append(samples, sample)
+	}
+	if len(samples) != 2 || d.SkippedLines != 1 {
+		t.Fatalf("Load streamed %d samples and skipped %d lines, want 2 and 1", len(samples), d.SkippedLines)
+	}
+	if samples[0].Key != "a\t1" || len(samples[0].Features) != 3 || samples[0].Features[1] != (Feature{1, 0.5}) {
+		t.Errorf("first sample is %+v", samples[0])
+	}
+	if samples[1].Key != "b\t0" || len(samples[1].Features) != 2 || samples[1].Features[1].Id != 3 {
+		t.Errorf("second sample is %+v", samples[1])
+	}
+}
+
+func TestParseSampleQid(t *testing.T) {
+	sample, err := ParseSample("1 qid:42 1:0.5 2:1", -1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sample.Qid != "42" || len(sample.Features) != 2 || sample.Label != 1 {
+		t.Errorf("parsed %+v", sample)
+	}
+	if _, err = ParseSample("1 qid: 1:0.5", -1, true); err == nil {
+		t.Error("ParseSample of an empty qid returned no error")
+	}
+}
+
+func TestRealDataSetLoadQid(t *testing.T) {
+	path, cleanup := writeDataFile(t, "1.5 qid:42 weight:2 1:0.5 2:1\n0.5 qid:43 3:1\n")
+	defer cleanup()
+	d := NewRealDataSet()
+	if err := d.Load(path, -1); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Samples) != 2 {
+		t.Fatalf("loaded %d samples, want 2", len(d.Samples))
+	}
+	if s := d.Samples[0]; s.Qid != "42" || s.Weight != 2 || s.Value != 1.5 || len(s.Features) != 2 {
+		t.Errorf("first sample %+v", s)
+	}
+	if d.Samples[1].Qid != "43" {
+		t.Errorf("second sample %+v", d.Samples[1])
+	}
+
+	// The cache keeps the queries.
+	cache := filepath.Join(filepath.Dir(path), "data.hc")
+	if err := d.WriteCache(cache); err != nil {
+		t.Fatal(err)
+	}
+	cached := NewRealDataSet()
+	if err := cached.Load(cache, -1); err != nil {
+		t.Fatal(err)
+	}
+	if cached.Samples[0].Qid != "42" || cached.Samples[1].Qid != "43" {
+		t.Errorf("cached samples %+v %+v", cached.Samples[0], cached.Samples[1])
+	}
+
+	empty := filepath.Join(filepath.Dir(path), "empty.tsv")
+	if err := ioutil.WriteFile(empty, []byte("1.5 qid: 1:0.5\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewRealDataSet().Load(empty, -1); err == nil {
+		t.Error("Load of an empty qid returned no error")
+	}
+}
+
+func TestParseSampleWeight(t *testing.T) {
+	sample, err := ParseSample("1 weight:2.5 1:0.5", -1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sample.GetWeight() != 2.5 || len(sample.Features) != 1 {
+		t.Errorf("parsed %+v", sample)
+	}
+	if sample, _ = ParseSample("1 1:0.5", -1, true); sample.GetWeight() != 1 {
+		t.Errorf("weight of a sample without a weight token = %v, want 1", sample.GetWeight())
+	}
+	for _, line := range []string{"1 weight:0 1:0.5", "1 weight:-1 1:0.5", "1 weight:x 1:0.5"} {
+		if _, err = ParseSample(line, -1, true); err == nil {
+			t.Errorf("ParseSample(%q) returned no error", line)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+FeatureDict maps the IDs of hashed features back to the names they were hashed from, see
+FeatureID. Features named by integers are their own IDs and are left out. Where two names
+hash to the same ID, the first one added is kept.
+
+Dictionaries are written as lines of an ID and a name separated by a tab, sorted by ID.
+*/
+type FeatureDict map[int64]string
+
+// NewFeatureDict returns the dictionary of the hashed features among names, a map of
+// feature IDs to names such as the FeatureNameIdMap of a data set.
+func NewFeatureDict(names map[int64]string) FeatureDict {
+	d := make(FeatureDict)
+	for id, name := range names {
+		d.Add(name, id)
+	}
+	return d
+}
+
+// Add records the name of the feature of ID id if the name is hashed.
+func (d FeatureDict) Add(name string, id int64) {
+	if _, hashed := FeatureID(name); !hashed {
+		return
+	}
+	if _, ok := d[id]; !ok {
+		d[id] = name
+	}
+}
+
+// Name returns the name of the feature of ID id, or the ID if it is not in d.
+func (d FeatureDict) Name(id int64) string {
+	if name, ok := d[id]; ok {
+		return name
+	}
+	return strconv.FormatInt(id, 10)
+}
+
+// Write writes d to w.
+func (d FeatureDict) Write(w io.Writer) error {
+	ids := make([]int64, 0, len(d))
+	for id := range d {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	bw := bufio.NewWriter(w)
+	for _, id := range ids {
+		bw.WriteString(strconv.FormatInt(id, 10))
+		bw.WriteByte('\t')
+		bw.WriteString(d[id])
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// Read adds the names of the dictionary read from r to d.
+func (d FeatureDict) Read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxLineSize)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		tks := strings.SplitN(line, "\t", 2)
+		id, err := strconv.ParseInt(tks[0], 10, 64)
+		if err != nil || len(tks) != 2 {
+			return fmt.Errorf("line %d: want a feature ID and a name separated by a tab", n)
+		}
+		if _, ok := d[id]; !ok {
+			d[id] = tks[1]
+		}
+	}
+	return scanner.Err()
+}
+
+// Save writes d to the file at path.
+func (d FeatureDict) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = d.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// LoadFeatureDict reads the dictionary written to the file at path by Save.
+func LoadFeatureDict(path string) (FeatureDict, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	d := make(FeatureDict)
+	if err = d.Read(file); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return d, nil
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/pantsing/hector/internal/utils"
+)
+
+func TestFeatureDict(t *testing.T) {
+	d := NewFeatureDict(map[int64]string{3: "3", utils.Hash("country=fr"): "country=fr"})
+	if len(d) != 1 || d.Name(utils.Hash("country=fr")) != "country=fr" || d.Name(3) != "3" {
+		t.Errorf("dictionary %v", d)
+	}
+
+	var buf bytes.Buffer
+	if err := d.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	read := make(FeatureDict)
+	if err := read.Read(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 1 || read.Name(utils.Hash("country=fr")) != "country=fr" {
+		t.Errorf("dictionary %v after read", read)
+	}
+	if err := read.Read(bytes.NewBufferString("x\tname\n")); err == nil {
+		t.Error("Read of a line without an ID returned no error")
+	}
+}
+
+func TestLoadWritesNoFile(t *testing.T) {
+	path, cleanup := writeDataFile(t, "1\tcountry:1\t2:0.5\n")
+	defer cleanup()
+	dir, err := ioutil.TempDir("", "hector-core")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	d := NewDataSet()
+	if err = d.Load(path, -1); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Errorf("Load wrote %s to the working directory", files[0].Name())
+	}
+	if dict := d.FeatureDict(); len(dict) != 1 || dict.Name(utils.Hash("country")) != "country" {
+		t.Errorf("feature dictionary %v", dict)
+	}
+}
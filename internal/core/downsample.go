@@ -0,0 +1,50 @@
+package core
+
+import (
+	"math/rand"
+)
+
+// NegativeSampler keeps every positive sample and every negative sample, labeled 0 or
+// less, with probability Rate. It draws from a source seeded with the seed it was made
+// with, so equal seeds keep equal samples.
+type NegativeSampler struct {
+	Rate float64
+	rng  *rand.Rand
+}
+
+// NewNegativeSampler returns a sampler keeping negative samples at rate, which is in (0,1].
+func NewNegativeSampler(rate float64, seed int64) *NegativeSampler {
+	return &NegativeSampler{Rate: rate, rng: rand.New(rand.NewSource(seed))}
+}
+
+// Keep tells whether the sample is kept.
+func (s *NegativeSampler) Keep(sample *Sample) bool {
+	return sample.Label > 0 || s.Rate >= 1 || s.rng.Float64() < s.Rate
+}
+
+// Downsample returns a data set of the samples of d the sampler keeps, in their order,
+// or d itself if Rate is 1. The samples are shared with d.
+func (s *NegativeSampler) Downsample(d *DataSet) *DataSet {
+	if s.Rate >= 1 {
+		return d
+	}
+	ret := NewDataSet()
+	ret.FeatureNameIdMap = d.FeatureNameIdMap
+	for _, sample := range d.Samples {
+		if s.Keep(sample) {
+			ret.AddSample(sample)
+		}
+	}
+	return ret
+}
+
+// Recalibrate corrects the probability p of a sample to be positive, predicted by a model
+// trained on negative samples kept at rate, back to the base rate of the data the samples
+// were drawn from: the odds of p are multiplied by rate. p is returned unchanged unless
+// rate is in (0,1).
+func Recalibrate(p, rate float64) float64 {
+	if rate <= 0 || rate >= 1 {
+		return p
+	}
+	return p * rate / (p*rate + 1 - p)
+}
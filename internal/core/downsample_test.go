@@ -0,0 +1,44 @@
+package core
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNegativeSampler(t *testing.T) {
+	d := NewDataSet()
+	for i := 0; i < 10000; i++ {
+		d.AddSample(&Sample{Label: i % 2})
+	}
+	kept := NewNegativeSampler(0.1, 1).Downsample(d)
+	positives, negatives := 0, 0
+	for _, sample := range kept.Samples {
+		if sample.Label > 0 {
+			positives++
+		} else {
+			negatives++
+		}
+	}
+	if positives != 5000 {
+		t.Errorf("kept %d positive samples, want all 5000", positives)
+	}
+	if negatives < 400 || negatives > 600 {
+		t.Errorf("kept %d negative samples at rate 0.1 of 5000", negatives)
+	}
+	if NewNegativeSampler(1, 1).Downsample(d) != d {
+		t.Error("Downsample at rate 1 did not return the data set")
+	}
+}
+
+func TestRecalibrate(t *testing.T) {
+	// A model trained on a tenth of the negatives predicting 0.5 has seen even odds of
+	// which the true ones are a tenth.
+	if p := Recalibrate(0.5, 0.1); math.Abs(p-1.0/11) > 1e-12 {
+		t.Errorf("Recalibrate(0.5, 0.1) = %v, want 1/11", p)
+	}
+	for _, p := range []float64{0, 0.3, 1} {
+		if got := Recalibrate(p, 1); got != p {
+			t.Errorf("Recalibrate(%v, 1) = %v", p, got)
+		}
+	}
+}
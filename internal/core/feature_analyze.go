@@ -8,6 +8,8 @@ import(
 type WeightLabel struct {
 	weight float64
 	label int
+	// sample_weight is the weight of the sample, weight being its feature value.
+	sample_weight float64
 }
 
 func (self *WeightLabel) LabelDoubleValue() float64{
@@ -21,6 +23,7 @@ type FeatureLabelDistribution struct {
 type WeightGoal struct {
 	weight float64
 	goal float64
+	sample_weight float64
 }
 
 type FeatureGoalDistribution struct {
@@ -39,13 +42,17 @@ func NewFeatureGoalDistribution() *FeatureGoalDistribution{
 	return &ret
 }
 
-func (f *FeatureLabelDistribution) AddWeightLabel(weight float64, label int){
-	wl := WeightLabel{weight:weight, label:label}
+// AddWeightLabel adds the feature value weight of a sample of the label and the weight
+// sample_weight.
+func (f *FeatureLabelDistribution) AddWeightLabel(weight float64, label int, sample_weight float64){
+	wl := WeightLabel{weight:weight, label:label, sample_weight:sample_weight}
 	f.weight_label = append(f.weight_label, wl)
 }
 
-func (f *FeatureGoalDistribution) AddWeightGoal(weight float64, goal float64){
-	wl := WeightGoal{weight:weight, goal:goal}
+// AddWeightGoal adds the feature value weight of a sample of the goal and the weight
+// sample_weight.
+func (f *FeatureGoalDistribution) AddWeightGoal(weight float64, goal float64, sample_weight float64){
+	wl := WeightGoal{weight:weight, goal:goal, sample_weight:sample_weight}
 	f.weight_goal = append(f.weight_goal, wl)
 }
 
@@ -84,7 +91,7 @@ func (f *FeatureLabelDistribution) PositiveCount() int {
 func (f *FeatureLabelDistribution) LabelDistribution() *ArrayVector {
 	ret := NewArrayVector()
 	for _, e := range f.weight_label {
-		ret.AddValue(e.label, 1.0)
+		ret.AddValue(e.label, e.sample_weight)
 	}
 	return ret
 }
@@ -110,13 +117,13 @@ func (f *FeatureGoalDistribution) BestSplitByVariance(sum_left, sum_left2, count
 			}	
 		}
 		prev_weight = wl.weight
-		sum_left += wl.goal
-		sum_left2 += wl.goal * wl.goal
-		count_left += 1.0
+		sum_left += wl.sample_weight * wl.goal
+		sum_left2 += wl.sample_weight * wl.goal * wl.goal
+		count_left += wl.sample_weight
 
-		sum_right -= wl.goal
-		sum_right2 -= wl.goal * wl.goal
-		count_right -= 1.0
+		sum_right -= wl.sample_weight * wl.goal
+		sum_right2 -= wl.sample_weight * wl.goal * wl.goal
+		count_right -= wl.sample_weight
 	}
 	return split, min_vari
 }
@@ -199,8 +206,8 @@ func (self *FeatureLabelDistribution) BestSplitByGini(total_dis *ArrayVector) (f
 			}
 		}
 		prev_weight = wl.weight
-		left_dis.AddValue(wl.label, 1.0)
-		right_dis.AddValue(wl.label, -1.0)
+		left_dis.AddValue(wl.label, wl.sample_weight)
+		right_dis.AddValue(wl.label, -wl.sample_weight)
 	}
 	return split, min_gini
 }
@@ -268,7 +275,7 @@ func InformationValue(dataset *DataSet) map[int64]float64 {
 			if !ok {
 				feature_weight_labels[feature.Id] = NewFeatureLabelDistribution()
 			}
-			feature_weight_labels[feature.Id].AddWeightLabel(feature.Value, sample.Label)
+			feature_weight_labels[feature.Id].AddWeightLabel(feature.Value, sample.Label, sample.GetWeight())
 		}
 	}
 	
@@ -0,0 +1,226 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pantsing/hector/internal/utils"
+)
+
+// hashFuncs are the hash functions of feature names a Hasher may use, by name.
+var hashFuncs = map[string]func(str string, seed uint64) uint64{
+	"poly":     utils.PolyHash,
+	"fnv1a":    utils.FNV1a,
+	"murmur3":  func(str string, seed uint64) uint64 { return utils.Murmur3(str, uint32(seed)) },
+	"xxhash64": utils.XXHash64,
+}
+
+// HashFuncs returns the names of the hash functions of Hasher, sorted.
+func HashFuncs() []string {
+	names := make([]string, 0, len(hashFuncs))
+	for name := range hashFuncs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MaxHashBits is the largest Bits of a Hasher.
+const MaxHashBits = 62
+
+/*
+Hasher turns the names of features into their IDs. Names which are integers are their own
+IDs, folded into [0, 2^Bits) like the hashes; the others are hashed. The zero Hasher, like
+a nil one, is the polynomial hash of utils.Hash all models were hashed with before hashing
+was configurable.
+
+Feature IDs are only valid under the Hasher they were made by, which model files and data
+set caches record by String.
+*/
+type Hasher struct {
+	// Func is the name of the hash function, one of HashFuncs, "poly" if empty.
+	Func string
+	// Bits, if positive, folds the IDs into [0, 2^Bits) by keeping their low bits,
+	// bounding the number of weights of a model at the cost of collisions.
+	Bits int
+	// Signed multiplies the value of every hashed feature by 1 or -1 after the top bit
+	// of its hash, so that the features colliding into an ID cancel out on average
+	// instead of adding up.
+	Signed bool
+	// NamespaceSep, if set, splits names at its first occurrence into a namespace, such
+	// as the column of a categorical CSV feature named column=value, and the rest, which
+	// is hashed seeded with the hash of the namespace. Names without it are in the
+	// namespace of seed 0.
+	NamespaceSep string
+}
+
+// defaultHasher is the Hasher of nil receivers.
+var defaultHasher Hasher
+
+// ParseHasher returns the Hasher whose String is spec.
+func ParseHasher(spec string) (*Hasher, error) {
+	h := &Hasher{}
+	if i := strings.Index(spec, ",namespace="); i >= 0 {
+		h.NamespaceSep = spec[i+len(",namespace="):]
+		spec = spec[:i]
+	}
+	tks := strings.Split(spec, ",")
+	h.Func = tks[0]
+	if h.Func == utils.HashName {
+		h.Func = "poly"
+	}
+	for _, tk := range tks[1:] {
+		switch {
+		case tk == "signed":
+			h.Signed = true
+		case strings.HasPrefix(tk, "bits="):
+			bits, err := strconv.Atoi(tk[len("bits="):])
+			if err != nil {
+				return nil, fmt.Errorf("bad feature hash %q", spec)
+			}
+			h.Bits = bits
+		default:
+			return nil, fmt.Errorf("bad feature hash %q", spec)
+		}
+	}
+	if err := h.Validate(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Validate returns an error if h has an unknown Func or Bits out of [0, MaxHashBits].
+func (h *Hasher) Validate() error {
+	if h == nil {
+		return nil
+	}
+	if _, ok := hashFuncs[h.Func]; !ok && h.Func != "" {
+		return fmt.Errorf("unknown feature hash %q, want one of %s", h.Func, strings.Join(HashFuncs(), ", "))
+	}
+	if h.Bits < 0 || h.Bits > MaxHashBits {
+		return fmt.Errorf("%d hash bits, want 0 to %d", h.Bits, MaxHashBits)
+	}
+	return nil
+}
+
+// String returns the spec of h ParseHasher reads: its function, then the options which
+// are set, separated by commas. The polynomial function is named utils.HashName.
+func (h *Hasher) String() string {
+	if h == nil {
+		h = &defaultHasher
+	}
+	spec := h.Func
+	if spec == "" || spec == "poly" {
+		spec = utils.HashName
+	}
+	if h.Bits > 0 {
+		spec += ",bits=" + strconv.Itoa(h.Bits)
+	}
+	if h.Signed {
+		spec += ",signed"
+	}
+	if h.NamespaceSep != "" {
+		spec += ",namespace=" + h.NamespaceSep
+	}
+	return spec
+}
+
+// FeatureID returns the ID of a feature named in a data set, and the sign its value is
+// multiplied by.
+func (h *Hasher) FeatureID(name string) (id int64, sign float64, hashed bool) {
+	if h == nil {
+		h = &defaultHasher
+	}
+	if id, err := strconv.ParseInt(name, 10, 64); err == nil {
+		if h.Bits > 0 {
+			id &= 1<<uint(h.Bits) - 1
+		}
+		return id, 1, false
+	}
+	hash := hashFuncs["poly"]
+	if h.Func != "" {
+		hash = hashFuncs[h.Func]
+	}
+	seed := uint64(0)
+	if h.NamespaceSep != "" {
+		if i := strings.Index(name, h.NamespaceSep); i >= 0 {
+			seed = hash(name[:i], 0)
+			name = name[i+len(h.NamespaceSep):]
+		}
+	}
+	v := hash(name, seed)
+	switch {
+	case h.Bits > 0:
+		id = int64(v & (1<<uint(h.Bits) - 1))
+	case h.Func == "" || h.Func == "poly":
+		// The absolute value of utils.Hash.
+		if id = int64(v); id < 0 {
+			id = -id
+		}
+	default:
+		id = int64(v &^ (1 << 63))
+	}
+	sign = 1
+	if h.Signed && v>>63 == 1 {
+		sign = -1
+	}
+	return id, sign, true
+}
+
+// ParseSample is the package ParseSample hashing feature names with h.
+func (h *Hasher) ParseSample(line string, globalBiasFeatureID int64, labeled bool) (*Sample, error) {
+	return parseSample(line, globalBiasFeatureID, labeled, h.features(nil, nil, -1))
+}
+
+// HashStats counts the feature names a loader saw.
+type HashStats struct {
+	// Names is the number of distinct hashed names.
+	Names int
+	// Collisions is the number of distinct names, integers folded by Bits included, whose
+	// ID was the ID of another name seen before, or the global bias feature ID.
+	Collisions int
+}
+
+func (s HashStats) String() string {
+	return fmt.Sprintf("%d hashed feature names, %d colliding with another", s.Names, s.Collisions)
+}
+
+/*
+features returns the function the parsers turn the names and values of features into
+Features with, hashing the names with h. Unless names is nil, it records the first name
+of every ID in it, and counts the hashed names and their collisions, with names recorded
+before too and with the global bias feature unless globalBiasFeatureID is negative, in
+stats.
+*/
+func (h *Hasher) features(names map[int64]string, stats *HashStats, globalBiasFeatureID int64) func(name string, value float64) Feature {
+	if names == nil {
+		return func(name string, value float64) Feature {
+			id, sign, _ := h.FeatureID(name)
+			return Feature{Id: id, Value: sign * value}
+		}
+	}
+	// The names seen but not recorded as the names of their IDs.
+	collided := make(map[string]bool)
+	return func(name string, value float64) Feature {
+		id, sign, hashed := h.FeatureID(name)
+		if first, ok := names[id]; !ok {
+			names[id] = name
+			if hashed {
+				stats.Names++
+			}
+			// The names hashed or folded to the bias feature ID, not the ID itself.
+			if id == globalBiasFeatureID && name != strconv.FormatInt(id, 10) {
+				stats.Collisions++
+			}
+		} else if first != name && !collided[name] {
+			collided[name] = true
+			if hashed {
+				stats.Names++
+			}
+			stats.Collisions++
+		}
+		return Feature{Id: id, Value: sign * value}
+	}
+}
@@ -0,0 +1,174 @@
+package core
+
+import (
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pantsing/hector/internal/utils"
+)
+
+func TestHashFuncs(t *testing.T) {
+	long := "hello, world! this is a longer key"
+	for _, c := range []struct {
+		name string
+		got  uint64
+		want uint64
+	}{
+		{"xxhash64 empty", utils.XXHash64("", 0), 0xef46db3751d8e999},
+		{"xxhash64 abc", utils.XXHash64("abc", 0), 0x44bc2cf5ad770999},
+		{"xxhash64 abc seed 42", utils.XXHash64("abc", 42), 0x13c1d910702770e6},
+		{"xxhash64 long", utils.XXHash64(long, 0), 0x97622899c0f6dab7},
+		{"murmur3 empty", utils.Murmur3("", 0), 0},
+		{"murmur3 abc", utils.Murmur3("abc", 0), 0xb4963f3f3fad7867},
+		{"murmur3 abc seed 42", utils.Murmur3("abc", 42), 0xd85089fb3cff7d6},
+		{"murmur3 long", utils.Murmur3(long, 0), 0xa17f804a996fde18},
+	} {
+		if c.got != c.want {
+			t.Errorf("%s: %#x, want %#x", c.name, c.got, c.want)
+		}
+	}
+	for _, s := range []string{"", "abc", long} {
+		h := fnv.New64a()
+		h.Write([]byte(s))
+		if got := utils.FNV1a(s, 0); got != h.Sum64() {
+			t.Errorf("FNV1a(%q) is %#x, want %#x", s, got, h.Sum64())
+		}
+	}
+}
+
+func TestHasher(t *testing.T) {
+	// The default hasher gives the IDs of utils.Hash.
+	for _, h := range []*Hasher{nil, {}, {Func: "poly"}} {
+		if id, sign, hashed := h.FeatureID("country=fr"); id != utils.Hash("country=fr") || sign != 1 || !hashed {
+			t.Errorf("%v: FeatureID is %d, %v, %v", h, id, sign, hashed)
+		}
+		if h.String() != utils.HashName {
+			t.Errorf("%#v: String is %q, want %q", h, h.String(), utils.HashName)
+		}
+	}
+	if id, _, hashed := (&Hasher{Func: "murmur3"}).FeatureID("1234"); id != 1234 || hashed {
+		t.Errorf("integer name has ID %d, hashed %v", id, hashed)
+	}
+	// Integer names are folded like the hashes.
+	if id, sign, hashed := (&Hasher{Func: "murmur3", Bits: 4, Signed: true}).FeatureID("1234"); id != 1234%16 || sign != 1 || hashed {
+		t.Errorf("integer name has ID %d, sign %v, hashed %v under 4 bits", id, sign, hashed)
+	}
+
+	for _, h := range []*Hasher{
+		{Func: "poly"},
+		{Func: "murmur3", Bits: 18, Signed: true},
+		{Func: "xxhash64", NamespaceSep: ",="},
+		{Func: "fnv1a", Bits: 62, Signed: true, NamespaceSep: "="},
+	} {
+		parsed, err := ParseHasher(h.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *parsed != *h {
+			t.Errorf("ParseHasher(%q) is %#v", h.String(), parsed)
+		}
+	}
+	for _, spec := range []string{"md5", "murmur3,bits=x", "murmur3,bits=63", "murmur3,unsigned"} {
+		if _, err := ParseHasher(spec); err == nil {
+			t.Errorf("ParseHasher(%q) returned no error", spec)
+		}
+	}
+
+	h := &Hasher{Func: "xxhash64", Bits: 10, Signed: true}
+	signs := map[float64]int{}
+	for i := 0; i < 1000; i++ {
+		id, sign, _ := h.FeatureID(fmt.Sprintf("f%d", i))
+		if id < 0 || id >= 1<<10 {
+			t.Fatalf("ID %d out of 10 bits", id)
+		}
+		signs[sign]++
+	}
+	if signs[1] < 400 || signs[-1] < 400 {
+		t.Errorf("signs %v, want about as many of each", signs)
+	}
+
+	h = &Hasher{Func: "murmur3", NamespaceSep: "="}
+	fr, _, _ := h.FeatureID("country=fr")
+	if lang, _, _ := h.FeatureID("lang=fr"); lang == fr {
+		t.Error("the same name in two namespaces has the same ID")
+	}
+	if whole, _, _ := (&Hasher{Func: "murmur3"}).FeatureID("country=fr"); whole == fr {
+		t.Error("namespaced name has the ID of the whole name")
+	}
+}
+
+func TestLoadHashCollisions(t *testing.T) {
+	// Ten names folded into two IDs, with two integer features, one of them folded,
+	// colliding with them.
+	var lines []string
+	for i := 0; i < 10; i++ {
+		lines = append(lines, fmt.Sprintf("1\tf%d:1\tf%d:1\t0:1\t3:1", i, (i+1)%10))
+	}
+	path, cleanup := writeDataFile(t, strings.Join(lines, "\n")+"\n")
+	defer cleanup()
+	d := NewDataSet()
+	d.Hasher = &Hasher{Func: "murmur3", Bits: 1, Signed: true}
+	if err := d.Load(path, -1); err != nil {
+		t.Fatal(err)
+	}
+	if d.HashStats.Names != 10 || d.HashStats.Collisions != 10 {
+		t.Errorf("stats %+v, want 10 names and 10 collisions", d.HashStats)
+	}
+	// The name first seen with the ID of the global bias feature collides with it.
+	biased := NewDataSet()
+	biased.Hasher = d.Hasher
+	if err := biased.Load(path, 1); err != nil {
+		t.Fatal(err)
+	}
+	if biased.HashStats.Collisions != 11 {
+		t.Errorf("stats %+v with the global bias feature 1, want 11 collisions", biased.HashStats)
+	}
+	for _, s := range d.Samples {
+		for _, f := range s.Features {
+			if f.Id > 1 || (f.Value != 1 && f.Value != -1) {
+				t.Fatalf("feature %v", f)
+			}
+		}
+	}
+
+	// The samples of the cache were hashed by d.Hasher.
+	if err := d.WriteCache(path + ".hc"); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewDataSet().Load(path+".hc", -1); err == nil {
+		t.Error("Load of a cache hashed by another hasher returned no error")
+	}
+	cached := NewDataSet()
+	cached.Hasher = d.Hasher
+	if err := cached.Load(path+".hc", -1); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRealDataSetLoadHashed(t *testing.T) {
+	path, cleanup := writeDataFile(t, "1.5\t9:2\tf:1\n0.5\t6:1\n")
+	defer cleanup()
+	d := NewRealDataSet()
+	d.Hasher = &Hasher{Bits: 2, Signed: true}
+	if err := d.Load(path, -1); err != nil {
+		t.Fatal(err)
+	}
+	// The features of the train set get the IDs and values of the same features
+	// predicted.
+	for i, line := range []string{"9:2\tf:1", "6:1"} {
+		want, err := d.Hasher.ParseSample(line, -1, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := d.Samples[i].Features; !reflect.DeepEqual(got, want.Features) {
+			t.Errorf("features of sample %d are %v, want %v", i, got, want.Features)
+		}
+	}
+	// f is folded into the ID of 6.
+	if d.HashStats.Names != 1 || d.HashStats.Collisions != 1 {
+		t.Errorf("stats %+v, want 1 name and 1 collision", d.HashStats)
+	}
+}
@@ -0,0 +1,252 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+/*
+InputFiles returns the files a data set path names, in sorted order: the files matching
+it if it is a glob pattern, the files of it if it is a directory, leaving out those whose
+name starts with . or _ such as _SUCCESS markers, and the path itself otherwise.
+*/
+func InputFiles(path string) ([]string, error) {
+	if strings.ContainsAny(path, "*?[") {
+		files, err := filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("%s: no file matches", path)
+		}
+		sort.Strings(files)
+		return files, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || strings.HasPrefix(entry.Name(), "_") {
+			continue
+		}
+		files = append(files, filepath.Join(path, entry.Name()))
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%s: no file in the directory", path)
+	}
+	return files, nil
+}
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// errNoZstd is the error of zstd compressed input without the zstd command to read it.
+var errNoZstd = fmt.Errorf("zstd compressed input is read with the zstd command, which is not in the PATH")
+
+// decompress returns the decompressed contents of r, which is gzip, bzip2 or zstd
+// compressed if it starts with their magic bytes or name ends in .gz, .bz2 or .zst, and
+// is returned as is otherwise. zstd is decompressed by the zstd command, which Go has no
+// decoder of in its standard library.
+func decompress(r io.Reader, name string) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic) || strings.HasSuffix(name, ".gz"):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, bzip2Magic) || strings.HasSuffix(name, ".bz2"):
+		return ioutil.NopCloser(bzip2.NewReader(br)), nil
+	case bytes.HasPrefix(magic, zstdMagic) || strings.HasSuffix(name, ".zst"):
+		zstd, err := exec.LookPath("zstd")
+		if err != nil {
+			return nil, errNoZstd
+		}
+		cmd := exec.Command(zstd, "-d", "-c")
+		cmd.Stdin = br
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		output := &commandOutput{ReadCloser: out, cmd: cmd}
+		cmd.Stderr = &output.stderr
+		if err = cmd.Start(); err != nil {
+			return nil, fmt.Errorf("zstd command: %v", err)
+		}
+		return output, nil
+	}
+	return ioutil.NopCloser(br), nil
+}
+
+// commandOutput is the output of a command, which Close waits for, reporting the error
+// messages of the command if it fails.
+type commandOutput struct {
+	io.ReadCloser
+	cmd    *exec.Cmd
+	stderr bytes.Buffer
+}
+
+func (c *commandOutput) Close() error {
+	c.ReadCloser.Close()
+	if err := c.cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(c.stderr.String()); msg != "" {
+			return fmt.Errorf("%s command: %v: %s", filepath.Base(c.cmd.Path), err, msg)
+		}
+		return fmt.Errorf("%s command: %v", filepath.Base(c.cmd.Path), err)
+	}
+	return nil
+}
+
+// openInput opens the file at path and decompresses it, see decompress. Errors do not
+// name the file, which readLines does.
+func openInput(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := decompress(file, path)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &fileInput{ReadCloser: r, file: file}, nil
+}
+
+// fileInput is the decompressed contents of a file, whose Close closes the file too.
+type fileInput struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (f *fileInput) Close() error {
+	err := f.ReadCloser.Close()
+	if ferr := f.file.Close(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+const (
+	// prefetchFiles is the number of files of a data set read ahead of the one parsed.
+	prefetchFiles = 4
+	// prefetchChunks is the number of chunks of prefetchChunkSize bytes of a file read
+	// ahead of its parsing.
+	prefetchChunks    = 16
+	prefetchChunkSize = 1 << 20
+)
+
+// prefetch reads the decompressed contents of a file in chunks ahead of its parsing.
+type prefetch struct {
+	chunks chan []byte
+	// err is the error reading the file stopped at, set before chunks is closed.
+	err error
+	buf []byte
+}
+
+func startPrefetch(path string, done <-chan struct{}) *prefetch {
+	p := &prefetch{chunks: make(chan []byte, prefetchChunks)}
+	go func() {
+		defer close(p.chunks)
+		r, err := openInput(path)
+		if err != nil {
+			p.err = err
+			return
+		}
+		defer func() {
+			// Close reports the failure of a zstd command, after the output it wrote.
+			if err := r.Close(); err != nil && p.err == nil {
+				p.err = err
+			}
+		}()
+		for {
+			chunk := make([]byte, prefetchChunkSize)
+			n := 0
+			for n < len(chunk) && err == nil {
+				var m int
+				m, err = r.Read(chunk[n:])
+				n += m
+			}
+			if n > 0 {
+				select {
+				case p.chunks <- chunk[:n]:
+				case <-done:
+					return
+				}
+			}
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				p.err = err
+				return
+			}
+		}
+	}()
+	return p
+}
+
+func (p *prefetch) Read(b []byte) (int, error) {
+	for len(p.buf) == 0 {
+		chunk, ok := <-p.chunks
+		if !ok {
+			if p.err != nil {
+				return 0, p.err
+			}
+			return 0, io.EOF
+		}
+		p.buf = chunk
+	}
+	n := copy(b, p.buf)
+	p.buf = p.buf[n:]
+	return n, nil
+}
+
+/*
+readPath calls a parser with every line of the files path names, see InputFiles, as
+readLines does, in the order of the files and of their lines. Each file is parsed by a
+parser newParser returns for it, so every CSV file starts with its header. The files are
+decompressed, see decompress, and read concurrently: up to prefetchFiles files are read
+ahead of the one being parsed. It returns the number of lines skipped in all the files.
+*/
+func readPath(path string, mode ParseMode, newParser func() func(line string) error) (skipped int, err error) {
+	files, err := InputFiles(path)
+	if err != nil {
+		return 0, err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	prefetches := make([]*prefetch, len(files))
+	for i, file := range files {
+		for j := i; j <= i+prefetchFiles && j < len(files); j++ {
+			if prefetches[j] == nil {
+				prefetches[j] = startPrefetch(files[j], done)
+			}
+		}
+		n, err := readLines(prefetches[i], file, mode, newParser())
+		skipped += n
+		if err != nil {
+			return skipped, err
+		}
+	}
+	return skipped, nil
+}
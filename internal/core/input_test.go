@@ -0,0 +1,126 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func gzipped(t *testing.T, text string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(text)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestLoadDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hector-core")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// More files than are prefetched, some gzipped without the extension, which magic
+	// bytes tell, and a marker file which is skipped.
+	files := map[string][]byte{"_SUCCESS": []byte("not data\n")}
+	for i, name := range []string{"part-0", "part-1", "part-2", "part-3", "part-4", "part-5", "part-6"} {
+		text := string('0'+rune(i%2)) + " 1:1\n"
+		if i%3 == 0 {
+			files[name+".gz"] = gzipped(t, text)
+		} else if i%3 == 1 {
+			files[name] = gzipped(t, text)
+		} else {
+			files[name] = []byte(text)
+		}
+	}
+	for name, data := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, path := range []string{dir, filepath.Join(dir, "part-*")} {
+		d := NewDataSet()
+		if err = d.Load(path, -1); err != nil {
+			t.Fatal(err)
+		}
+		if len(d.Samples) != 7 {
+			t.Fatalf("loaded %d samples from %s, want 7", len(d.Samples), path)
+		}
+		for i, sample := range d.Samples {
+			if sample.Label != i%2 {
+				t.Errorf("sample %d of %s has label %d, want the order of the files", i, path, sample.Label)
+			}
+		}
+	}
+
+	if err = NewDataSet().Load(filepath.Join(dir, "none-*"), -1); err == nil {
+		t.Error("Load of a glob matching no file returned no error")
+	}
+}
+
+func TestLoadCompressedErrors(t *testing.T) {
+	// A truncated gzip file fails however lenient the mode.
+	data := gzipped(t, "1 1:1\n0 1:2\n")
+	path, cleanup := writeDataFile(t, string(data[:len(data)-6]))
+	defer cleanup()
+	d := NewRealDataSet()
+	d.Mode = Lenient
+	if err := d.Load(path, -1); err == nil {
+		t.Error("Load of a truncated gzip file returned no error")
+	}
+}
+
+func TestLoadZstd(t *testing.T) {
+	text := "1 1:1\n0 1:2\n"
+	path, cleanup := writeDataFile(t, text)
+	defer cleanup()
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("no zstd command")
+	}
+	if out, err := exec.Command("zstd", "-q", path, "-o", path+".zst").CombinedOutput(); err != nil {
+		t.Fatalf("zstd: %v: %s", err, out)
+	}
+	d := NewDataSet()
+	if err := d.Load(path+".zst", -1); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Samples) != 2 || d.Samples[1].Label != 0 {
+		t.Errorf("loaded %d samples, want 2", len(d.Samples))
+	}
+
+	// The messages of a failing zstd command are reported.
+	data, err := ioutil.ReadFile(path + ".zst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(path+".zst", data[:len(data)-4], 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = NewDataSet().Load(path+".zst", -1); err == nil || !strings.Contains(err.Error(), "zstd command") {
+		t.Errorf("Load of a truncated zstd file returned %v", err)
+	}
+}
+
+func TestLoadZstdWithoutCommand(t *testing.T) {
+	path, cleanup := writeDataFile(t, "1 1:1\n")
+	defer cleanup()
+	if err := os.Rename(path, path+".zst"); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", filepath.Dir(path))
+	err := NewDataSet().Load(path+".zst", -1)
+	if err == nil || !strings.Contains(err.Error(), errNoZstd.Error()) {
+		t.Errorf("Load without the zstd command returned %v, want %v", err, errNoZstd)
+	}
+}
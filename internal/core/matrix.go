@@ -1,5 +1,7 @@
 package core
 
+import "sort"
+
 type Matrix struct {
 	Data map[int64]*Vector
 }
@@ -10,6 +12,16 @@ func NewMatrix() *Matrix {
 	return &m
 }
 
+// SortedKeys returns the keys of the rows of m in increasing order.
+func (m *Matrix) SortedKeys() []int64 {
+	keys := make([]int64, 0, len(m.Data))
+	for key := range m.Data {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
 func (m *Matrix) AddValue(k1, k2 int64, v float64){
 	_, ok := m.Data[k1]
 	if !ok {
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package core
+
+import (
+	"io/ioutil"
+)
+
+// mapFile reads the file at path in memory, where it cannot be memory mapped.
+func mapFile(path string) (data []byte, unmap func() error, err error) {
+	data, err = ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
@@ -3,6 +3,7 @@ package core
 import (
 	"math"
 	"math/rand"
+	"strconv"
 )
 
 func XORDataSet(n int) *DataSet{
@@ -73,3 +74,21 @@ func SinusoidalDataSet(n int) *RealDataSet {
 
 	return ret
 }
+
+// RankingDataSet returns n queries of size samples of the labels 0, 1 and 2, ranked by
+// feature 1, which is the label plus noise, among the noise of feature 2.
+func RankingDataSet(n, size int) *DataSet {
+	ret := NewDataSet()
+	for q := 0; q < n; q++ {
+		for i := 0; i < size; i++ {
+			sample := NewSample()
+			sample.Label = rand.Intn(3)
+			sample.Qid = strconv.Itoa(q)
+			sample.AddFeature(Feature{Id: 1, Value: float64(sample.Label) + rand.NormFloat64()*0.3})
+			sample.AddFeature(Feature{Id: 2, Value: rand.NormFloat64()})
+			sample.AddFeature(Feature{Id: 3, Value: 1.0})
+			ret.AddSample(sample)
+		}
+	}
+	return ret
+}
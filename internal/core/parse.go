@@ -0,0 +1,142 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MaxLineSize is the longest line a data set file may hold.
+const MaxLineSize = 64 << 20
+
+// ParseMode tells the data set loaders what to do with a malformed line.
+type ParseMode int
+
+const (
+	// Strict aborts loading at the first malformed line.
+	Strict ParseMode = iota
+	// Lenient skips malformed lines and counts them.
+	Lenient
+)
+
+// LineError reports a malformed line of a data set file.
+type LineError struct {
+	Path string
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.Path, e.Line, e.Err)
+}
+
+// FeatureID returns the ID of a feature named in a data set. Names which are not
+// integers are hashed by utils.Hash, see Hasher.
+func FeatureID(name string) (id int64, hashed bool) {
+	id, _, hashed = (*Hasher)(nil).FeatureID(name)
+	return id, hashed
+}
+
+// ParseSample parses a line of a classification data set, which starts with the label
+// if labeled is true. A qid:<query> token sets the Qid of the sample, and a
+// weight:<w> token its Weight, which must be positive. The sample gets the global bias
+// feature unless globalBiasFeatureID is negative.
+func ParseSample(line string, globalBiasFeatureID int64, labeled bool) (*Sample, error) {
+	return (*Hasher)(nil).ParseSample(line, globalBiasFeatureID, labeled)
+}
+
+// parseSample is ParseSample turning the names and values of features into Features by
+// feature, see Hasher.features.
+func parseSample(line string, globalBiasFeatureID int64, labeled bool, feature func(name string, value float64) Feature) (*Sample, error) {
+	tks := strings.Split(strings.TrimSpace(strings.Replace(line, " ", "\t", -1)), "\t")
+	sample := &Sample{Features: make([]Feature, 0, 20), Label: 0}
+	if globalBiasFeatureID >= 0 {
+		sample.Features = append(sample.Features, Feature{globalBiasFeatureID, 1.0})
+	}
+	for i, tk := range tks {
+		if i == 0 && labeled {
+			label, err := strconv.Atoi(tk)
+			if err != nil {
+				return nil, fmt.Errorf("bad label %q", tk)
+			}
+			sample.Label = label
+			continue
+		}
+		if tk == "" {
+			continue
+		}
+		if strings.HasPrefix(tk, "qid:") {
+			if sample.Qid = tk[len("qid:"):]; sample.Qid == "" {
+				return nil, fmt.Errorf("empty qid")
+			}
+			continue
+		}
+		if strings.HasPrefix(tk, "weight:") {
+			w, err := parseWeight(tk)
+			if err != nil {
+				return nil, err
+			}
+			sample.Weight = w
+			continue
+		}
+		kv := strings.Split(tk, ":")
+		feature_value := 1.0
+		if len(kv) > 1 {
+			var err error
+			feature_value, err = strconv.ParseFloat(kv[1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad value of feature %q", tk)
+			}
+		}
+		sample.Features = append(sample.Features, feature(kv[0], feature_value))
+	}
+	return sample, nil
+}
+
+/*
+readLines calls parse with every line of r that is not blank. In Strict mode the first
+line parse fails on aborts the read with a LineError; in Lenient mode the line is skipped
+and counted, unless it is a malformed header. path names r in errors and logs.
+*/
+func readLines(r io.Reader, path string, mode ParseMode, parse func(line string) error) (skipped int, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxLineSize)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if err := parse(line); err != nil {
+			lineErr := &LineError{Path: path, Line: n, Err: err}
+			if _, header := err.(headerError); mode == Strict || header {
+				return skipped, lineErr
+			}
+			if skipped == 0 {
+				log.Println("skipping malformed line", lineErr)
+			}
+			skipped++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return skipped, &LineError{Path: path, Line: n + 1, Err: err}
+	}
+	if skipped > 0 {
+		log.Printf("%s: skipped %d malformed lines", path, skipped)
+	}
+	return skipped, nil
+}
+
+// parseWeight returns the weight of a weight:<w> token, which must be positive.
+func parseWeight(tk string) (float64, error) {
+	w, err := strconv.ParseFloat(strings.TrimPrefix(tk, "weight:"), 64)
+	if err != nil || !(w > 0) || math.IsInf(w, 1) {
+		return 0, fmt.Errorf("bad weight %q, want a positive number", tk)
+	}
+	return w, nil
+}
@@ -12,6 +12,14 @@ type Sample struct {
 	Features   []Feature
 	Label      int
 	Prediction float64
+	// Key holds the columns before the label of the line of the sample, see KeyColumns.
+	Key string
+	// Qid is the query, or page view, the sample was ranked in, given by the qid:
+	// token of SVMlight ranking files.
+	Qid string
+	// Weight is the importance of the sample in training and evaluation, given by a
+	// weight: token. 0, for samples built without one, is taken as 1; see GetWeight.
+	Weight float64
 }
 
 func NewSample() *Sample {
@@ -26,6 +34,9 @@ func (s *Sample) Clone() *Sample {
 	ret := NewSample()
 	ret.Label = s.Label
 	ret.Prediction = s.Prediction
+	ret.Key = s.Key
+	ret.Qid = s.Qid
+	ret.Weight = s.Weight
 	for _, feature := range s.Features {
 		clone_feature := Feature{feature.Id, feature.Value}
 		ret.Features = append(ret.Features, clone_feature)
@@ -38,6 +49,14 @@ func (s *Sample) ToString(includePrediction bool) []byte {
 	sb := utils.StringBuilder{}
 	sb.Int(s.Label)
 	sb.Write(" ")
+	if s.Qid != "" {
+		sb.Write("qid:" + s.Qid + " ")
+	}
+	if s.Weight != 0 {
+		sb.Write("weight:")
+		sb.Float(s.Weight)
+		sb.Write(" ")
+	}
 	if includePrediction {
 		sb.Float(s.Prediction)
 		sb.Write(" ")
@@ -51,6 +70,14 @@ func (s *Sample) ToString(includePrediction bool) []byte {
 	return sb.Bytes()
 }
 
+// GetWeight returns the weight of the sample, 1 if it is not set.
+func (s *Sample) GetWeight() float64 {
+	if s.Weight == 0 {
+		return 1
+	}
+	return s.Weight
+}
+
 func (s *Sample) LabelDoubleValue() float64 {
 	if s.Label > 0 {
 		return 1.0
@@ -92,6 +119,8 @@ type MapBasedSample struct {
 	Label      int
 	Prediction float64
 	Features   map[int64]float64
+	// Weight is the weight of the sample it was made of, see Sample.GetWeight.
+	Weight float64
 }
 
 func (s *MapBasedSample) LabelDoubleValue() float64 {
@@ -103,6 +132,7 @@ func (s *Sample) ToMapBasedSample() *MapBasedSample {
 	ret.Features = make(map[int64]float64)
 	ret.Label = s.Label
 	ret.Prediction = s.Prediction
+	ret.Weight = s.GetWeight()
 	for _, feature := range s.Features {
 		ret.Features[feature.Id] = feature.Value
 	}
@@ -125,6 +155,12 @@ type RealSample struct {
 	Features   []Feature
 	Prediction float64
 	Value      float64
+	// Key holds the key columns of the line of the sample, if its data set has any.
+	Key string
+	// Weight is the weight of the sample given by a weight: token, 0 if it has none.
+	Weight float64
+	// Qid is the query of the sample given by a qid: token, empty if it has none.
+	Qid string
 }
 
 func NewRealSample() *RealSample {
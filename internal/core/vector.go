@@ -1,9 +1,11 @@
 package core
 
 import (
+	"fmt"
 	"github.com/pantsing/hector/internal/utils"
 	"math"
 	"math/rand"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -18,28 +20,50 @@ func NewVector() *Vector {
 	return &v
 }
 
+// SortedKeys returns the keys of v in increasing order.
+func (v *Vector) SortedKeys() []int64 {
+	keys := make([]int64, 0, len(v.Data))
+	for key := range v.Data {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// ToString writes the values of v in the order of their keys, so that equal vectors
+// give equal strings.
 func (v *Vector) ToString() []byte {
 	sb := utils.StringBuilder{}
-	for key, value := range v.Data {
+	for _, key := range v.SortedKeys() {
 		sb.Int64(key)
 		sb.Write(":")
-		sb.Float(value)
+		sb.Float(v.Data[key])
 		sb.Write("|")
 	}
 	return sb.Bytes()
 }
 
-func (v *Vector) FromString(buf string) {
+func (v *Vector) FromString(buf string) error {
 	tks := strings.Split(buf, "|")
 	for _, tk := range tks {
 		if len(tk) == 0 {
 			continue
 		}
 		kv := strings.Split(tk, ":")
-		key, _ := strconv.ParseInt(kv[0], 10, 64)
-		value, _ := strconv.ParseFloat(kv[1], 64)
+		if len(kv) != 2 {
+			return fmt.Errorf("bad vector element %q", tk)
+		}
+		key, err := strconv.ParseInt(kv[0], 10, 64)
+		if err != nil {
+			return err
+		}
+		value, err := strconv.ParseFloat(kv[1], 64)
+		if err != nil {
+			return err
+		}
 		v.Data[key] = value
 	}
+	return nil
 }
 
 func (v *Vector) AddValue(key int64, value float64) {
@@ -61,9 +85,18 @@ func (v *Vector) GetValue(key int64) float64 {
 }
 
 func (v *Vector) RandomInit(key int64, c float64) {
+	v.RandomInitFrom(key, c, nil)
+}
+
+// RandomInitFrom is RandomInit drawing from r, or from the global source if r is nil.
+func (v *Vector) RandomInitFrom(key int64, c float64, r *rand.Rand) {
 	value, ok := v.Data[key]
 	if !ok {
-		value = rand.NormFloat64() * c
+		if r != nil {
+			value = r.NormFloat64() * c
+		} else {
+			value = rand.NormFloat64() * c
+		}
 		v.Data[key] = value
 	}
 }
@@ -2,9 +2,17 @@ package internal
 
 import (
 	"github.com/urfave/cli"
+	"github.com/pantsing/hector/internal/cmds/convert"
+	"github.com/pantsing/hector/internal/cmds/inspect"
 	"github.com/pantsing/hector/internal/cmds/run"
+	"github.com/pantsing/hector/internal/cmds/serve"
+	"github.com/pantsing/hector/internal/cmds/tune"
 )
 
 var Commands []cli.Command = []cli.Command{
 	run.Command(),
+	convert.Command(),
+	inspect.Command(),
+	serve.Command(),
+	tune.Command(),
 }
@@ -0,0 +1,272 @@
+/*
+Package model reads and writes hector model files.
+
+A model file starts with a magic line holding the format version, followed by a one line
+JSON header describing how the model was trained, followed by the model body written by the
+algorithm:
+
+	#hector-model 1
+	{"version":1,"algorithm":"ftrl","task":"binary",...,"checksum":"sha256:..."}
+	<body>
+
+The checksum covers the body only. Models of version 2 hold the names of the hashed
+features between the header and the body, as many lines of a feature dictionary as the
+feature_names field of the header tells, and the checksum covers them and the body:
+
+	#hector-model 2
+	{"version":2,...,"feature_names":2,"checksum":"sha256:..."}
+	-8817043823743457536	country=fr
+	4096023187744567731	user_agent=curl
+	<body>
+*/
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pantsing/hector/internal/core"
+	"github.com/pantsing/hector/internal/version"
+)
+
+const (
+	Magic   = "#hector-model"
+	Version = 2
+	// versionFeatureNames is the first version holding feature names. Models without are
+	// written as version 1, readable by older releases.
+	versionFeatureNames = 2
+)
+
+// Tasks a model can be trained for.
+const (
+	TaskBinary     = "binary"
+	TaskMultiClass = "multiclass"
+	TaskRegression = "regression"
+)
+
+// Persistent is implemented by algorithms whose trained state can be saved and loaded.
+type Persistent interface {
+	SaveModel(w io.Writer) error
+	LoadModel(r io.Reader) error
+}
+
+// Header describes how a model was trained.
+type Header struct {
+	Version             int               `json:"version"`
+	HectorVersion       string            `json:"hector_version"`
+	Algorithm           string            `json:"algorithm"`
+	Task                string            `json:"task"`
+	CommandLine         string            `json:"command_line,omitempty"`
+	Params              map[string]string `json:"params,omitempty"`
+	GlobalBiasFeatureID int64             `json:"global_bias_feature_id"`
+	// FeatureHash is the core.Hasher of the feature names, see Hasher.String.
+	FeatureHash string `json:"feature_hash"`
+	// Format is the format of the train set, libsvm, csv or tsv, and Columns the roles
+	// of the columns of csv and tsv train sets, see CSVFormat.ColumnRoles. Models
+	// written before the format was recorded have neither.
+	Format  string `json:"format,omitempty"`
+	Columns string `json:"columns,omitempty"`
+	// NegSampleRate is the rate the negative samples of the train set were kept at, if
+	// they were downsampled. Predictions are recalibrated by it.
+	NegSampleRate float64 `json:"neg_sample_rate,omitempty"`
+	// FeatureNameCount is the number of lines of FeatureNames in the model file.
+	FeatureNameCount int    `json:"feature_names,omitempty"`
+	Checksum         string `json:"checksum"`
+	// FeatureNames maps the IDs of the hashed features of the train set back to their
+	// names, if the model file holds them.
+	FeatureNames core.FeatureDict `json:"-"`
+}
+
+// Check returns an error if the model was not trained by the algorithm for the task.
+func (h *Header) Check(algorithm, task string) error {
+	if h.Algorithm != algorithm {
+		return fmt.Errorf("model was trained by %q, not %q", h.Algorithm, algorithm)
+	}
+	if h.Task != task {
+		return fmt.Errorf("model was trained for %s, not %s", h.Task, task)
+	}
+	return nil
+}
+
+func checksum(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Write writes the header, the feature names of h if any, and the model body of m to w,
+// filling in the version, the number of feature names and the checksum of h.
+func Write(w io.Writer, h *Header, m Persistent) error {
+	var names, body bytes.Buffer
+	if err := h.FeatureNames.Write(&names); err != nil {
+		return err
+	}
+	if err := m.SaveModel(&body); err != nil {
+		return err
+	}
+
+	h.Version = 1
+	h.FeatureNameCount = len(h.FeatureNames)
+	if h.FeatureNameCount > 0 {
+		h.Version = versionFeatureNames
+	}
+	h.HectorVersion = version.Binary
+	h.Checksum = checksum(append(names.Bytes(), body.Bytes()...))
+	meta, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s %d\n", Magic, h.Version)
+	bw.Write(meta)
+	bw.WriteString("\n")
+	bw.Write(names.Bytes())
+	bw.Write(body.Bytes())
+	return bw.Flush()
+}
+
+func readHeader(r *bufio.Reader) (*Header, error) {
+	line, err := r.ReadString('\n')
+	if err != nil && line == "" {
+		return nil, fmt.Errorf("not a hector model: %v", err)
+	}
+	tks := strings.Fields(line)
+	if len(tks) != 2 || tks[0] != Magic {
+		return nil, fmt.Errorf("not a hector model: missing %q line", Magic)
+	}
+	if v, err := strconv.Atoi(tks[1]); err != nil || v > Version {
+		return nil, fmt.Errorf("unsupported model format version %q", tks[1])
+	}
+
+	line, err = r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("truncated model header: %v", err)
+	}
+	h := &Header{}
+	if err = json.Unmarshal([]byte(line), h); err != nil {
+		return nil, fmt.Errorf("bad model header: %v", err)
+	}
+	return h, nil
+}
+
+// decode reads the header, the feature names and the body from r, and verifies the
+// checksum of the names and the body.
+func decode(r io.Reader) (*Header, []byte, error) {
+	br := bufio.NewReader(r)
+	h, err := readHeader(br)
+	if err != nil {
+		return nil, nil, err
+	}
+	var names []byte
+	for i := 0; i < h.FeatureNameCount; i++ {
+		line, err := br.ReadBytes('\n')
+		if err != nil {
+			return nil, nil, fmt.Errorf("truncated feature names: %v", err)
+		}
+		names = append(names, line...)
+	}
+	body, err := ioutil.ReadAll(br)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sum := checksum(append(names, body...)); sum != h.Checksum {
+		return nil, nil, fmt.Errorf("model checksum mismatch: header has %s, body is %s", h.Checksum, sum)
+	}
+	if h.FeatureNameCount > 0 {
+		h.FeatureNames = make(core.FeatureDict)
+		if err = h.FeatureNames.Read(bytes.NewReader(names)); err != nil {
+			return nil, nil, fmt.Errorf("feature names: %v", err)
+		}
+	}
+	return h, body, nil
+}
+
+// Read reads the header from r, verifies the checksum of the body and loads the body into m.
+func Read(r io.Reader, m Persistent) (*Header, error) {
+	h, body, err := decode(r)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.LoadModel(bytes.NewReader(body)); err != nil {
+		return nil, fmt.Errorf("model body: %v", err)
+	}
+	return h, nil
+}
+
+// Save writes the model m with header h to the file at path. The model is written to a
+// temporary file first and renamed to path, so readers never see a partial model.
+func Save(path string, h *Header, m Persistent) error {
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	file.Chmod(0644)
+	if err = Write(file, h, m); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err = file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	if err = os.Rename(file.Name(), path); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return nil
+}
+
+// ReadFile reads the model file at path and verifies its checksum. The body is left for
+// the LoadModel method of an algorithm set up according to the header.
+func ReadFile(path string) (*Header, []byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	h, body, err := decode(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return h, body, nil
+}
+
+// Load loads the model file at path into m.
+func Load(path string, m Persistent) (*Header, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	h, err := Read(file, m)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return h, nil
+}
+
+// ReadHeader reads the header of the model file at path without loading the feature
+// names and the body.
+func ReadHeader(path string) (*Header, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	h, err := readHeader(bufio.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return h, nil
+}
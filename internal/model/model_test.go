@@ -0,0 +1,96 @@
+package model
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/pantsing/hector/internal/core"
+)
+
+type textModel struct {
+	text string
+}
+
+func (m *textModel) SaveModel(w io.Writer) error {
+	_, err := io.WriteString(w, m.text)
+	return err
+}
+
+func (m *textModel) LoadModel(r io.Reader) error {
+	text, err := ioutil.ReadAll(r)
+	m.text = string(text)
+	return err
+}
+
+func TestWriteRead(t *testing.T) {
+	var buf bytes.Buffer
+	h := &Header{Algorithm: "ftrl", Task: TaskBinary, Params: map[string]string{"alpha": "0.1"}}
+	if err := Write(&buf, h, &textModel{"1\t0.5\n"}); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := &textModel{}
+	got, err := Read(bytes.NewReader(buf.Bytes()), loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.text != "1\t0.5\n" {
+		t.Errorf("body %q after read, want %q", loaded.text, "1\t0.5\n")
+	}
+	if err = got.Check("ftrl", TaskBinary); err != nil {
+		t.Error(err)
+	}
+	if got.Check("logRegr", TaskBinary) == nil {
+		t.Error("Check accepted a model of another algorithm")
+	}
+	if got.Params["alpha"] != "0.1" {
+		t.Errorf("param alpha is %q after read, want 0.1", got.Params["alpha"])
+	}
+
+	corrupted := strings.Replace(buf.String(), "0.5", "0.6", 1)
+	if _, err = Read(strings.NewReader(corrupted), &textModel{}); err == nil {
+		t.Error("Read accepted a model with a wrong checksum")
+	}
+}
+
+func TestWriteReadFeatureNames(t *testing.T) {
+	var buf bytes.Buffer
+	h := &Header{Algorithm: "ftrl", Task: TaskBinary, FeatureNames: core.FeatureDict{-3: "country=fr", 7: "age"}}
+	if err := Write(&buf, h, &textModel{"-3\t0.5\n"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), Magic+" 2\n") {
+		t.Errorf("model with feature names starts with %q, want version 2", strings.SplitN(buf.String(), "\n", 2)[0])
+	}
+
+	loaded := &textModel{}
+	got, err := Read(bytes.NewReader(buf.Bytes()), loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.text != "-3\t0.5\n" {
+		t.Errorf("body %q after read, want %q", loaded.text, "-3\t0.5\n")
+	}
+	if got.FeatureNames.Name(-3) != "country=fr" || got.FeatureNames.Name(7) != "age" || got.FeatureNames.Name(8) != "8" {
+		t.Errorf("feature names %v after read", got.FeatureNames)
+	}
+
+	corrupted := strings.Replace(buf.String(), "country=fr", "country=us", 1)
+	if _, err = Read(strings.NewReader(corrupted), &textModel{}); err == nil {
+		t.Error("Read accepted a model with altered feature names")
+	}
+}
+
+func TestScannerReportsLine(t *testing.T) {
+	s := NewScanner(strings.NewReader("1\t0.5\n2\tx\n"))
+	for s.Scan() {
+		s.Int64(0)
+		s.Float(1)
+	}
+	if err := s.Err(); err == nil || !strings.HasPrefix(err.Error(), "line 2: field 2") {
+		t.Errorf("Err() is %v, want an error on line 2 field 2", err)
+	}
+}
@@ -0,0 +1,101 @@
+package model
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MaxLineSize is the longest line a model body may hold, support vectors of KNN and L1VM
+// being written on one line each.
+const MaxLineSize = 64 << 20
+
+// Scanner reads the tab separated lines of a model body. Parse errors are kept together
+// with the line number they occurred on, and stop the scan.
+type Scanner struct {
+	scanner *bufio.Scanner
+	line    int
+	fields  []string
+	err     error
+}
+
+func NewScanner(r io.Reader) *Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxLineSize)
+	return &Scanner{scanner: scanner}
+}
+
+// Scan advances to the next line. It returns false at the end of the body or after an error.
+func (s *Scanner) Scan() bool {
+	if s.err != nil || !s.scanner.Scan() {
+		return false
+	}
+	s.line++
+	s.fields = strings.Split(s.scanner.Text(), "\t")
+	return true
+}
+
+// Text returns the current line.
+func (s *Scanner) Text() string {
+	return s.scanner.Text()
+}
+
+// Line returns the number of the current line, starting at 1.
+func (s *Scanner) Line() int {
+	return s.line
+}
+
+// Fields returns the number of fields of the current line.
+func (s *Scanner) Fields() int {
+	return len(s.fields)
+}
+
+// Errorf records an error on the current line unless one was recorded before.
+func (s *Scanner) Errorf(format string, args ...interface{}) {
+	if s.err == nil {
+		s.err = fmt.Errorf("line %d: %s", s.line, fmt.Sprintf(format, args...))
+	}
+}
+
+// String returns field i of the current line.
+func (s *Scanner) String(i int) string {
+	if i >= len(s.fields) {
+		s.Errorf("missing field %d", i+1)
+		return ""
+	}
+	return s.fields[i]
+}
+
+func (s *Scanner) Int(i int) int {
+	v, err := strconv.Atoi(s.String(i))
+	if err != nil && s.err == nil {
+		s.Errorf("field %d: %v", i+1, err)
+	}
+	return v
+}
+
+func (s *Scanner) Int64(i int) int64 {
+	v, err := strconv.ParseInt(s.String(i), 10, 64)
+	if err != nil && s.err == nil {
+		s.Errorf("field %d: %v", i+1, err)
+	}
+	return v
+}
+
+func (s *Scanner) Float(i int) float64 {
+	v, err := strconv.ParseFloat(s.String(i), 64)
+	if err != nil && s.err == nil {
+		s.Errorf("field %d: %v", i+1, err)
+	}
+	return v
+}
+
+// Err returns the first parse or read error.
+func (s *Scanner) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.scanner.Err()
+}
@@ -0,0 +1,266 @@
+/*
+Package params binds the parameters of the algorithms to command line flags, config
+file keys and library options. The parameters of an algorithm are the fields of a struct,
+each declared once by its tags:
+
+	type LogisticRegressionParams struct {
+		LearningRate float64 `flag:"learning-rate,lrate" default:"0.01" min:"0" usage:"Learning rate"`
+		Steps        int     `flag:"steps" default:"10" min:"1" usage:"Number of passes over the train set"`
+	}
+
+flag holds the names of the parameter, the first of which is its config key; default its
+default value; min and max its inclusive bounds. Fields of nested structs without a flag
+tag are parameters of the outer struct. Every other field needs a flag tag, which is "-"
+for fields that are not parameters. A params struct may check more than its bounds by
+implementing Validator.
+*/
+package params
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// Validator is implemented by params structs with constraints across fields.
+type Validator interface {
+	Validate() error
+}
+
+// param is a field of a params struct.
+type param struct {
+	names []string
+	field reflect.StructField
+	value reflect.Value
+}
+
+func (p *param) name() string {
+	return p.names[0]
+}
+
+// fields returns the params of the struct p points to, nested structs flattened.
+func fields(p interface{}) []*param {
+	v := reflect.ValueOf(p)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("params: %T is not a pointer to a struct", p))
+	}
+	return appendFields(nil, v.Elem())
+}
+
+func appendFields(ret []*param, v reflect.Value) []*param {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup("flag")
+		if !ok && field.Type.Kind() == reflect.Struct {
+			ret = appendFields(ret, v.Field(i))
+			continue
+		}
+		if !ok {
+			panic(fmt.Sprintf("params: %s.%s has no flag tag", t.Name(), field.Name))
+		}
+		if tag == "-" {
+			continue
+		}
+		names := strings.Split(tag, ",")
+		for j := range names {
+			names[j] = strings.TrimSpace(names[j])
+		}
+		ret = append(ret, &param{names: names, field: field, value: v.Field(i)})
+	}
+	return ret
+}
+
+func find(p interface{}, name string) *param {
+	for _, f := range fields(p) {
+		for _, n := range f.names {
+			if n == name {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+func (p *param) set(value string) error {
+	var err error
+	switch p.value.Kind() {
+	case reflect.Float64:
+		var v float64
+		if v, err = strconv.ParseFloat(value, 64); err == nil {
+			p.value.SetFloat(v)
+		}
+	case reflect.Int, reflect.Int64:
+		var v int64
+		if v, err = strconv.ParseInt(value, 10, 64); err == nil {
+			p.value.SetInt(v)
+		}
+	case reflect.Bool:
+		var v bool
+		if v, err = strconv.ParseBool(value); err == nil {
+			p.value.SetBool(v)
+		}
+	case reflect.String:
+		p.value.SetString(value)
+	default:
+		panic(fmt.Sprintf("params: %s: unsupported type %s", p.field.Name, p.value.Type()))
+	}
+	if err != nil {
+		return fmt.Errorf("%s: invalid value %q", p.name(), value)
+	}
+	return nil
+}
+
+func (p *param) String() string {
+	return fmt.Sprint(p.value.Interface())
+}
+
+// Defaults sets every param of the struct p points to its default value.
+func Defaults(p interface{}) {
+	for _, f := range fields(p) {
+		if value, ok := f.field.Tag.Lookup("default"); ok {
+			if err := f.set(value); err != nil {
+				panic(fmt.Sprintf("params: default of %v", err))
+			}
+		} else {
+			f.value.Set(reflect.Zero(f.value.Type()))
+		}
+	}
+}
+
+// Set sets the param named name of the struct p points to. name may be any of its names.
+func Set(p interface{}, name, value string) error {
+	f := find(p, name)
+	if f == nil {
+		return fmt.Errorf("unknown param %q", name)
+	}
+	return f.set(value)
+}
+
+// Has tells whether the struct p points to has a param named name.
+func Has(p interface{}, name string) bool {
+	return find(p, name) != nil
+}
+
+// Values returns the value of every param keyed by its config key.
+func Values(p interface{}) map[string]string {
+	values := make(map[string]string)
+	for _, f := range fields(p) {
+		values[f.name()] = f.String()
+	}
+	return values
+}
+
+// Names returns the config keys of the params.
+func Names(p interface{}) []string {
+	var names []string
+	for _, f := range fields(p) {
+		names = append(names, f.name())
+	}
+	return names
+}
+
+// Validate checks every param against its bounds, then calls the Validate method of p
+// if it implements Validator.
+func Validate(p interface{}) error {
+	for _, f := range fields(p) {
+		if f.value.Kind() != reflect.Float64 && f.value.Kind() != reflect.Int && f.value.Kind() != reflect.Int64 {
+			continue
+		}
+		v, _ := strconv.ParseFloat(f.String(), 64)
+		if min, ok := f.field.Tag.Lookup("min"); ok {
+			if bound, _ := strconv.ParseFloat(min, 64); v < bound {
+				return fmt.Errorf("%s is %s, it must be at least %s", f.name(), f, min)
+			}
+		}
+		if max, ok := f.field.Tag.Lookup("max"); ok {
+			if bound, _ := strconv.ParseFloat(max, 64); v > bound {
+				return fmt.Errorf("%s is %s, it must be at most %s", f.name(), f, max)
+			}
+		}
+	}
+	if v, ok := p.(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// Flags returns a command line flag for every param, holding its default value.
+func Flags(p interface{}) []cli.Flag {
+	var flags []cli.Flag
+	for _, f := range fields(p) {
+		name := strings.Join(f.names, ", ")
+		usage := f.field.Tag.Get("usage")
+		value := f.field.Tag.Get("default")
+		switch f.value.Kind() {
+		case reflect.Float64:
+			v, _ := strconv.ParseFloat(value, 64)
+			flags = append(flags, cli.Float64Flag{Name: name, Usage: usage, Value: v})
+		case reflect.Int:
+			v, _ := strconv.Atoi(value)
+			flags = append(flags, cli.IntFlag{Name: name, Usage: usage, Value: v})
+		case reflect.Int64:
+			v, _ := strconv.ParseInt(value, 10, 64)
+			flags = append(flags, cli.Int64Flag{Name: name, Usage: usage, Value: v})
+		case reflect.Bool:
+			if v, _ := strconv.ParseBool(value); v {
+				flags = append(flags, cli.BoolTFlag{Name: name, Usage: usage})
+			} else {
+				flags = append(flags, cli.BoolFlag{Name: name, Usage: usage})
+			}
+		case reflect.String:
+			flags = append(flags, cli.StringFlag{Name: name, Usage: usage, Value: value})
+		default:
+			panic(fmt.Sprintf("params: %s: unsupported type %s", f.field.Name, f.value.Type()))
+		}
+	}
+	return flags
+}
+
+// Bind sets every param of the struct p points to from the flags of ctx, which must
+// include the flags returned by Flags, and validates them.
+func Bind(ctx *cli.Context, p interface{}) error {
+	for _, f := range fields(p) {
+		if err := f.set(ctx.String(f.name())); err != nil {
+			return err
+		}
+	}
+	return Validate(p)
+}
+
+/*
+ReadConfig reads a config file of "key = value" lines. Blank lines and lines starting
+with # are ignored.
+*/
+func ReadConfig(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("%s:%d: want key = value", path, n)
+		}
+		values[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return values, nil
+}
@@ -0,0 +1,75 @@
+package params
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type treeParams struct {
+	MaxDepth int `flag:"max-depth" default:"10" min:"1"`
+}
+
+type forestParams struct {
+	TreeCount int     `flag:"tree-count,tc" default:"5" min:"1"`
+	Ratio     float64 `flag:"ratio" default:"0.5" min:"0" max:"1"`
+	Balance   bool    `flag:"balance" default:"true"`
+	Derived   float64 `flag:"-"`
+	Tree      treeParams
+}
+
+func TestDefaultsSetValidate(t *testing.T) {
+	p := forestParams{Derived: 3}
+	Defaults(&p)
+	if p.TreeCount != 5 || p.Ratio != 0.5 || !p.Balance || p.Tree.MaxDepth != 10 || p.Derived != 3 {
+		t.Errorf("defaults are %+v", p)
+	}
+	if err := Set(&p, "tc", "7"); err != nil || p.TreeCount != 7 {
+		t.Errorf("Set of an alias gave %v, TreeCount %d", err, p.TreeCount)
+	}
+	if err := Set(&p, "max-depth", "x"); err == nil {
+		t.Error("Set accepted an invalid int")
+	}
+	if err := Set(&p, "unknown", "1"); err == nil {
+		t.Error("Set accepted an unknown param")
+	}
+	if err := Validate(&p); err != nil {
+		t.Error(err)
+	}
+	p.Ratio = 1.5
+	if err := Validate(&p); err == nil {
+		t.Error("Validate accepted ratio 1.5 above its max")
+	}
+	if n := len(Flags(&p)); n != 4 {
+		t.Errorf("%d flags, want 4", n)
+	}
+}
+
+func TestUntaggedFieldPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("a field without flag tag did not panic")
+		}
+	}()
+	Names(&struct{ Steps int }{})
+}
+
+func TestReadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hector-params")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "train.conf")
+	if err = ioutil.WriteFile(path, []byte("# forest\ntree-count = 20\n\nratio=0.25\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	values, err := ReadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 || values["tree-count"] != "20" || values["ratio"] != "0.25" {
+		t.Errorf("config is %v", values)
+	}
+}
@@ -14,6 +14,9 @@ func CombineFeatures(fids []int64) int64{
 	return ret
 }
 
+// HashName identifies Hash in model files, whose feature IDs are only valid under the same hash.
+const HashName = "poly601840361"
+
 func Hash(str string) int64 {
 	h := int64(0)
 
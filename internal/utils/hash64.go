@@ -0,0 +1,168 @@
+package utils
+
+import "math/bits"
+
+// 64 bit hash functions of feature names, seeded to hash the names of a namespace apart
+// from those of the others.
+
+// le32 and le64 read little endian integers from the start of s.
+func le32(s string) uint32 {
+	_ = s[3]
+	return uint32(s[0]) | uint32(s[1])<<8 | uint32(s[2])<<16 | uint32(s[3])<<24
+}
+
+func le64(s string) uint64 {
+	return uint64(le32(s)) | uint64(le32(s[4:]))<<32
+}
+
+// PolyHash is the polynomial hash of Hash, which returns its absolute value, starting
+// from seed.
+func PolyHash(str string, seed uint64) uint64 {
+	h := int64(seed)
+	for _, ch := range str {
+		h *= 601840361
+		h += int64(ch)
+	}
+	return uint64(h)
+}
+
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// FNV1a is the 64 bit FNV-1a hash of str, whose offset basis is xored with seed.
+func FNV1a(str string, seed uint64) uint64 {
+	h := uint64(fnvOffset64) ^ seed
+	for i := 0; i < len(str); i++ {
+		h ^= uint64(str[i])
+		h *= fnvPrime64
+	}
+	return h
+}
+
+const (
+	murmurC1 = 0x87c37b91114253d5
+	murmurC2 = 0x4cf5ad432745937f
+)
+
+func murmurFmix(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
+
+// Murmur3 is the first half of the 128 bit MurmurHash3 x64 hash of str.
+func Murmur3(str string, seed uint32) uint64 {
+	h1, h2 := uint64(seed), uint64(seed)
+	n := len(str)
+	for i := 0; i+16 <= n; i += 16 {
+		k1, k2 := le64(str[i:]), le64(str[i+8:])
+		k1 *= murmurC1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= murmurC2
+		h1 ^= k1
+		h1 = bits.RotateLeft64(h1, 27)
+		h1 += h2
+		h1 = h1*5 + 0x52dce729
+		k2 *= murmurC2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= murmurC1
+		h2 ^= k2
+		h2 = bits.RotateLeft64(h2, 31)
+		h2 += h1
+		h2 = h2*5 + 0x38495ab5
+	}
+	tail := str[n-n%16:]
+	var k1, k2 uint64
+	for i := len(tail) - 1; i >= 8; i-- {
+		k2 ^= uint64(tail[i]) << (8 * uint(i-8))
+	}
+	if len(tail) > 8 {
+		k2 *= murmurC2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= murmurC1
+		h2 ^= k2
+	}
+	for i := 0; i < len(tail) && i < 8; i++ {
+		k1 ^= uint64(tail[i]) << (8 * uint(i))
+	}
+	if len(tail) > 0 {
+		k1 *= murmurC1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= murmurC2
+		h1 ^= k1
+	}
+	h1 ^= uint64(n)
+	h2 ^= uint64(n)
+	h1 += h2
+	h2 += h1
+	h1 = murmurFmix(h1)
+	h2 = murmurFmix(h2)
+	return h1 + h2
+}
+
+const (
+	xxPrime1 uint64 = 11400714785074694791
+	xxPrime2 uint64 = 14029467366897019727
+	xxPrime3 uint64 = 1609587929392839161
+	xxPrime4 uint64 = 9650029242287828579
+	xxPrime5 uint64 = 2870177450012600261
+)
+
+func xxRound(acc, input uint64) uint64 {
+	acc += input * xxPrime2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * xxPrime1
+}
+
+func xxMerge(acc, val uint64) uint64 {
+	acc ^= xxRound(0, val)
+	return acc*xxPrime1 + xxPrime4
+}
+
+// XXHash64 is the 64 bit xxHash of str.
+func XXHash64(str string, seed uint64) uint64 {
+	n := len(str)
+	i := 0
+	var h uint64
+	if n >= 32 {
+		v1, v2, v3, v4 := seed+xxPrime1+xxPrime2, seed+xxPrime2, seed, seed-xxPrime1
+		for ; i+32 <= n; i += 32 {
+			v1 = xxRound(v1, le64(str[i:]))
+			v2 = xxRound(v2, le64(str[i+8:]))
+			v3 = xxRound(v3, le64(str[i+16:]))
+			v4 = xxRound(v4, le64(str[i+24:]))
+		}
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) + bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = xxMerge(h, v1)
+		h = xxMerge(h, v2)
+		h = xxMerge(h, v3)
+		h = xxMerge(h, v4)
+	} else {
+		h = seed + xxPrime5
+	}
+	h += uint64(n)
+	for ; i+8 <= n; i += 8 {
+		h ^= xxRound(0, le64(str[i:]))
+		h = bits.RotateLeft64(h, 27)*xxPrime1 + xxPrime4
+	}
+	if i+4 <= n {
+		h ^= uint64(le32(str[i:])) * xxPrime1
+		h = bits.RotateLeft64(h, 23)*xxPrime2 + xxPrime3
+		i += 4
+	}
+	for ; i < n; i++ {
+		h ^= uint64(str[i]) * xxPrime5
+		h = bits.RotateLeft64(h, 11) * xxPrime1
+	}
+	h ^= h >> 33
+	h *= xxPrime2
+	h ^= h >> 29
+	h *= xxPrime3
+	h ^= h >> 32
+	return h
+}
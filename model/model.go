@@ -0,0 +1,53 @@
+/*
+Package model reads and writes hector model files.
+
+A model file is a "#hector-model <version>" line, a one line JSON Header, the names of
+the hashed features if the FeatureNames of the header are set, and the body written by
+the SaveModel method of the algorithm. Files written by Save can be used
+with the test and predict actions of the hector command.
+*/
+package model
+
+import (
+	"github.com/pantsing/hector/internal/model"
+	"io"
+)
+
+type (
+	// Header describes the model that follows it.
+	Header = model.Header
+	// Persistent is implemented by every model which can be saved.
+	Persistent = model.Persistent
+)
+
+// Tasks a model can be trained for.
+const (
+	TaskBinary     = model.TaskBinary
+	TaskMultiClass = model.TaskMultiClass
+	TaskRegression = model.TaskRegression
+)
+
+// Write writes the header and the body of m to w.
+func Write(w io.Writer, h *Header, m Persistent) error {
+	return model.Write(w, h, m)
+}
+
+// Read reads a model written by Write into m, and returns its header.
+func Read(r io.Reader, m Persistent) (*Header, error) {
+	return model.Read(r, m)
+}
+
+// Save writes the model to the file path.
+func Save(path string, h *Header, m Persistent) error {
+	return model.Save(path, h, m)
+}
+
+// Load reads the model file path into m, and returns its header.
+func Load(path string, m Persistent) (*Header, error) {
+	return model.Load(path, m)
+}
+
+// ReadHeader returns the header of the model file path without reading its body.
+func ReadHeader(path string) (*Header, error) {
+	return model.ReadHeader(path)
+}
@@ -0,0 +1,39 @@
+/*
+Package params sets the parameters of the algorithms of the packages classifier and
+regressor. The params structs declare the defaults and bounds of their fields, as well as
+the names of the matching command line flags and config file keys:
+
+	p := classifier.LogisticRegressionParams{}
+	params.Defaults(&p)
+	p.Steps = 20
+	if err := params.Validate(&p); err != nil {
+		...
+	}
+	c := classifier.NewLogisticRegression(p)
+*/
+package params
+
+import (
+	"github.com/pantsing/hector/internal/params"
+)
+
+// Defaults sets every param of the struct p points to its default value.
+func Defaults(p interface{}) {
+	params.Defaults(p)
+}
+
+// Validate checks the params of the struct p points to against their bounds.
+func Validate(p interface{}) error {
+	return params.Validate(p)
+}
+
+// Set sets the param with the flag or config key name to value.
+func Set(p interface{}, name, value string) error {
+	return params.Set(p, name, value)
+}
+
+// Values returns the value of every param keyed by its config key, as recorded in the
+// Params of a model.Header.
+func Values(p interface{}) map[string]string {
+	return params.Values(p)
+}
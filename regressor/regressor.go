@@ -0,0 +1,31 @@
+// Package regressor exposes the regression algorithms of hector to Go programs.
+package regressor
+
+import (
+	"github.com/pantsing/hector/core"
+	"github.com/pantsing/hector/internal/algorithms/regressor/gp"
+	"io"
+)
+
+// Regressor predicts a real value.
+type Regressor interface {
+	// Train model on a given dataset
+	Train(dataset *core.RealDataSet)
+	// Predict the output of an input sample
+	Predict(sample *core.RealSample) float64
+	SaveModel(w io.Writer) error
+	LoadModel(r io.Reader) error
+}
+
+type (
+	GaussianProcess           = gp.GaussianProcess
+	GaussianProcessParameters = gp.GaussianProcessParameters
+)
+
+var _ Regressor = (*GaussianProcess)(nil)
+
+// NewGaussianProcess returns a Gaussian process over the continuous features 1 to params.Dim.
+// Its model is the training set, so it can not be saved.
+func NewGaussianProcess(params GaussianProcessParameters) *GaussianProcess {
+	return gp.NewGaussianProcess(params)
+}